@@ -0,0 +1,424 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package builder implements the builder API, allowing validators to source
+// their execution payloads from the node through MEV-boost style relays.
+//
+// Bids are built by the miner with the payload attributes the consensus client
+// last requested a payload with, paying the fees to the fee recipient the
+// proposer registered.
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+const (
+	// maxBids is the number of bids whose payloads are retained, to be revealed
+	// once the proposer commits to their headers.
+	maxBids = 16
+
+	// maxRegistrationDrift is the maximum amount of time a validator registration
+	// may be timestamped in the future.
+	maxRegistrationDrift = 10 * time.Second
+
+	// maxRequestSize is the maximum size of the accepted request bodies.
+	maxRequestSize = 4 * 1024 * 1024
+)
+
+// Config contains the settings of the builder API service.
+type Config struct {
+	Host               string  // Interface to listen on
+	Port               int     // Port to listen on
+	SecretKey          []byte  // BLS secret key signing the bids, random if empty
+	GenesisForkVersion [4]byte // Genesis fork version of the beacon chain, part of the signing domain
+}
+
+// DefaultConfig contains the default settings of the builder API service.
+var DefaultConfig = Config{
+	Host: "localhost",
+	Port: 28545,
+}
+
+// bid is a payload offered to a proposer.
+type bid struct {
+	header  *ExecutionPayloadHeader
+	payload *ExecutionPayload
+	version string
+}
+
+// offerKey identifies the payload attributes a bid is made for.
+type offerKey struct {
+	parent       common.Hash
+	feeRecipient common.Address
+	timestamp    uint64
+}
+
+// offer is a signed bid, served again to the proposers asking for a bid with
+// the same payload attributes.
+type offer struct {
+	signed  *SignedBuilderBid
+	version string
+}
+
+// Builder serves the builder API on top of the payload building of the miner.
+type Builder struct {
+	eth    *eth.Ethereum
+	config Config
+	key    *bls12381.SecretKey
+	pubkey []byte
+	domain common.Hash
+
+	validators map[string]*ValidatorRegistration // Latest registrations by public key
+	bids       map[common.Hash]*bid              // Recent bids by block hash
+	bidOrder   []common.Hash                     // Block hashes of the bids in creation order
+	lock       sync.Mutex
+
+	offers    map[offerKey]*offer // Bids made on top of the latest parent
+	offerLock sync.Mutex          // Serializes bid making, so every bid is built once
+
+	server   *http.Server
+	listener net.Listener
+}
+
+// New creates the builder API service and registers it with the node.
+func New(stack *node.Node, backend *eth.Ethereum, config Config) (*Builder, error) {
+	var (
+		key *bls12381.SecretKey
+		err error
+	)
+	if len(config.SecretKey) == 0 {
+		key, err = bls12381.GenerateKey(nil)
+		log.Warn("Builder API using an ephemeral signing key")
+	} else {
+		key, err = bls12381.SecretKeyFromBytes(config.SecretKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid builder key: %v", err)
+	}
+	b := newBuilder(backend, config, key)
+	stack.RegisterLifecycle(b)
+	return b, nil
+}
+
+func newBuilder(backend *eth.Ethereum, config Config, key *bls12381.SecretKey) *Builder {
+	b := &Builder{
+		eth:        backend,
+		config:     config,
+		key:        key,
+		pubkey:     key.PublicKey(),
+		domain:     computeDomain(domainApplicationBuilder, config.GenesisForkVersion, common.Hash{}),
+		validators: make(map[string]*ValidatorRegistration),
+		bids:       make(map[common.Hash]*bid),
+		offers:     make(map[offerKey]*offer),
+	}
+	b.server = &http.Server{
+		Handler:           b.handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return b
+}
+
+// Start implements node.Lifecycle, starting to serve the builder API.
+func (b *Builder) Start() error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(b.config.Host, strconv.Itoa(b.config.Port)))
+	if err != nil {
+		return err
+	}
+	b.listener = listener
+	go b.server.Serve(listener)
+
+	log.Info("Builder API started", "url", "http://"+listener.Addr().String(), "pubkey", hexutil.Bytes(b.pubkey))
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the builder API.
+func (b *Builder) Stop() error {
+	if b.listener == nil {
+		return nil
+	}
+	return b.server.Close()
+}
+
+// PublicKey returns the BLS public key signing the bids.
+func (b *Builder) PublicKey() []byte {
+	return b.pubkey
+}
+
+// handler returns the HTTP handler of the builder API endpoints.
+func (b *Builder) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eth/v1/builder/status", b.handleStatus)
+	mux.HandleFunc("/eth/v1/builder/validators", b.handleRegisterValidators)
+	mux.HandleFunc("/eth/v1/builder/header/", b.handleGetHeader)
+	mux.HandleFunc("/eth/v1/builder/blinded_blocks", b.handleSubmitBlindedBlock)
+	return mux
+}
+
+// handleStatus reports that the builder is available.
+func (b *Builder) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleRegisterValidators records the fee recipients of validators, the
+// payloads built for them pay the fees to.
+func (b *Builder) handleRegisterValidators(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var regs []*SignedValidatorRegistration
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&regs); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid registrations: %v", err))
+		return
+	}
+	for i, reg := range regs {
+		if err := b.verifyRegistration(reg); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid registration %d: %v", i, err))
+			return
+		}
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for _, reg := range regs {
+		key := string(reg.Message.Pubkey)
+		if prev := b.validators[key]; prev == nil || prev.Timestamp < reg.Message.Timestamp {
+			b.validators[key] = reg.Message
+		}
+	}
+	log.Debug("Registered validators", "count", len(regs))
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifyRegistration checks that a registration is well formed and signed by
+// the validator.
+func (b *Builder) verifyRegistration(reg *SignedValidatorRegistration) error {
+	if reg == nil || reg.Message == nil {
+		return errors.New("missing message")
+	}
+	if len(reg.Message.Pubkey) != bls12381.PublicKeyLength {
+		return errors.New("invalid public key length")
+	}
+	if limit := time.Now().Add(maxRegistrationDrift).Unix(); int64(reg.Message.Timestamp) > limit {
+		return errors.New("timestamp too far in the future")
+	}
+	root := signingRoot(reg.Message.hashTreeRoot(), b.domain)
+	if !bls12381.Verify(reg.Message.Pubkey, root[:], reg.Signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// handleGetHeader builds a payload on top of the requested parent for the given
+// proposer, and offers its header. If the proposer isn't registered or no
+// payload was requested on top of the parent, no bid is made. Payloads are only
+// built once per attributes and fee recipient, repeated requests are served the
+// same bid.
+func (b *Builder) handleGetHeader(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	// Parse the /eth/v1/builder/header/{slot}/{parent_hash}/{pubkey} path
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/eth/v1/builder/header/"), "/")
+	if len(parts) != 3 {
+		writeError(w, http.StatusBadRequest, "invalid path")
+		return
+	}
+	slot, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid slot")
+		return
+	}
+	var parent common.Hash
+	if err := parent.UnmarshalText([]byte(parts[1])); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid parent hash")
+		return
+	}
+	pubkey, err := hexutil.Decode(parts[2])
+	if err != nil || len(pubkey) != bls12381.PublicKeyLength {
+		writeError(w, http.StatusBadRequest, "invalid public key")
+		return
+	}
+	b.lock.Lock()
+	reg := b.validators[string(pubkey)]
+	b.lock.Unlock()
+	if reg == nil {
+		log.Debug("No bid for unregistered proposer", "slot", slot, "pubkey", hexutil.Bytes(pubkey))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	args := b.eth.Miner().LastPayloadArgs()
+	if args == nil || args.Parent != parent {
+		log.Debug("No bid for unknown payload attributes", "slot", slot, "parent", parent)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	offer, err := b.offer(slot, args, reg)
+	if err != nil {
+		log.Warn("Failed to make bid", "slot", slot, "parent", parent, "err", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, &versionedResponse{Version: offer.version, Data: offer.signed})
+}
+
+// offer returns the bid made for the given payload attributes and registration,
+// making it if there's none yet. Bids on top of older parents are dropped, and
+// at most maxBids are retained.
+func (b *Builder) offer(slot uint64, args *miner.BuildPayloadArgs, reg *ValidatorRegistration) (*offer, error) {
+	b.offerLock.Lock()
+	defer b.offerLock.Unlock()
+
+	key := offerKey{parent: args.Parent, feeRecipient: reg.FeeRecipient, timestamp: args.Timestamp}
+	if o := b.offers[key]; o != nil {
+		return o, nil
+	}
+	signed, version, err := b.makeBid(args, reg)
+	if err != nil {
+		return nil, err
+	}
+	for k := range b.offers {
+		if k.parent != key.parent || len(b.offers) >= maxBids {
+			delete(b.offers, k)
+		}
+	}
+	o := &offer{signed: signed, version: version}
+	b.offers[key] = o
+
+	log.Info("Made builder bid", "slot", slot, "number", uint64(signed.Message.Header.BlockNumber),
+		"hash", signed.Message.Header.BlockHash, "value", (*big.Int)(signed.Message.Value))
+	return o, nil
+}
+
+// makeBid builds a payload with the given attributes paying the registered fee
+// recipient, and signs a bid for it. The fork of the payload is returned too.
+func (b *Builder) makeBid(args *miner.BuildPayloadArgs, reg *ValidatorRegistration) (*SignedBuilderBid, string, error) {
+	attrs := *args
+	attrs.FeeRecipient = reg.FeeRecipient
+
+	payload, err := b.eth.Miner().BuildPayload(&attrs)
+	if err != nil {
+		return nil, "", err
+	}
+	envelope := payload.Resolve()
+
+	msg := &BuilderBid{
+		Header: newExecutionPayloadHeader(envelope.ExecutionPayload),
+		Value:  (*BigQuantity)(envelope.BlockValue),
+		Pubkey: b.pubkey,
+	}
+	root := signingRoot(msg.hashTreeRoot(), b.domain)
+	sig, err := b.key.Sign(root[:])
+	if err != nil {
+		return nil, "", err
+	}
+	version := "bellatrix"
+	if envelope.ExecutionPayload.Withdrawals != nil {
+		version = "capella"
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	hash := msg.Header.BlockHash
+	if _, ok := b.bids[hash]; !ok {
+		if len(b.bidOrder) == maxBids {
+			delete(b.bids, b.bidOrder[0])
+			b.bidOrder = b.bidOrder[1:]
+		}
+		b.bidOrder = append(b.bidOrder, hash)
+	}
+	b.bids[hash] = &bid{
+		header:  msg.Header,
+		payload: newExecutionPayload(envelope.ExecutionPayload),
+		version: version,
+	}
+	return &SignedBuilderBid{Message: msg, Signature: sig}, version, nil
+}
+
+// handleSubmitBlindedBlock reveals the payload of a bid once the proposer has
+// signed a beacon block committing to its header.
+//
+// The signature of the proposer over the beacon block is not verified, as that
+// would require the beacon state. Only the payloads of bids made are revealed.
+func (b *Builder) handleSubmitBlindedBlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var block SignedBlindedBeaconBlock
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&block); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid blinded block: %v", err))
+		return
+	}
+	if block.Message == nil || block.Message.Body == nil || block.Message.Body.ExecutionPayloadHeader == nil {
+		writeError(w, http.StatusBadRequest, "missing execution payload header")
+		return
+	}
+	header := block.Message.Body.ExecutionPayloadHeader
+
+	b.lock.Lock()
+	bid := b.bids[header.BlockHash]
+	b.lock.Unlock()
+	if bid == nil {
+		writeError(w, http.StatusBadRequest, "unknown payload")
+		return
+	}
+	if header.BaseFeePerGas == nil || header.hashTreeRoot() != bid.header.hashTreeRoot() {
+		writeError(w, http.StatusBadRequest, "payload header mismatch")
+		return
+	}
+	log.Info("Revealed builder payload", "slot", uint64(block.Message.Slot), "number", uint64(header.BlockNumber), "hash", header.BlockHash)
+	writeJSON(w, &versionedResponse{Version: bid.version, Data: bid.payload})
+}
+
+// writeJSON writes a successful JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug("Failed to write builder API response", "err", err)
+	}
+}
+
+// writeError writes an error response.
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&errorResponse{Code: code, Message: msg})
+}
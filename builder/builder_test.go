@@ -0,0 +1,234 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"bytes"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr   = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+// startEthService starts a node running a chain merged from genesis on.
+func startEthService(t *testing.T) (*node.Node, *eth.Ethereum) {
+	t.Helper()
+
+	n, err := node.New(&node.Config{
+		P2P: p2p.Config{
+			ListenAddr:  "0.0.0.0:0",
+			NoDiscovery: true,
+			MaxPeers:    25,
+		}})
+	if err != nil {
+		t.Fatal("can't create node:", err)
+	}
+	ethcfg := &ethconfig.Config{Genesis: core.DeveloperGenesisBlock(11_500_000, testAddr), SyncMode: downloader.FullSync, TrieTimeout: time.Minute, TrieDirtyCache: 256, TrieCleanCache: 256}
+	ethservice, err := eth.New(n, ethcfg)
+	if err != nil {
+		t.Fatal("can't create eth service:", err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatal("can't start node:", err)
+	}
+	ethservice.SetEtherbase(testAddr)
+	ethservice.SetSynced()
+	return n, ethservice
+}
+
+// Tests the builder API through a relay: validators register, get bids built
+// for them, and retrieve the payloads once committing to them.
+func TestBuilder(t *testing.T) {
+	n, ethservice := startEthService(t)
+	defer n.Close()
+
+	key, _ := bls12381.GenerateKey(nil)
+	config := DefaultConfig
+	config.GenesisForkVersion = [4]byte{0x00, 0x00, 0x10, 0x20}
+	b := newBuilder(ethservice, config, key)
+
+	server := httptest.NewServer(b.handler())
+	defer server.Close()
+	relay := newMockRelay(server.URL, config.GenesisForkVersion)
+
+	var (
+		head         = ethservice.BlockChain().CurrentBlock()
+		feeRecipient = common.Address{0xfe}
+		slot         = uint64(1)
+	)
+	// No bids are made for unregistered validators.
+	if bid, _, err := relay.getHeader(slot, head.Hash()); err != nil || bid != nil {
+		t.Fatalf("bid for unregistered validator: %v, %v", bid, err)
+	}
+	if err := relay.register(feeRecipient, false); err == nil {
+		t.Fatal("registration with invalid signature accepted")
+	}
+	if err := relay.register(feeRecipient, true); err != nil {
+		t.Fatalf("failed to register validator: %v", err)
+	}
+	// No bids are made before the consensus client requested a payload.
+	if bid, _, err := relay.getHeader(slot, head.Hash()); err != nil || bid != nil {
+		t.Fatalf("bid without payload attributes: %v, %v", bid, err)
+	}
+	// The transaction pays the fee recipient directly too.
+	signer := types.LatestSigner(ethservice.BlockChain().Config())
+	payment := big.NewInt(params.GWei)
+	tx := types.MustSignNewTx(testKey, signer, &types.DynamicFeeTx{
+		ChainID:   ethservice.BlockChain().Config().ChainID,
+		To:        &feeRecipient,
+		Value:     payment,
+		Gas:       params.TxGas,
+		GasFeeCap: big.NewInt(2 * params.InitialBaseFee),
+		GasTipCap: big.NewInt(params.GWei),
+	})
+	if err := ethservice.TxPool().AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if _, err := ethservice.Miner().BuildPayload(&miner.BuildPayloadArgs{
+		Parent:       head.Hash(),
+		Timestamp:    head.Time() + 12,
+		FeeRecipient: testAddr,
+		Random:       common.Hash{0x01},
+		Withdrawals:  types.Withdrawals{{Index: 0, Validator: 1, Address: common.Address{0x02}, Amount: 10}},
+	}); err != nil {
+		t.Fatalf("failed to request payload: %v", err)
+	}
+	// Bids are made for registered validators on top of the requested payload,
+	// paying the registered fee recipient.
+	bid, version, err := relay.getHeader(slot, head.Hash())
+	if err != nil || bid == nil {
+		t.Fatalf("no bid made: %v", err)
+	}
+	header := bid.Message.Header
+	if version != "capella" {
+		t.Errorf("version mismatch: have %s, want capella", version)
+	}
+	if header.FeeRecipient != feeRecipient {
+		t.Errorf("fee recipient mismatch: have %x, want %x", header.FeeRecipient, feeRecipient)
+	}
+	if header.ParentHash != head.Hash() {
+		t.Errorf("parent mismatch: have %x, want %x", header.ParentHash, head.Hash())
+	}
+	// The bid value is the balance increase of the fee recipient, including the
+	// direct payment besides the priority fees.
+	tip := math.BigMin(tx.GasTipCap(), new(big.Int).Sub(tx.GasFeeCap(), (*big.Int)(header.BaseFeePerGas)))
+	value := new(big.Int).Add(payment, new(big.Int).Mul(tip, new(big.Int).SetUint64(params.TxGas)))
+	if (*big.Int)(bid.Message.Value).Cmp(value) != 0 {
+		t.Errorf("bid value mismatch: have %v, want %v", (*big.Int)(bid.Message.Value), value)
+	}
+	if !bytes.Equal(bid.Message.Pubkey, b.PublicKey()) {
+		t.Errorf("bid pubkey mismatch: have %x, want %x", bid.Message.Pubkey, b.PublicKey())
+	}
+	// Repeated requests are served the same bid, instead of building new payloads.
+	next := types.MustSignNewTx(testKey, signer, &types.DynamicFeeTx{
+		ChainID:   ethservice.BlockChain().Config().ChainID,
+		Nonce:     1,
+		To:        &common.Address{0x01},
+		Gas:       params.TxGas,
+		GasFeeCap: big.NewInt(2 * params.InitialBaseFee),
+		GasTipCap: big.NewInt(params.GWei),
+	})
+	if err := ethservice.TxPool().AddLocal(next); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if again, _, err := relay.getHeader(slot, head.Hash()); err != nil || again == nil {
+		t.Fatalf("no bid made: %v", err)
+	} else if again.Message.Header.BlockHash != header.BlockHash {
+		t.Errorf("repeated bid mismatch: have %x, want %x", again.Message.Header.BlockHash, header.BlockHash)
+	}
+	// Payloads are only revealed for the headers bid.
+	tampered := *header
+	tampered.GasUsed++
+	if _, err := relay.submitBlindedBlock(slot, &tampered); err == nil {
+		t.Fatal("payload revealed for tampered header")
+	}
+	tampered = *header
+	tampered.BlockHash = common.Hash{0x01}
+	if _, err := relay.submitBlindedBlock(slot, &tampered); err == nil {
+		t.Fatal("payload revealed for unknown header")
+	}
+	payload, err := relay.submitBlindedBlock(slot, header)
+	if err != nil {
+		t.Fatalf("failed to retrieve payload: %v", err)
+	}
+	data := toExecutableData(payload)
+	block, err := beacon.ExecutableDataToBlock(data)
+	if err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if block.Hash() != header.BlockHash {
+		t.Errorf("payload hash mismatch: have %x, want %x", block.Hash(), header.BlockHash)
+	}
+	if txs := block.Transactions(); len(txs) != 1 || txs[0].Hash() != tx.Hash() {
+		t.Errorf("transaction not included: %v", txs)
+	}
+	if root := newExecutionPayloadHeader(&data).hashTreeRoot(); root != header.hashTreeRoot() {
+		t.Errorf("payload doesn't match header: have %x, want %x", root, header.hashTreeRoot())
+	}
+}
+
+// toExecutableData converts a payload revealed by the builder back to its
+// engine API form.
+func toExecutableData(payload *ExecutionPayload) beacon.ExecutableData {
+	data := beacon.ExecutableData{
+		ParentHash:    payload.ParentHash,
+		FeeRecipient:  payload.FeeRecipient,
+		StateRoot:     payload.StateRoot,
+		ReceiptsRoot:  payload.ReceiptsRoot,
+		LogsBloom:     payload.LogsBloom,
+		Random:        payload.PrevRandao,
+		Number:        uint64(payload.BlockNumber),
+		GasLimit:      uint64(payload.GasLimit),
+		GasUsed:       uint64(payload.GasUsed),
+		Timestamp:     uint64(payload.Timestamp),
+		ExtraData:     payload.ExtraData,
+		BaseFeePerGas: (*big.Int)(payload.BaseFeePerGas),
+		BlockHash:     payload.BlockHash,
+	}
+	for _, tx := range payload.Transactions {
+		data.Transactions = append(data.Transactions, tx)
+	}
+	for _, w := range payload.Withdrawals {
+		data.Withdrawals = append(data.Withdrawals, &types.Withdrawal{
+			Index:     uint64(w.Index),
+			Validator: uint64(w.ValidatorIndex),
+			Address:   w.Address,
+			Amount:    uint64(w.Amount),
+		})
+	}
+	return data
+}
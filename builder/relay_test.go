@@ -0,0 +1,161 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+// mockRelay is a relay forwarding the requests of a single validator to the
+// builder, and checking the responses like a relay would.
+type mockRelay struct {
+	url       string
+	domain    common.Hash
+	validator *bls12381.SecretKey
+}
+
+func newMockRelay(url string, forkVersion [4]byte) *mockRelay {
+	validator, err := bls12381.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	return &mockRelay{
+		url:       url,
+		domain:    computeDomain(domainApplicationBuilder, forkVersion, common.Hash{}),
+		validator: validator,
+	}
+}
+
+// pubkey returns the public key of the validator.
+func (r *mockRelay) pubkey() hexutil.Bytes {
+	return r.validator.PublicKey()
+}
+
+// do sends a request to the builder, decoding the response into res if the
+// builder answers successfully. The status code of the response is returned.
+func (r *mockRelay) do(method, path string, req interface{}, res interface{}) (int, error) {
+	var body bytes.Buffer
+	if req != nil {
+		if err := json.NewEncoder(&body).Encode(req); err != nil {
+			return 0, err
+		}
+	}
+	httpReq, err := http.NewRequest(method, r.url+path, &body)
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if res != nil {
+			return resp.StatusCode, json.NewDecoder(resp.Body).Decode(res)
+		}
+		return resp.StatusCode, nil
+	case http.StatusNoContent:
+		return resp.StatusCode, nil
+	default:
+		var errRes errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errRes); err != nil {
+			return resp.StatusCode, err
+		}
+		return resp.StatusCode, fmt.Errorf("builder error %d: %s", errRes.Code, errRes.Message)
+	}
+}
+
+// register registers the validator with the given fee recipient. If sign is
+// false, the registration is signed by another key.
+func (r *mockRelay) register(feeRecipient common.Address, sign bool) error {
+	msg := &ValidatorRegistration{
+		FeeRecipient: feeRecipient,
+		GasLimit:     30_000_000,
+		Timestamp:    Quantity(time.Now().Unix()),
+		Pubkey:       r.pubkey(),
+	}
+	key := r.validator
+	if !sign {
+		key, _ = bls12381.GenerateKey(nil)
+	}
+	root := signingRoot(msg.hashTreeRoot(), r.domain)
+	sig, err := key.Sign(root[:])
+	if err != nil {
+		return err
+	}
+	_, err = r.do(http.MethodPost, "/eth/v1/builder/validators", []*SignedValidatorRegistration{{Message: msg, Signature: sig}}, nil)
+	return err
+}
+
+// getHeader requests a bid for the given slot and parent, and verifies the
+// signature of the builder. A nil bid is returned if the builder made none.
+func (r *mockRelay) getHeader(slot uint64, parent common.Hash) (*SignedBuilderBid, string, error) {
+	var res struct {
+		Version string            `json:"version"`
+		Data    *SignedBuilderBid `json:"data"`
+	}
+	status, err := r.do(http.MethodGet, fmt.Sprintf("/eth/v1/builder/header/%d/%s/%s", slot, parent.Hex(), r.pubkey()), nil, &res)
+	if err != nil || status == http.StatusNoContent {
+		return nil, "", err
+	}
+	if res.Data == nil || res.Data.Message == nil || res.Data.Message.Header == nil {
+		return nil, "", errors.New("incomplete bid")
+	}
+	root := signingRoot(res.Data.Message.hashTreeRoot(), r.domain)
+	if !bls12381.Verify(res.Data.Message.Pubkey, root[:], res.Data.Signature) {
+		return nil, "", errors.New("invalid bid signature")
+	}
+	return res.Data, res.Version, nil
+}
+
+// submitBlindedBlock signs a blinded block committing to the given header, and
+// returns the payload revealed by the builder.
+func (r *mockRelay) submitBlindedBlock(slot uint64, header *ExecutionPayloadHeader) (*ExecutionPayload, error) {
+	msg := &BlindedBeaconBlock{
+		Slot: Quantity(slot),
+		Body: &BlindedBeaconBlockBody{ExecutionPayloadHeader: header},
+	}
+	// The signature is not checked by the builder, sign the header only
+	root := header.hashTreeRoot()
+	sig, err := r.validator.Sign(root[:])
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Version string            `json:"version"`
+		Data    *ExecutionPayload `json:"data"`
+	}
+	if _, err := r.do(http.MethodPost, "/eth/v1/builder/blinded_blocks", &SignedBlindedBeaconBlock{Message: msg, Signature: sig}, &res); err != nil {
+		return nil, err
+	}
+	if res.Data == nil {
+		return nil, errors.New("missing payload")
+	}
+	return res.Data, nil
+}
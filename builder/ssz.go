@@ -0,0 +1,141 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// This file implements the subset of the SSZ merkleization needed to compute
+// the signing roots of the builder API messages.
+
+const (
+	maxBytesPerTransaction  = 1 << 30 // MAX_BYTES_PER_TRANSACTION
+	maxTransactionsPerBlock = 1 << 20 // MAX_TRANSACTIONS_PER_PAYLOAD
+	maxWithdrawalsPerBlock  = 16      // MAX_WITHDRAWALS_PER_PAYLOAD
+	maxExtraDataBytes       = 32      // MAX_EXTRA_DATA_BYTES
+)
+
+// zeroHashes contains the roots of the all-zero trees of increasing depths.
+var zeroHashes [64]common.Hash
+
+func init() {
+	for i := 1; i < len(zeroHashes); i++ {
+		zeroHashes[i] = hashPair(zeroHashes[i-1], zeroHashes[i-1])
+	}
+}
+
+// hashPair hashes two sibling nodes into their parent.
+func hashPair(a, b common.Hash) common.Hash {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
+
+// merkleize computes the root of the tree of the given chunks, padded with
+// zero chunks up to the limit.
+func merkleize(chunks []common.Hash, limit uint64) common.Hash {
+	depth := 0
+	for uint64(1)<<depth < limit {
+		depth++
+	}
+	if len(chunks) == 0 {
+		return zeroHashes[depth]
+	}
+	layer := append([]common.Hash{}, chunks...)
+	for i := 0; i < depth; i++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zeroHashes[i])
+		}
+		for j := 0; j < len(layer)/2; j++ {
+			layer[j] = hashPair(layer[2*j], layer[2*j+1])
+		}
+		layer = layer[:len(layer)/2]
+	}
+	return layer[0]
+}
+
+// mixInLength mixes the length of a list into its root.
+func mixInLength(root common.Hash, length int) common.Hash {
+	var chunk common.Hash
+	binary.LittleEndian.PutUint64(chunk[:], uint64(length))
+	return hashPair(root, chunk)
+}
+
+// pack splits a byte string into right padded chunks.
+func pack(data []byte) []common.Hash {
+	chunks := make([]common.Hash, (len(data)+31)/32)
+	for i := range chunks {
+		copy(chunks[i][:], data[i*32:])
+	}
+	return chunks
+}
+
+// uint64Root computes the root of an uint64.
+func uint64Root(v uint64) common.Hash {
+	var chunk common.Hash
+	binary.LittleEndian.PutUint64(chunk[:], v)
+	return chunk
+}
+
+// uint256Root computes the root of an uint256, given as a non-negative big
+// integer.
+func uint256Root(v *big.Int) common.Hash {
+	var chunk common.Hash
+	v.FillBytes(chunk[:])
+	for i := 0; i < 16; i++ {
+		chunk[i], chunk[31-i] = chunk[31-i], chunk[i]
+	}
+	return chunk
+}
+
+// vectorRoot computes the root of a fixed size byte vector.
+func vectorRoot(data []byte) common.Hash {
+	return merkleize(pack(data), uint64(len(data)+31)/32)
+}
+
+// listRoot computes the root of a byte list with the given maximum length.
+func listRoot(data []byte, limit uint64) common.Hash {
+	return mixInLength(merkleize(pack(data), (limit+31)/32), len(data))
+}
+
+// containerRoot computes the root of a container from the roots of its fields.
+func containerRoot(fields ...common.Hash) common.Hash {
+	return merkleize(fields, uint64(len(fields)))
+}
+
+// domainApplicationBuilder is the domain type of the builder API signatures.
+var domainApplicationBuilder = [4]byte{0x00, 0x00, 0x00, 0x01}
+
+// computeDomain computes the signing domain of the given type for a fork.
+func computeDomain(domainType [4]byte, forkVersion [4]byte, genesisValidatorsRoot common.Hash) common.Hash {
+	var version common.Hash
+	copy(version[:], forkVersion[:])
+	forkDataRoot := containerRoot(version, genesisValidatorsRoot)
+
+	var domain common.Hash
+	copy(domain[:], domainType[:])
+	copy(domain[4:], forkDataRoot[:28])
+	return domain
+}
+
+// signingRoot computes the root to sign for an object in a signing domain.
+func signingRoot(objectRoot common.Hash, domain common.Hash) common.Hash {
+	return containerRoot(objectRoot, domain)
+}
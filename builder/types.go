@@ -0,0 +1,292 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/beacon"
+)
+
+// Quantity is an uint64 encoded as a decimal string, as done by the consensus
+// layer APIs.
+type Quantity uint64
+
+// MarshalText implements encoding.TextMarshaler.
+func (q Quantity) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(q), 10)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (q *Quantity) UnmarshalText(input []byte) error {
+	v, err := strconv.ParseUint(string(input), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quantity %q", input)
+	}
+	*q = Quantity(v)
+	return nil
+}
+
+// BigQuantity is an uint256 encoded as a decimal string, as done by the consensus
+// layer APIs.
+type BigQuantity big.Int
+
+// MarshalText implements encoding.TextMarshaler.
+func (q *BigQuantity) MarshalText() ([]byte, error) {
+	return []byte((*big.Int)(q).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (q *BigQuantity) UnmarshalText(input []byte) error {
+	v, ok := new(big.Int).SetString(string(input), 10)
+	if !ok || v.Sign() < 0 || v.BitLen() > 256 {
+		return fmt.Errorf("invalid quantity %q", input)
+	}
+	*q = BigQuantity(*v)
+	return nil
+}
+
+// ValidatorRegistration is the preference of a validator for the payloads built
+// for it.
+type ValidatorRegistration struct {
+	FeeRecipient common.Address `json:"fee_recipient"`
+	GasLimit     Quantity       `json:"gas_limit"`
+	Timestamp    Quantity       `json:"timestamp"`
+	Pubkey       hexutil.Bytes  `json:"pubkey"`
+}
+
+// hashTreeRoot computes the SSZ root of the registration.
+func (r *ValidatorRegistration) hashTreeRoot() common.Hash {
+	return containerRoot(
+		vectorRoot(r.FeeRecipient[:]),
+		uint64Root(uint64(r.GasLimit)),
+		uint64Root(uint64(r.Timestamp)),
+		vectorRoot(r.Pubkey),
+	)
+}
+
+// SignedValidatorRegistration is a registration signed by the validator.
+type SignedValidatorRegistration struct {
+	Message   *ValidatorRegistration `json:"message"`
+	Signature hexutil.Bytes          `json:"signature"`
+}
+
+// ExecutionPayloadHeader is an execution payload with the transactions and
+// withdrawals replaced by their roots.
+type ExecutionPayloadHeader struct {
+	ParentHash       common.Hash    `json:"parent_hash"`
+	FeeRecipient     common.Address `json:"fee_recipient"`
+	StateRoot        common.Hash    `json:"state_root"`
+	ReceiptsRoot     common.Hash    `json:"receipts_root"`
+	LogsBloom        hexutil.Bytes  `json:"logs_bloom"`
+	PrevRandao       common.Hash    `json:"prev_randao"`
+	BlockNumber      Quantity       `json:"block_number"`
+	GasLimit         Quantity       `json:"gas_limit"`
+	GasUsed          Quantity       `json:"gas_used"`
+	Timestamp        Quantity       `json:"timestamp"`
+	ExtraData        hexutil.Bytes  `json:"extra_data"`
+	BaseFeePerGas    *BigQuantity   `json:"base_fee_per_gas"`
+	BlockHash        common.Hash    `json:"block_hash"`
+	TransactionsRoot common.Hash    `json:"transactions_root"`
+	WithdrawalsRoot  *common.Hash   `json:"withdrawals_root,omitempty"` // Since capella
+}
+
+// newExecutionPayloadHeader creates the header of an execution payload.
+func newExecutionPayloadHeader(data *beacon.ExecutableData) *ExecutionPayloadHeader {
+	txs := make([]common.Hash, len(data.Transactions))
+	for i, tx := range data.Transactions {
+		txs[i] = listRoot(tx, maxBytesPerTransaction)
+	}
+	header := &ExecutionPayloadHeader{
+		ParentHash:       data.ParentHash,
+		FeeRecipient:     data.FeeRecipient,
+		StateRoot:        data.StateRoot,
+		ReceiptsRoot:     data.ReceiptsRoot,
+		LogsBloom:        data.LogsBloom,
+		PrevRandao:       data.Random,
+		BlockNumber:      Quantity(data.Number),
+		GasLimit:         Quantity(data.GasLimit),
+		GasUsed:          Quantity(data.GasUsed),
+		Timestamp:        Quantity(data.Timestamp),
+		ExtraData:        data.ExtraData,
+		BaseFeePerGas:    (*BigQuantity)(data.BaseFeePerGas),
+		BlockHash:        data.BlockHash,
+		TransactionsRoot: mixInLength(merkleize(txs, maxTransactionsPerBlock), len(txs)),
+	}
+	if data.Withdrawals != nil {
+		ws := make([]common.Hash, len(data.Withdrawals))
+		for i, w := range data.Withdrawals {
+			ws[i] = containerRoot(
+				uint64Root(w.Index),
+				uint64Root(w.Validator),
+				vectorRoot(w.Address[:]),
+				uint64Root(w.Amount),
+			)
+		}
+		root := mixInLength(merkleize(ws, maxWithdrawalsPerBlock), len(ws))
+		header.WithdrawalsRoot = &root
+	}
+	return header
+}
+
+// hashTreeRoot computes the SSZ root of the header.
+func (h *ExecutionPayloadHeader) hashTreeRoot() common.Hash {
+	fields := []common.Hash{
+		h.ParentHash,
+		vectorRoot(h.FeeRecipient[:]),
+		h.StateRoot,
+		h.ReceiptsRoot,
+		vectorRoot(h.LogsBloom),
+		h.PrevRandao,
+		uint64Root(uint64(h.BlockNumber)),
+		uint64Root(uint64(h.GasLimit)),
+		uint64Root(uint64(h.GasUsed)),
+		uint64Root(uint64(h.Timestamp)),
+		listRoot(h.ExtraData, maxExtraDataBytes),
+		uint256Root((*big.Int)(h.BaseFeePerGas)),
+		h.BlockHash,
+		h.TransactionsRoot,
+	}
+	if h.WithdrawalsRoot != nil {
+		fields = append(fields, *h.WithdrawalsRoot)
+	}
+	return containerRoot(fields...)
+}
+
+// BuilderBid is the offer of a builder to deliver a payload.
+type BuilderBid struct {
+	Header *ExecutionPayloadHeader `json:"header"`
+	Value  *BigQuantity            `json:"value"`
+	Pubkey hexutil.Bytes           `json:"pubkey"`
+}
+
+// hashTreeRoot computes the SSZ root of the bid.
+func (b *BuilderBid) hashTreeRoot() common.Hash {
+	return containerRoot(
+		b.Header.hashTreeRoot(),
+		uint256Root((*big.Int)(b.Value)),
+		vectorRoot(b.Pubkey),
+	)
+}
+
+// SignedBuilderBid is a bid signed by the builder.
+type SignedBuilderBid struct {
+	Message   *BuilderBid   `json:"message"`
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// Withdrawal is a withdrawal processed by an execution payload.
+type Withdrawal struct {
+	Index          Quantity       `json:"index"`
+	ValidatorIndex Quantity       `json:"validator_index"`
+	Address        common.Address `json:"address"`
+	Amount         Quantity       `json:"amount"`
+}
+
+// ExecutionPayload is a payload revealed by the builder once the proposer has
+// committed to its header.
+type ExecutionPayload struct {
+	ParentHash    common.Hash     `json:"parent_hash"`
+	FeeRecipient  common.Address  `json:"fee_recipient"`
+	StateRoot     common.Hash     `json:"state_root"`
+	ReceiptsRoot  common.Hash     `json:"receipts_root"`
+	LogsBloom     hexutil.Bytes   `json:"logs_bloom"`
+	PrevRandao    common.Hash     `json:"prev_randao"`
+	BlockNumber   Quantity        `json:"block_number"`
+	GasLimit      Quantity        `json:"gas_limit"`
+	GasUsed       Quantity        `json:"gas_used"`
+	Timestamp     Quantity        `json:"timestamp"`
+	ExtraData     hexutil.Bytes   `json:"extra_data"`
+	BaseFeePerGas *BigQuantity    `json:"base_fee_per_gas"`
+	BlockHash     common.Hash     `json:"block_hash"`
+	Transactions  []hexutil.Bytes `json:"transactions"`
+	Withdrawals   []*Withdrawal   `json:"withdrawals,omitempty"` // Since capella
+}
+
+// newExecutionPayload converts an execution payload to its builder API form.
+func newExecutionPayload(data *beacon.ExecutableData) *ExecutionPayload {
+	payload := &ExecutionPayload{
+		ParentHash:    data.ParentHash,
+		FeeRecipient:  data.FeeRecipient,
+		StateRoot:     data.StateRoot,
+		ReceiptsRoot:  data.ReceiptsRoot,
+		LogsBloom:     data.LogsBloom,
+		PrevRandao:    data.Random,
+		BlockNumber:   Quantity(data.Number),
+		GasLimit:      Quantity(data.GasLimit),
+		GasUsed:       Quantity(data.GasUsed),
+		Timestamp:     Quantity(data.Timestamp),
+		ExtraData:     data.ExtraData,
+		BaseFeePerGas: (*BigQuantity)(data.BaseFeePerGas),
+		BlockHash:     data.BlockHash,
+		Transactions:  make([]hexutil.Bytes, len(data.Transactions)),
+	}
+	for i, tx := range data.Transactions {
+		payload.Transactions[i] = tx
+	}
+	if data.Withdrawals != nil {
+		payload.Withdrawals = make([]*Withdrawal, len(data.Withdrawals))
+		for i, w := range data.Withdrawals {
+			payload.Withdrawals[i] = &Withdrawal{
+				Index:          Quantity(w.Index),
+				ValidatorIndex: Quantity(w.Validator),
+				Address:        w.Address,
+				Amount:         Quantity(w.Amount),
+			}
+		}
+	}
+	return payload
+}
+
+// BlindedBeaconBlock is a beacon block committing to an execution payload header
+// instead of the payload. Only the fields relevant to the builder are decoded.
+type BlindedBeaconBlock struct {
+	Slot          Quantity                `json:"slot"`
+	ProposerIndex Quantity                `json:"proposer_index"`
+	ParentRoot    common.Hash             `json:"parent_root"`
+	StateRoot     common.Hash             `json:"state_root"`
+	Body          *BlindedBeaconBlockBody `json:"body"`
+}
+
+// BlindedBeaconBlockBody is the body of a blinded beacon block. Only the fields
+// relevant to the builder are decoded.
+type BlindedBeaconBlockBody struct {
+	ExecutionPayloadHeader *ExecutionPayloadHeader `json:"execution_payload_header"`
+}
+
+// SignedBlindedBeaconBlock is a blinded beacon block signed by its proposer.
+type SignedBlindedBeaconBlock struct {
+	Message   *BlindedBeaconBlock `json:"message"`
+	Signature hexutil.Bytes       `json:"signature"`
+}
+
+// versionedResponse is the envelope of the builder API responses, specifying
+// the fork the data belongs to.
+type versionedResponse struct {
+	Version string      `json:"version"`
+	Data    interface{} `json:"data"`
+}
+
+// errorResponse is the body of the builder API error responses.
+type errorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
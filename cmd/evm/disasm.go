@@ -17,12 +17,14 @@
 package main
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/urfave/cli/v2"
 )
 
@@ -51,5 +53,36 @@ func disasmCmd(ctx *cli.Context) error {
 
 	code := strings.TrimSpace(in)
 	fmt.Printf("%v\n", code)
+
+	// EOF containers are disassembled section by section, as the code of each
+	// section is framed by the container header.
+	if script, err := hex.DecodeString(code); err == nil && vm.HasEOFMagic(script) {
+		return disasmEOF(script)
+	}
 	return asm.PrintDisassembled(code)
 }
+
+// disasmEOF pretty-prints the layout of an EOF container and the disassembled
+// instructions of each of its code sections.
+func disasmEOF(script []byte) error {
+	var c vm.Container
+	if err := c.UnmarshalBinary(script); err != nil {
+		return err
+	}
+	fmt.Print(c.String())
+	for i, section := range c.Code {
+		fmt.Printf("Code section %d:\n", i)
+		it := asm.NewEOFInstructionIterator(section)
+		for it.Next() {
+			if it.Arg() != nil && 0 < len(it.Arg()) {
+				fmt.Printf("%05x: %v %#x\n", it.PC(), it.Op(), it.Arg())
+			} else {
+				fmt.Printf("%05x: %v\n", it.PC(), it.Op())
+			}
+		}
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/t8ntool"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/tests"
+	"github.com/urfave/cli/v2"
+)
+
+var eofValidateCommand = &cli.Command{
+	Action:    eofValidateCmd,
+	Name:      "eofvalidate",
+	Usage:     "validates EOF containers, one hex encoded container per line",
+	ArgsUsage: "<file>",
+	Flags: []cli.Flag{
+		InputFlag,
+		t8ntool.ForknameFlag,
+	},
+}
+
+func eofValidateCmd(ctx *cli.Context) error {
+	var in io.Reader
+	switch {
+	case len(ctx.Args().First()) > 0:
+		f, err := os.Open(ctx.Args().First())
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	case ctx.IsSet(InputFlag.Name):
+		in = strings.NewReader(ctx.String(InputFlag.Name))
+	default:
+		return errors.New("missing filename or --input value")
+	}
+	// Validate against the instruction set of the selected fork, with EOF
+	// activated on top of it.
+	config, eips, err := tests.GetChainConfig(ctx.String(t8ntool.ForknameFlag.Name))
+	if err != nil {
+		return err
+	}
+	jt := vm.LookupInstructionSet(config.Rules(common.Big0, config.TerminalTotalDifficultyPassed, 0))
+	for _, eip := range append(eips, 3540) {
+		if err := vm.EnableEIP(eip, &jt); err != nil {
+			return err
+		}
+	}
+	var (
+		scanner = bufio.NewScanner(in)
+		total   int
+		invalid int
+	)
+	scanner.Buffer(make([]byte, 1024*1024), 2*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		total++
+		if err := validateEOFContainer(line, &jt); err != nil {
+			invalid++
+			fmt.Printf("err: %v\n", err)
+			continue
+		}
+		fmt.Println("OK")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d containers invalid", invalid, total)
+	}
+	return nil
+}
+
+// validateEOFContainer decodes a hex encoded EOF container and validates it
+// against the given instruction set.
+func validateEOFContainer(hexcode string, jt *vm.JumpTable) error {
+	if !strings.HasPrefix(hexcode, "0x") {
+		hexcode = "0x" + hexcode
+	}
+	code, err := hexutil.Decode(hexcode)
+	if err != nil {
+		return fmt.Errorf("unable to decode data: %w", err)
+	}
+	var c vm.Container
+	if err := c.UnmarshalBinary(code); err != nil {
+		return err
+	}
+	return c.ValidateCode(jt)
+}
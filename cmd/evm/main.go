@@ -216,6 +216,7 @@ func init() {
 	app.Commands = []*cli.Command{
 		compileCommand,
 		disasmCommand,
+		eofValidateCommand,
 		runCommand,
 		stateTestCommand,
 		stateTransitionCommand,
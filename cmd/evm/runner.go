@@ -25,7 +25,9 @@ import (
 	"os"
 	goruntime "runtime"
 	"runtime/pprof"
+	"sort"
 	"testing"
+	"text/tabwriter"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/evm/internal/compiler"
@@ -273,6 +275,17 @@ func runCmd(ctx *cli.Context) error {
 	bench := ctx.Bool(BenchFlag.Name)
 	output, leftOverGas, stats, err := timedExec(bench, execFunc)
 
+	// Profile a separate run when benchmarking, as profiling slows the execution
+	// down and would skew the measured times.
+	var profile *vm.Profile
+	if bench {
+		profiler := vm.NewProfiler()
+		runtimeConfig.EVMConfig.Profiler = profiler
+		execFunc()
+		runtimeConfig.EVMConfig.Profiler = nil
+		profile = profiler.Profile()
+	}
+
 	if ctx.Bool(DumpFlag.Name) {
 		statedb.Commit(true)
 		statedb.IntermediateRoot(true)
@@ -308,6 +321,9 @@ allocations:     %d
 allocated bytes: %d
 `, initialGas-leftOverGas, stats.time, stats.allocs, stats.bytesAllocated)
 	}
+	if profile != nil {
+		printProfile(os.Stderr, profile)
+	}
 	if tracer == nil {
 		fmt.Printf("%#x\n", output)
 		if err != nil {
@@ -317,3 +333,43 @@ allocated bytes: %d
 
 	return nil
 }
+
+// printProfile writes the breakdown of an EVM execution profile, ordered by the
+// time spent.
+func printProfile(w io.Writer, profile *vm.Profile) {
+	type entry struct {
+		name  string
+		stats vm.ProfileStats
+	}
+	section := func(title string, entries []entry) {
+		if len(entries) == 0 {
+			return
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].stats.Time != entries[j].stats.Time {
+				return entries[i].stats.Time > entries[j].stats.Time
+			}
+			return entries[i].name < entries[j].name
+		})
+		fmt.Fprintf(w, "#### %s ####\n", title)
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "\tcount\tgas\ttime\t")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t\n", e.name, e.stats.Count, e.stats.Gas, e.stats.Time)
+		}
+		tw.Flush()
+	}
+	var opcodes, contracts, precompiles []entry
+	for op, stats := range profile.Opcodes {
+		opcodes = append(opcodes, entry{op, stats})
+	}
+	for hash, stats := range profile.Contracts {
+		contracts = append(contracts, entry{hash.Hex(), stats})
+	}
+	for addr, stats := range profile.Precompiles {
+		precompiles = append(precompiles, entry{addr.Hex(), stats})
+	}
+	section("OPCODES", opcodes)
+	section("CONTRACTS (by code hash)", contracts)
+	section("PRECOMPILES", precompiles)
+}
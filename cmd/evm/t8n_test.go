@@ -243,6 +243,14 @@ func TestT8n(t *testing.T) {
 			output:      t8nOutput{alloc: false, result: false},
 			expExitCode: 3,
 		},
+		{ // Test transient storage (EIP-1153) being cleared between transactions
+			base: "./testdata/25",
+			input: t8nInput{
+				"alloc.json", "txs.json", "env.json", "Cancun", "",
+			},
+			output: t8nOutput{alloc: true, result: true},
+			expOut: "exp.json",
+		},
 	} {
 		args := []string{"t8n"}
 		args = append(args, tc.output.get()...)
@@ -512,3 +520,35 @@ func cmpJson(a, b []byte) (bool, error) {
 	}
 	return reflect.DeepEqual(j2, j), nil
 }
+
+func TestEOFValidate(t *testing.T) {
+	tt := new(testT8n)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+	for i, tc := range []struct {
+		input       string
+		expExitCode int
+		expOut      string
+	}{
+		{
+			input:  "./testdata/26/valid.txt",
+			expOut: "OK\nOK\n",
+		},
+		{
+			input:       "./testdata/26/invalid.txt",
+			expExitCode: 1,
+			expOut:      "err: invalid container size: have 21, want 20\nerr: invalid code termination: end with PUSH1, pos 2\n",
+		},
+	} {
+		args := []string{"eofvalidate", "--state.fork", "Cancun", tc.input}
+
+		tt.Run("evm-test", args...)
+		tt.Logf("args:\n go run . %v\n", strings.Join(args, " "))
+		if have := string(tt.Output()); have != tc.expOut {
+			t.Fatalf("test %d: output wrong, have \n%v\nwant\n%v\n", i, have, tc.expOut)
+		}
+		tt.WaitExit()
+		if have, want := tt.ExitStatus(), tc.expExitCode; have != want {
+			t.Fatalf("test %d: wrong exit code, have %d, want %d", i, have, want)
+		}
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/eth/catalyst"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
@@ -165,6 +166,10 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		override := ctx.Bool(utils.OverrideTerminalTotalDifficultyPassed.Name)
 		cfg.Eth.OverrideTerminalTotalDifficultyPassed = &override
 	}
+	if ctx.IsSet(utils.OverrideShanghai.Name) {
+		override := ctx.Uint64(utils.OverrideShanghai.Name)
+		cfg.Eth.OverrideShanghai = &override
+	}
 
 	backend, eth := utils.RegisterEthService(stack, &cfg.Eth)
 
@@ -187,6 +192,21 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		}
 	}
 
+	// Drive the developer chain with a simulated beacon client.
+	if eth != nil && ctx.Bool(utils.DeveloperFlag.Name) && !ctx.Bool(utils.ReadOnlyFlag.Name) {
+		simBeacon, err := catalyst.NewSimulatedBeacon(uint64(ctx.Int(utils.DeveloperPeriodFlag.Name)), eth)
+		if err != nil {
+			utils.Fatalf("Failed to register the simulated beacon: %v", err)
+		}
+		catalyst.RegisterSimulatedBeaconAPIs(stack, simBeacon)
+		stack.RegisterLifecycle(simBeacon)
+	}
+
+	// Serve the builder API if requested.
+	if eth != nil && ctx.Bool(utils.BuilderEnabledFlag.Name) {
+		utils.RegisterBuilderService(ctx, stack, eth)
+	}
+
 	// Configure log filter RPC API.
 	filterSystem := utils.RegisterFilterAPI(stack, backend, &cfg.Eth)
 
@@ -18,7 +18,12 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -31,11 +36,14 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core/backup"
+	"github.com/ethereum/go-ethereum/core/integrity"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/remotefreezer"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/trie"
@@ -44,6 +52,40 @@ import (
 )
 
 var (
+	verifyRootsFlag = &cli.IntFlag{
+		Name:  "verify.roots",
+		Usage: "Number of recent state roots to verify (0 = skip state checks)",
+		Value: integrity.DefaultConfig.Roots,
+	}
+	verifySkipFreezerFlag = &cli.BoolFlag{
+		Name:  "verify.skipfreezer",
+		Usage: "Skip the verification of the ancient store",
+	}
+	verifySkipCanonicalFlag = &cli.BoolFlag{
+		Name:  "verify.skipcanonical",
+		Usage: "Skip the verification of the canonical hash mappings",
+	}
+	verifyMaxIssuesFlag = &cli.IntFlag{
+		Name:  "verify.maxissues",
+		Usage: "Maximum number of issues to include in the report (0 = unlimited)",
+		Value: integrity.DefaultConfig.MaxIssues,
+	}
+	verifyOutputFlag = &cli.StringFlag{
+		Name:  "verify.output",
+		Usage: "File to write the JSON report into (default = stdout)",
+	}
+	backupFullFlag = &cli.BoolFlag{
+		Name:  "backup.full",
+		Usage: "Store all data instead of only the changes since the latest backup",
+	}
+	recompressDictFlag = &cli.StringFlag{
+		Name:  "dict",
+		Usage: "File holding the zstd dictionary to compress the table with, trained or raw content",
+	}
+	recompressDictSizeFlag = &cli.IntFlag{
+		Name:  "dict.size",
+		Usage: "Size of the zstd dictionary to sample from the table (0 = no dictionary)",
+	}
 	removedbCommand = &cli.Command{
 		Action:    removeDB,
 		Name:      "removedb",
@@ -59,6 +101,7 @@ Remove blockchain and state databases`,
 		ArgsUsage: "",
 		Subcommands: []*cli.Command{
 			dbInspectCmd,
+			dbVerifyCmd,
 			dbStatCmd,
 			dbCompactCmd,
 			dbGetCmd,
@@ -70,6 +113,10 @@ Remove blockchain and state databases`,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbMigrateFreezerCmd,
+			dbFreezerServeCmd,
+			dbFreezerRecompressCmd,
+			dbBackupCmd,
+			dbRestoreCmd,
 			dbCheckStateContentCmd,
 		},
 	}
@@ -92,6 +139,26 @@ Remove blockchain and state databases`,
 		Description: `This command iterates the entire database for 32-byte keys, looking for rlp-encoded trie nodes.
 For each trie node encountered, it checks that the key corresponds to the keccak256(value). If this is not true, this indicates
 a data corruption.`,
+	}
+	dbVerifyCmd = &cli.Command{
+		Action: verifyDatabase,
+		Name:   "verify",
+		Usage:  "Verify the integrity of the trie, snapshot, freezer and canonical chain data",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			verifyRootsFlag,
+			verifySkipFreezerFlag,
+			verifySkipCanonicalFlag,
+			verifyMaxIssuesFlag,
+			verifyOutputFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command walks the state tries of the most recent blocks, re-hashing every node
+and cross-checking the accounts and storage slots against the snapshot. It then
+validates every item of the ancient store against its index and the canonical
+hashes, and finally checks the canonical hash mappings against the headers.
+
+The outcome is written as a JSON report. The command fails if any corruption
+was found.`,
 	}
 	dbStatCmd = &cli.Command{
 		Action: dbStats,
@@ -206,6 +273,59 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		Description: `The freezer-migrate command checks your database for receipts in a legacy format and updates those.
 WARNING: please back-up the receipt files in your ancients before running this command.`,
 	}
+	dbFreezerServeCmd = &cli.Command{
+		Action:    freezerServe,
+		Name:      "freezer-serve",
+		Usage:     "Serve the ancient chain data over RPC as a standalone freezer server",
+		ArgsUsage: "<listen address>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `The freezer-serve command opens the ancient chain data and serves it over HTTP
+under the freezer_ RPC namespace, e.g. 'geth db freezer-serve 127.0.0.1:8549'.
+Nodes can place their freezer behind the server with --datadir.ancient.remote.`,
+	}
+	dbFreezerRecompressCmd = &cli.Command{
+		Action:    freezerRecompress,
+		Name:      "freezer-recompress",
+		Usage:     "Rewrite a chain freezer table with a different compression codec",
+		ArgsUsage: "<table> <codec>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			recompressDictFlag,
+			recompressDictSizeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `The freezer-recompress command rewrites all items of a chain freezer table
+(e.g. 'receipts' or 'bodies') with the given codec, which is one of 'snappy' or
+'zstd'. Zstd can use a dictionary, either loaded from a file with --dict (as
+trained by 'zstd --train') or sampled from the table itself with --dict.size.
+Tables stored uncompressed can't be recompressed. Running nodes can recompress
+their tables without downtime with the admin_recompressFreezer RPC method.`,
+	}
+	dbBackupCmd = &cli.Command{
+		Action:    backupDB,
+		Name:      "backup",
+		Usage:     "Create an incremental backup of the chain database",
+		ArgsUsage: "<backup directory>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			backupFullFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `The backup command stores a consistent checkpoint of the key-value store and
+the chain freezer as a new numbered backup within the given directory. Only the
+key-value ranges and freezer files changed since the latest backup in the
+directory are stored, unless --backup.full is set. Running nodes can be backed
+up with the admin_backup RPC method instead.`,
+	}
+	dbRestoreCmd = &cli.Command{
+		Action:    restoreDB,
+		Name:      "restore",
+		Usage:     "Restore the chain database from a backup",
+		ArgsUsage: "<backup directory> [<backup id>]",
+		Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `The restore command writes the given backup (or the latest one if no id is
+specified) into the chain database of the data directory, which must be empty.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -845,3 +965,178 @@ func dbHasLegacyReceipts(db ethdb.Database, firstIdx uint64) (bool, uint64, erro
 	legacy, err = types.IsLegacyStoredReceipts(first)
 	return legacy, firstIdx, err
 }
+
+func freezerServe(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	store, err := rawdb.NewChainAncientStore(ancient, "", false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	handler, err := remotefreezer.NewServer(store)
+	if err != nil {
+		return err
+	}
+	defer handler.Stop()
+
+	listener, err := net.Listen("tcp", ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	defer server.Close()
+
+	frozen, _ := store.Ancients()
+	log.Info("Freezer server started", "endpoint", "http://"+listener.Addr().String(), "ancient", ancient, "items", frozen)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	<-sigc
+
+	log.Info("Freezer server shutting down")
+	return store.Sync()
+}
+
+func freezerRecompress(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var dict []byte
+	if path := ctx.String(recompressDictFlag.Name); path != "" {
+		blob, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dict = blob
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	store, err := rawdb.NewChainAncientStore(stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name)), "", false)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	table := ctx.Args().Get(0)
+	if size := ctx.Int(recompressDictSizeFlag.Name); dict == nil && size > 0 {
+		if dict, err = store.SampleDictionary(table, size); err != nil {
+			return err
+		}
+		log.Info("Sampled compression dictionary", "table", table, "size", len(dict))
+	}
+	return store.RecompressTable(table, ctx.Args().Get(1), dict)
+}
+
+func backupDB(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	manifest, err := backup.Backup(db, ctx.Args().First(), ctx.Bool(backupFullFlag.Name))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backup %d created: %d/%d ranges and %d/%d freezer files stored (%v)\n", manifest.ID,
+		manifest.ShippedRanges, len(manifest.Ranges), manifest.ShippedFiles, len(manifest.Files), common.StorageSize(manifest.ShippedBytes))
+	return nil
+}
+
+func restoreDB(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var id *uint64
+	if ctx.NArg() == 2 {
+		number, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid backup id: %v", err)
+		}
+		id = &number
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db, err := stack.OpenDatabase("chaindata", ctx.Int(utils.CacheFlag.Name)*ctx.Int(utils.CacheDatabaseFlag.Name)/100,
+		utils.MakeDatabaseHandles(ctx.Int(utils.FDLimitFlag.Name)), "", false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	manifest, err := backup.Restore(ctx.Args().First(), id, db, stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name)))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backup %d restored, head %x\n", manifest.ID, manifest.Head)
+	return nil
+}
+
+func verifyDatabase(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	config := integrity.Config{
+		Roots:         ctx.Int(verifyRootsFlag.Name),
+		SkipFreezer:   ctx.Bool(verifySkipFreezerFlag.Name),
+		SkipCanonical: ctx.Bool(verifySkipCanonicalFlag.Name),
+		MaxIssues:     ctx.Int(verifyMaxIssuesFlag.Name),
+	}
+	var (
+		triedb = trie.NewDatabase(db)
+		snaps  *snapshot.Tree
+	)
+	if head := rawdb.ReadHeadBlock(db); head != nil && config.Roots > 0 {
+		snapconfig := snapshot.Config{
+			CacheSize:  256,
+			Recovery:   false,
+			NoBuild:    true,
+			AsyncBuild: false,
+		}
+		var err error
+		if snaps, err = snapshot.New(snapconfig, db, triedb, head.Root()); err != nil {
+			log.Warn("Snapshot unavailable, skipping cross-checks", "err", err)
+			snaps = nil
+		}
+	}
+	report := integrity.New(db, triedb, snaps, config).Run(context.Background())
+
+	out := os.Stdout
+	if path := ctx.String(verifyOutputFlag.Name); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if report.Error != "" {
+		return errors.New(report.Error)
+	}
+	if len(report.Issues) > 0 {
+		return fmt.Errorf("database corrupted, %d issue(s) found", len(report.Issues))
+	}
+	return nil
+}
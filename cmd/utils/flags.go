@@ -34,6 +34,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/builder"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
@@ -99,6 +100,22 @@ var (
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	AncientRemoteFlag = &cli.StringFlag{
+		Name:     "datadir.ancient.remote",
+		Usage:    "RPC endpoint of a remote freezer server storing the ancient data (overrides --datadir.ancient)",
+		Category: flags.EthCategory,
+	}
+	ReadOnlyFlag = &cli.BoolFlag{
+		Name:     "readonly",
+		Usage:    "Serve RPC requests as a read-only secondary instance on top of the datadir of a running node",
+		Category: flags.EthCategory,
+	}
+	ReadOnlyRefreshFlag = &cli.DurationFlag{
+		Name:     "readonly.refresh",
+		Usage:    "Time interval for read-only instances to catch up with the running node",
+		Value:    node.DefaultReadOnlyRefresh,
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -160,12 +177,12 @@ var (
 	// Dev mode
 	DeveloperFlag = &cli.BoolFlag{
 		Name:     "dev",
-		Usage:    "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage:    "Ephemeral proof-of-stake network with a pre-funded developer account, driven by a simulated beacon client",
 		Category: flags.DevCategory,
 	}
 	DeveloperPeriodFlag = &cli.IntFlag{
 		Name:     "dev.period",
-		Usage:    "Block period to use in developer mode (0 = mine only if transaction pending)",
+		Usage:    "Block period in seconds to use in developer mode (0 = produce a block when a transaction arrives)",
 		Category: flags.DevCategory,
 	}
 	DeveloperGasLimitFlag = &cli.Uint64Flag{
@@ -277,6 +294,11 @@ var (
 		Usage:    "Manually specify TerminalTotalDifficultyPassed, overriding the bundled setting",
 		Category: flags.EthCategory,
 	}
+	OverrideShanghai = &cli.Uint64Flag{
+		Name:     "override.shanghai",
+		Usage:    "Manually specify the Shanghai fork timestamp, overriding the bundled setting",
+		Category: flags.EthCategory,
+	}
 	// Light server and client settings
 	LightServeFlag = &cli.IntFlag{
 		Name:     "light.serve",
@@ -400,6 +422,23 @@ var (
 		Value:    txpool.DefaultConfig.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolFullJournalFlag = &cli.StringFlag{
+		Name:     "txpool.fulljournal",
+		Usage:    "Disk journal for all pooled transactions, remote and queued ones included, to survive node restarts (disabled if empty)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolFullRejournalFlag = &cli.DurationFlag{
+		Name:     "txpool.fullrejournal",
+		Usage:    "Time interval to regenerate the full transaction pool journal",
+		Value:    txpool.DefaultConfig.FullRejournal,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolFullJournalAgeFlag = &cli.DurationFlag{
+		Name:     "txpool.fulljournalage",
+		Usage:    "Maximum age of the transactions reloaded from the full transaction pool journal",
+		Value:    txpool.DefaultConfig.FullJournalAge,
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
 		Name:     "txpool.pricelimit",
 		Usage:    "Minimum gas price limit to enforce for acceptance into the pool",
@@ -442,6 +481,39 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolHistoryFlag = &cli.Uint64Flag{
+		Name:     "txpool.history",
+		Usage:    "Number of transactions to retain the lifecycle events of (0 = disabled)",
+		Value:    ethconfig.Defaults.TxPool.History,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolAllowSendersFlag = &cli.StringFlag{
+		Name:     "txpool.allowsenders",
+		Usage:    "Comma separated accounts exclusively accepted as transaction senders",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolDenySendersFlag = &cli.StringFlag{
+		Name:     "txpool.denysenders",
+		Usage:    "Comma separated accounts whose transactions are rejected",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolDenyCallsFlag = &cli.StringFlag{
+		Name:     "txpool.denycalls",
+		Usage:    "Comma separated contract calls to reject, as address or address:selector",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSenderRateFlag = &cli.Uint64Flag{
+		Name:     "txpool.senderrate",
+		Usage:    "Maximum number of remote transactions accepted per sender per minute (0 = unlimited)",
+		Value:    ethconfig.Defaults.TxPool.Policy.SenderRate,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolMinFeeCapFlag = &cli.Uint64Flag{
+		Name:     "txpool.minfeecap",
+		Usage:    "Minimum fee cap to enforce for acceptance of remote transactions (0 = disabled)",
+		Value:    ethconfig.Defaults.TxPool.Policy.MinFeeCap,
+		Category: flags.TxPoolCategory,
+	}
 
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
@@ -491,6 +563,11 @@ var (
 		Usage:    "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
 		Category: flags.PerfCategory,
 	}
+	ParallelExecFlag = &cli.BoolFlag{
+		Name:     "parallelexec",
+		Usage:    "Execute the transactions of imported blocks speculatively in parallel (experimental)",
+		Category: flags.PerfCategory,
+	}
 	CachePreimagesFlag = &cli.BoolFlag{
 		Name:     "cache.preimages",
 		Usage:    "Enable recording the SHA3/keccak preimages of trie keys",
@@ -602,6 +679,16 @@ var (
 		Usage:    "Record information useful for VM and contract debugging",
 		Category: flags.VMCategory,
 	}
+	VMProfileFlag = &cli.BoolFlag{
+		Name:     "vmprofile",
+		Usage:    "Aggregate per-opcode, per-contract and per-precompile execution statistics of block processing",
+		Category: flags.VMCategory,
+	}
+	VMProgramsFlag = &cli.BoolFlag{
+		Name:     "vmprograms",
+		Usage:    "Run frequently executed contract code as cached, pre-decoded programs (experimental)",
+		Category: flags.VMCategory,
+	}
 
 	// API options.
 	RPCGlobalGasCapFlag = &cli.Uint64Flag{
@@ -739,6 +826,34 @@ var (
 		Value:    strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 		Category: flags.APICategory,
 	}
+	BuilderEnabledFlag = &cli.BoolFlag{
+		Name:     "builder",
+		Usage:    "Enable the builder API, serving payloads to MEV-boost style relays",
+		Category: flags.APICategory,
+	}
+	BuilderListenAddrFlag = &cli.StringFlag{
+		Name:     "builder.addr",
+		Usage:    "Builder API server listening interface",
+		Value:    builder.DefaultConfig.Host,
+		Category: flags.APICategory,
+	}
+	BuilderPortFlag = &cli.IntFlag{
+		Name:     "builder.port",
+		Usage:    "Builder API server listening port",
+		Value:    builder.DefaultConfig.Port,
+		Category: flags.APICategory,
+	}
+	BuilderSecretKeyFlag = &cli.StringFlag{
+		Name:     "builder.secretkey",
+		Usage:    "Path to a file containing the hex encoded BLS secret key signing the builder bids (ephemeral key if unset)",
+		Category: flags.APICategory,
+	}
+	BuilderGenesisForkVersionFlag = &cli.StringFlag{
+		Name:     "builder.genesisforkversion",
+		Usage:    "Genesis fork version of the beacon chain, part of the builder API signing domain",
+		Value:    "0x00000000",
+		Category: flags.APICategory,
+	}
 	WSEnabledFlag = &cli.BoolFlag{
 		Name:     "ws",
 		Usage:    "Enable the WS-RPC server",
@@ -897,6 +1012,12 @@ var (
 		Value:    ethconfig.Defaults.GPO.IgnorePrice.Int64(),
 		Category: flags.GasPriceCategory,
 	}
+	GpoEstimatorFlag = &cli.StringFlag{
+		Name:     "gpo.estimator",
+		Usage:    "Default fee estimation strategy of eth_feeEstimate (percentile, pool)",
+		Value:    ethconfig.Defaults.GPO.Estimator,
+		Category: flags.GasPriceCategory,
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = &cli.BoolFlag{
@@ -1010,6 +1131,7 @@ var (
 	DatabasePathFlags = []cli.Flag{
 		DataDirFlag,
 		AncientFlag,
+		AncientRemoteFlag,
 		RemoteDBFlag,
 		HttpHeaderFlag,
 	}
@@ -1263,6 +1385,19 @@ func setIPC(ctx *cli.Context, cfg *node.Config) {
 		cfg.IPCPath = ""
 	case ctx.IsSet(IPCPathFlag.Name):
 		cfg.IPCPath = ctx.String(IPCPathFlag.Name)
+	case cfg.ReadOnly:
+		// The default endpoint is owned by the primary instance
+		cfg.IPCPath = ""
+	}
+}
+
+// setReadOnly configures the read-only secondary mode from the command line flags.
+func setReadOnly(ctx *cli.Context, cfg *node.Config) {
+	if ctx.IsSet(ReadOnlyFlag.Name) {
+		cfg.ReadOnly = ctx.Bool(ReadOnlyFlag.Name)
+	}
+	if ctx.IsSet(ReadOnlyRefreshFlag.Name) {
+		cfg.ReadOnlyRefresh = ctx.Duration(ReadOnlyRefreshFlag.Name)
 	}
 }
 
@@ -1469,6 +1604,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 // SetNodeConfig applies node-related command line flags to the config.
 func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	SetP2PConfig(ctx, &cfg.P2P)
+	setReadOnly(ctx, cfg)
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
@@ -1572,6 +1708,9 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config, light bool) {
 	if ctx.IsSet(GpoIgnoreGasPriceFlag.Name) {
 		cfg.IgnorePrice = big.NewInt(ctx.Int64(GpoIgnoreGasPriceFlag.Name))
 	}
+	if ctx.IsSet(GpoEstimatorFlag.Name) {
+		cfg.Estimator = ctx.String(GpoEstimatorFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
@@ -1594,6 +1733,15 @@ func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
 	if ctx.IsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.IsSet(TxPoolFullJournalFlag.Name) {
+		cfg.FullJournal = ctx.String(TxPoolFullJournalFlag.Name)
+	}
+	if ctx.IsSet(TxPoolFullRejournalFlag.Name) {
+		cfg.FullRejournal = ctx.Duration(TxPoolFullRejournalFlag.Name)
+	}
+	if ctx.IsSet(TxPoolFullJournalAgeFlag.Name) {
+		cfg.FullJournalAge = ctx.Duration(TxPoolFullJournalAgeFlag.Name)
+	}
 	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1615,6 +1763,43 @@ func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolHistoryFlag.Name) {
+		cfg.History = ctx.Uint64(TxPoolHistoryFlag.Name)
+	}
+	if ctx.IsSet(TxPoolAllowSendersFlag.Name) {
+		cfg.Policy.AllowSenders = splitAccounts(ctx, TxPoolAllowSendersFlag.Name)
+	}
+	if ctx.IsSet(TxPoolDenySendersFlag.Name) {
+		cfg.Policy.DenySenders = splitAccounts(ctx, TxPoolDenySendersFlag.Name)
+	}
+	if ctx.IsSet(TxPoolDenyCallsFlag.Name) {
+		for _, rule := range strings.Split(ctx.String(TxPoolDenyCallsFlag.Name), ",") {
+			rule = strings.TrimSpace(rule)
+			if _, err := txpool.NewCallFilter([]string{rule}); err != nil {
+				Fatalf("Invalid rule in --%s: %v", TxPoolDenyCallsFlag.Name, err)
+			}
+			cfg.Policy.DenyCalls = append(cfg.Policy.DenyCalls, rule)
+		}
+	}
+	if ctx.IsSet(TxPoolSenderRateFlag.Name) {
+		cfg.Policy.SenderRate = ctx.Uint64(TxPoolSenderRateFlag.Name)
+	}
+	if ctx.IsSet(TxPoolMinFeeCapFlag.Name) {
+		cfg.Policy.MinFeeCap = ctx.Uint64(TxPoolMinFeeCapFlag.Name)
+	}
+}
+
+// splitAccounts parses the comma separated accounts of the given flag.
+func splitAccounts(ctx *cli.Context, name string) []common.Address {
+	var accounts []common.Address
+	for _, account := range strings.Split(ctx.String(name), ",") {
+		if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+			Fatalf("Invalid account in --%s: %s", name, trimmed)
+		} else {
+			accounts = append(accounts, common.HexToAddress(trimmed))
+		}
+	}
+	return accounts
 }
 
 func setEthash(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1796,6 +1981,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(AncientFlag.Name) {
 		cfg.DatabaseFreezer = ctx.String(AncientFlag.Name)
 	}
+	if ctx.IsSet(AncientRemoteFlag.Name) {
+		cfg.DatabaseFreezerRemote = ctx.String(AncientRemoteFlag.Name)
+	}
 
 	if gcmode := ctx.String(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1806,6 +1994,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.Bool(CacheNoPrefetchFlag.Name)
 	}
+	if ctx.IsSet(ParallelExecFlag.Name) {
+		cfg.ParallelExecution = ctx.Bool(ParallelExecFlag.Name)
+	}
 	// Read the value from the flag no matter if it's set or not.
 	cfg.Preimages = ctx.Bool(CachePreimagesFlag.Name)
 	if cfg.NoPruning && !cfg.Preimages {
@@ -1849,6 +2040,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.Bool(VMEnableDebugFlag.Name)
 	}
+	if ctx.IsSet(VMProfileFlag.Name) {
+		cfg.EnableEVMProfiling = ctx.Bool(VMProfileFlag.Name)
+	}
+	if ctx.IsSet(VMProgramsFlag.Name) {
+		cfg.EnableEVMPrograms = ctx.Bool(VMProgramsFlag.Name)
+	}
 
 	if ctx.IsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.Uint64(RPCGlobalGasCapFlag.Name)
@@ -1956,7 +2153,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Info("Using developer account", "address", developer.Address)
 
 		// Create a new developer genesis block or reuse existing one
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.Int(DeveloperPeriodFlag.Name)), ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address)
+		cfg.Genesis = core.DeveloperGenesisBlock(ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address)
 		if ctx.IsSet(DataDirFlag.Name) {
 			// If datadir doesn't exist we need to open db in write-mode
 			// so leveldb can create files.
@@ -2002,6 +2199,9 @@ func SetDNSDiscoveryDefaults(cfg *ethconfig.Config, genesis common.Hash) {
 // The second return value is the full node instance, which may be nil if the
 // node is running as a light client.
 func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend, *eth.Ethereum) {
+	if stack.Config().ReadOnly && (cfg.SyncMode == downloader.LightSync || cfg.DatabaseFreezerRemote != "") {
+		Fatalf("Read-only instances are not supported with light sync or a remote freezer")
+	}
 	if cfg.SyncMode == downloader.LightSync {
 		backend, err := les.New(stack, cfg)
 		if err != nil {
@@ -2017,6 +2217,12 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 	if err != nil {
 		Fatalf("Failed to register the Ethereum service: %v", err)
 	}
+	// Read-only instances follow the primary, they can't serve peers or be
+	// driven by a consensus client.
+	if stack.Config().ReadOnly {
+		stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+		return backend.APIBackend, backend
+	}
 	if cfg.LightServ > 0 {
 		_, err := les.NewLesServer(stack, backend, cfg)
 		if err != nil {
@@ -2045,6 +2251,29 @@ func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, filterSyst
 	}
 }
 
+// RegisterBuilderService adds the builder API to the node.
+func RegisterBuilderService(ctx *cli.Context, stack *node.Node, backend *eth.Ethereum) {
+	cfg := builder.DefaultConfig
+	cfg.Host = ctx.String(BuilderListenAddrFlag.Name)
+	cfg.Port = ctx.Int(BuilderPortFlag.Name)
+	if path := ctx.String(BuilderSecretKeyFlag.Name); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			Fatalf("Failed to read the builder secret key: %v", err)
+		}
+		cfg.SecretKey = common.FromHex(strings.TrimSpace(string(data)))
+	}
+	version := common.FromHex(ctx.String(BuilderGenesisForkVersionFlag.Name))
+	if len(version) != len(cfg.GenesisForkVersion) {
+		Fatalf("Invalid builder genesis fork version: %q", ctx.String(BuilderGenesisForkVersionFlag.Name))
+	}
+	copy(cfg.GenesisForkVersion[:], version)
+
+	if _, err := builder.New(stack, backend, cfg); err != nil {
+		Fatalf("Failed to register the builder API service: %v", err)
+	}
+}
+
 // RegisterFilterAPI adds the eth log filtering RPC API to the node.
 func RegisterFilterAPI(stack *node.Node, backend ethapi.Backend, ethcfg *ethconfig.Config) *filters.FilterSystem {
 	isLightClient := ethcfg.SyncMode == downloader.LightSync
@@ -2153,6 +2382,8 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 		chainDb = remotedb.New(client)
 	case ctx.String(SyncModeFlag.Name) == "light":
 		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles, "", readonly)
+	case ctx.IsSet(AncientRemoteFlag.Name):
+		chainDb, err = stack.OpenDatabaseWithRemoteFreezer("chaindata", cache, handles, ctx.String(AncientRemoteFlag.Name), "", readonly)
 	default:
 		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.String(AncientFlag.Name), "", readonly)
 	}
@@ -2238,6 +2469,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	cache := &core.CacheConfig{
 		TrieCleanLimit:      ethconfig.Defaults.TrieCleanCache,
 		TrieCleanNoPrefetch: ctx.Bool(CacheNoPrefetchFlag.Name),
+		ParallelExecution:   ctx.Bool(ParallelExecFlag.Name),
 		TrieDirtyLimit:      ethconfig.Defaults.TrieDirtyCache,
 		TrieDirtyDisabled:   ctx.String(GCModeFlag.Name) == "archive",
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
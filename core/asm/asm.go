@@ -32,6 +32,7 @@ type instructionIterator struct {
 	op      vm.OpCode
 	error   error
 	started bool
+	eof     bool
 }
 
 // NewInstructionIterator create a new instruction iterator.
@@ -41,6 +42,15 @@ func NewInstructionIterator(code []byte) *instructionIterator {
 	return it
 }
 
+// NewEOFInstructionIterator creates a new instruction iterator for a code
+// section of an EOF container, which is aware of the immediates of the EOF
+// instructions.
+func NewEOFInstructionIterator(code []byte) *instructionIterator {
+	it := NewInstructionIterator(code)
+	it.eof = true
+	return it
+}
+
 // Next returns true if there is a next instruction and moves on.
 func (it *instructionIterator) Next() bool {
 	if it.error != nil || uint64(len(it.code)) <= it.pc {
@@ -73,6 +83,24 @@ func (it *instructionIterator) Next() bool {
 			return false
 		}
 		it.arg = it.code[it.pc+1 : u]
+	} else if it.eof && (it.op == vm.RJUMP || it.op == vm.RJUMPI || it.op == vm.CALLF) {
+		u := it.pc + 3
+		if uint64(len(it.code)) < u {
+			it.error = fmt.Errorf("incomplete %v instruction at %v", it.op, it.pc)
+			return false
+		}
+		it.arg = it.code[it.pc+1 : u]
+	} else if it.eof && it.op == vm.RJUMPV {
+		if uint64(len(it.code)) <= it.pc+1 {
+			it.error = fmt.Errorf("incomplete RJUMPV instruction at %v", it.pc)
+			return false
+		}
+		u := it.pc + 2 + 2*uint64(it.code[it.pc+1])
+		if uint64(len(it.code)) < u {
+			it.error = fmt.Errorf("incomplete RJUMPV instruction at %v", it.pc)
+			return false
+		}
+		it.arg = it.code[it.pc+1 : u]
 	} else {
 		it.arg = nil
 	}
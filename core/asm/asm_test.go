@@ -72,3 +72,37 @@ func TestInstructionIteratorEmpty(t *testing.T) {
 		t.Errorf("Expected 0, but got %v instead.", cnt)
 	}
 }
+
+// Tests disassembling the instructions of an EOF code section
+func TestEOFInstructionIterator(t *testing.T) {
+	for i, tt := range []struct {
+		code string
+		cnt  int
+		err  bool
+	}{
+		{code: "e0000000", cnt: 2},           // RJUMP 0, STOP
+		{code: "6001e1fffbe30001e4", cnt: 4}, // PUSH1, RJUMPI, CALLF, RETF
+		{code: "5fe2020000000100", cnt: 3},   // PUSH0, RJUMPV, STOP
+		{code: "e300", err: true},
+		{code: "5fe2020000", err: true},
+	} {
+		script, _ := hex.DecodeString(tt.code)
+		cnt := 0
+		it := NewEOFInstructionIterator(script)
+		for it.Next() {
+			cnt++
+		}
+		if tt.err {
+			if it.Error() == nil {
+				t.Errorf("test %d: expected an error", i)
+			}
+			continue
+		}
+		if err := it.Error(); err != nil {
+			t.Errorf("test %d: unexpected error %v", i, err)
+		}
+		if cnt != tt.cnt {
+			t.Errorf("test %d: expected %d instructions, got %d", i, tt.cnt, cnt)
+		}
+	}
+}
@@ -0,0 +1,559 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package backup implements incremental hot backups of a chain database.
+//
+// A backup is a consistent checkpoint of the key-value store, taken through a
+// database snapshot, along with the content of the chain freezer up to the
+// number of items frozen at the time of the snapshot. Every backup lives in its
+// own numbered directory, described by a manifest:
+//
+//	<dir>/000000/manifest.json
+//	<dir>/000000/kv/<prefix>.rlp
+//	<dir>/000000/ancient/<freezer files>
+//
+// The key-value store is split into ranges by the first two bytes of the keys.
+// Every range is hashed and only shipped if it changed since the parent backup,
+// otherwise the manifest references the range stored in an earlier backup. The
+// freezer data files are append-only, so they're only shipped when their size
+// changed. Index and metadata files are always shipped.
+package backup
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	manifestName = "manifest.json" // Name of the manifest file within a backup
+	kvDir        = "kv"            // Directory holding the key-value ranges
+	ancientDir   = "ancient"       // Directory holding the freezer files
+	prefixLength = 2               // Number of key bytes determining the range
+)
+
+var (
+	// ErrNoBackup is returned if no backup is found in the backup directory.
+	ErrNoBackup = errors.New("no backup found")
+
+	// errNotEmpty is returned if the restore target already contains data.
+	errNotEmpty = errors.New("restore target is not empty")
+)
+
+// Range is a contiguous slice of the key-value store sharing the same prefix.
+type Range struct {
+	Prefix hexutil.Bytes `json:"prefix"`
+	Hash   common.Hash   `json:"hash"`   // Hash of the encoded entries
+	Items  uint64        `json:"items"`  // Number of entries in the range
+	Backup uint64        `json:"backup"` // Backup holding the range content
+}
+
+// File is a freezer file, identified by its path relative to the ancient root.
+type File struct {
+	Name    string `json:"name"`
+	Size    uint64 `json:"size"`
+	ModTime int64  `json:"modTime"` // Last modification time in nanoseconds
+	Backup  uint64 `json:"backup"`  // Backup holding the file content
+}
+
+// Manifest describes the content of a backup.
+type Manifest struct {
+	ID          uint64      `json:"id"`
+	Parent      *uint64     `json:"parent,omitempty"`
+	Time        time.Time   `json:"time"`
+	Head        common.Hash `json:"head"`
+	HeadNumber  *uint64     `json:"headNumber,omitempty"`
+	Ancients    uint64      `json:"ancients"`    // Number of frozen items at checkpoint time
+	AncientTail uint64      `json:"ancientTail"` // Number of pruned items at checkpoint time
+	Ranges      []*Range    `json:"ranges"`
+	Files       []*File     `json:"files"`
+
+	// Statistics about the data shipped in this backup
+	ShippedRanges int    `json:"shippedRanges"`
+	ShippedFiles  int    `json:"shippedFiles"`
+	ShippedBytes  uint64 `json:"shippedBytes"`
+}
+
+// backupName returns the directory name of the backup with the given id.
+func backupName(id uint64) string {
+	return fmt.Sprintf("%06d", id)
+}
+
+// List returns the ids of the backups in the given directory, in ascending
+// order. Incomplete backups are ignored.
+func List(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []uint64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseUint(entry.Name(), 10, 64)
+		if err != nil || entry.Name() != backupName(id) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), manifestName)); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// Load reads the manifest of the backup with the given id. If the id is nil,
+// the latest backup is loaded.
+func Load(dir string, id *uint64) (*Manifest, error) {
+	if id == nil {
+		ids, err := List(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, ErrNoBackup
+		}
+		id = &ids[len(ids)-1]
+	}
+	blob, err := os.ReadFile(filepath.Join(dir, backupName(*id), manifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %d", ErrNoBackup, *id)
+		}
+		return nil, err
+	}
+	manifest := new(Manifest)
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest of backup %d: %v", *id, err)
+	}
+	return manifest, nil
+}
+
+// Backup takes a consistent checkpoint of the database and stores it in a new
+// backup within the given directory. Unless a full backup is requested, only
+// the data changed since the latest backup in the directory is shipped.
+//
+// The database may be in use while the backup is taken. Note, the state which
+// is only held in memory by a running node is not part of the backup, after a
+// restore the node rewinds to the latest persisted state as it does after a
+// crash.
+func Backup(db ethdb.Database, dir string, full bool) (*Manifest, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	parent, err := Load(dir, nil)
+	switch {
+	case errors.Is(err, ErrNoBackup):
+		parent = nil
+	case err != nil:
+		return nil, err
+	}
+	manifest := &Manifest{Time: time.Now().UTC()}
+	if parent != nil {
+		manifest.ID = parent.ID + 1
+		if full {
+			parent = nil
+		} else {
+			manifest.Parent = &parent.ID
+		}
+	}
+	var (
+		start  = time.Now()
+		target = filepath.Join(dir, backupName(manifest.ID))
+		tmp    = target + ".tmp"
+	)
+	if err := os.RemoveAll(tmp); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(tmp, kvDir), 0755); err != nil {
+		return nil, err
+	}
+	// Take the key-value snapshot before reading the freezer state. Items moved
+	// into the freezer after the snapshot are still present in the snapshot, so
+	// the two never miss anything in between.
+	snap, err := db.NewSnapshot()
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	defer snap.Release()
+
+	manifest.Head = rawdb.ReadHeadBlockHash(snap)
+	manifest.HeadNumber = rawdb.ReadHeaderNumber(snap, manifest.Head)
+
+	if err := backupKeyValues(snap, tmp, manifest, parent); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	if err := backupAncients(db, tmp, manifest, parent); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, manifestName), blob, 0644); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	log.Info("Created database backup", "id", manifest.ID, "ranges", len(manifest.Ranges), "shippedRanges", manifest.ShippedRanges,
+		"files", len(manifest.Files), "shippedFiles", manifest.ShippedFiles, "shipped", common.StorageSize(manifest.ShippedBytes),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return manifest, nil
+}
+
+// entry is a single key-value pair as stored in the range files.
+type entry struct {
+	Key   []byte
+	Value []byte
+}
+
+// rangePrefix returns the prefix of the range the key belongs to. Keys shorter
+// than the prefix are padded with zeroes, which keeps them ordered before all
+// the longer keys of the same range.
+func rangePrefix(key []byte) []byte {
+	prefix := make([]byte, prefixLength)
+	copy(prefix, key)
+	return prefix
+}
+
+// rangeWriter streams the entries of a range into a file, hashing them along
+// the way.
+type rangeWriter struct {
+	prefix []byte
+	path   string
+	file   *os.File
+	buf    *bufio.Writer
+	hasher crypto.KeccakState
+	items  uint64
+	size   uint64
+}
+
+func newRangeWriter(dir string, prefix []byte) (*rangeWriter, error) {
+	path := filepath.Join(dir, kvDir, hex.EncodeToString(prefix)+".rlp")
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &rangeWriter{
+		prefix: prefix,
+		path:   path,
+		file:   file,
+		buf:    bufio.NewWriter(file),
+		hasher: crypto.NewKeccakState(),
+	}, nil
+}
+
+func (w *rangeWriter) add(key, value []byte) error {
+	blob, err := rlp.EncodeToBytes(&entry{Key: key, Value: value})
+	if err != nil {
+		return err
+	}
+	w.hasher.Write(blob)
+	w.items++
+	w.size += uint64(len(blob))
+	_, err = w.buf.Write(blob)
+	return err
+}
+
+// finish closes the range file and returns the hash of its content.
+func (w *rangeWriter) finish() (common.Hash, error) {
+	var hash common.Hash
+	w.hasher.Read(hash[:])
+
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return hash, err
+	}
+	return hash, w.file.Close()
+}
+
+// backupKeyValues iterates over the key-value snapshot and stores the ranges
+// which changed compared to the parent backup.
+func backupKeyValues(snap ethdb.Snapshot, dir string, manifest *Manifest, parent *Manifest) error {
+	previous := make(map[string]*Range)
+	if parent != nil {
+		for _, r := range parent.Ranges {
+			previous[string(r.Prefix)] = r
+		}
+	}
+	var writer *rangeWriter
+	finish := func() error {
+		hash, err := writer.finish()
+		if err != nil {
+			return err
+		}
+		r := &Range{Prefix: writer.prefix, Hash: hash, Items: writer.items, Backup: manifest.ID}
+		if old := previous[string(writer.prefix)]; old != nil && old.Hash == hash && old.Items == writer.items {
+			r.Backup = old.Backup
+			if err := os.Remove(writer.path); err != nil {
+				return err
+			}
+		} else {
+			manifest.ShippedRanges++
+			manifest.ShippedBytes += writer.size
+		}
+		manifest.Ranges = append(manifest.Ranges, r)
+		return nil
+	}
+	it := snap.NewIterator(nil, nil)
+	defer it.Release()
+
+	for it.Next() {
+		prefix := rangePrefix(it.Key())
+		if writer == nil || string(writer.prefix) != string(prefix) {
+			if writer != nil {
+				if err := finish(); err != nil {
+					return err
+				}
+			}
+			var err error
+			if writer, err = newRangeWriter(dir, prefix); err != nil {
+				return err
+			}
+		}
+		if err := writer.add(it.Key(), it.Value()); err != nil {
+			writer.file.Close()
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		if writer != nil {
+			writer.file.Close()
+		}
+		return err
+	}
+	if writer != nil {
+		return finish()
+	}
+	return nil
+}
+
+// isDataFile reports whether the freezer file is a data file. Data files are
+// mostly appended to, but truncating and re-appending, or recompressing a table,
+// may rewrite their content without changing the size. A data file is thus only
+// considered unchanged if both its size and modification time are.
+func isDataFile(name string) bool {
+	return strings.HasSuffix(name, ".cdat") || strings.HasSuffix(name, ".rdat")
+}
+
+// backupAncients copies the freezer files which changed compared to the parent
+// backup. The freezer is prevented from moving new items in while the files
+// are copied, so the recorded item counts are accurate.
+func backupAncients(db ethdb.Database, dir string, manifest *Manifest, parent *Manifest) error {
+	root, err := db.AncientDatadir()
+	if err != nil {
+		return nil // No freezer attached to the database
+	}
+	previous := make(map[string]*File)
+	if parent != nil {
+		for _, f := range parent.Files {
+			previous[f.Name] = f
+		}
+	}
+	return db.ReadAncients(func(op ethdb.AncientReaderOp) error {
+		frozen, err := op.Ancients()
+		if err != nil {
+			return err
+		}
+		tail, err := op.Tail()
+		if err != nil {
+			return err
+		}
+		manifest.Ancients, manifest.AncientTail = frozen, tail
+		if root == "" {
+			if frozen > tail {
+				return errors.New("ancient store is not backed by local files")
+			}
+			return nil
+		}
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || d.Name() == "FLOCK" {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			file := &File{Name: filepath.ToSlash(rel), Size: uint64(info.Size()), ModTime: info.ModTime().UnixNano(), Backup: manifest.ID}
+			if old := previous[file.Name]; old != nil && old.Size == file.Size && old.ModTime == file.ModTime && isDataFile(file.Name) {
+				file.Backup = old.Backup
+			} else {
+				if err := copyFile(filepath.Join(dir, ancientDir, rel), path, file.Size); err != nil {
+					return err
+				}
+				manifest.ShippedFiles++
+				manifest.ShippedBytes += file.Size
+			}
+			manifest.Files = append(manifest.Files, file)
+			return nil
+		})
+	})
+}
+
+// copyFile copies the first size bytes of the source file into a newly created
+// destination file, creating the parent directories if needed.
+func copyFile(dst, src string, size uint64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(out, in, int64(size)); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Restore writes the content of a backup into an empty key-value store and
+// chain freezer directory. If the id is nil, the latest backup is restored.
+func Restore(dir string, id *uint64, db ethdb.KeyValueStore, ancient string) (*Manifest, error) {
+	manifest, err := Load(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	it := db.NewIterator(nil, nil)
+	exists := it.Next()
+	it.Release()
+	if exists {
+		return nil, errNotEmpty
+	}
+	if entries, err := os.ReadDir(ancient); err == nil && len(entries) > 0 {
+		return nil, errNotEmpty
+	}
+	start := time.Now()
+	for _, r := range manifest.Ranges {
+		if err := restoreRange(dir, r, db); err != nil {
+			return nil, err
+		}
+	}
+	if len(manifest.Files) > 0 {
+		for _, f := range manifest.Files {
+			src := filepath.Join(dir, backupName(f.Backup), ancientDir, filepath.FromSlash(f.Name))
+			if err := copyFile(filepath.Join(ancient, filepath.FromSlash(f.Name)), src, f.Size); err != nil {
+				return nil, err
+			}
+		}
+		// Drop the items frozen during the backup, after the checkpoint was taken
+		freezer, err := rawdb.NewChainAncientStore(ancient, "", false)
+		if err != nil {
+			return nil, err
+		}
+		if err := freezer.TruncateHead(manifest.Ancients); err != nil {
+			freezer.Close()
+			return nil, err
+		}
+		if err := freezer.Close(); err != nil {
+			return nil, err
+		}
+	}
+	log.Info("Restored database backup", "id", manifest.ID, "ranges", len(manifest.Ranges), "files", len(manifest.Files),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return manifest, nil
+}
+
+// restoreRange writes the entries of a key-value range into the database,
+// verifying the content against the manifest.
+func restoreRange(dir string, r *Range, db ethdb.KeyValueStore) error {
+	path := filepath.Join(dir, backupName(r.Backup), kvDir, hex.EncodeToString(r.Prefix)+".rlp")
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var (
+		hasher = crypto.NewKeccakState()
+		stream = rlp.NewStream(io.TeeReader(bufio.NewReader(file), hasher), 0)
+		batch  = db.NewBatch()
+		items  uint64
+	)
+	for {
+		var e entry
+		if err := stream.Decode(&e); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("corrupted range %x in backup %d: %v", []byte(r.Prefix), r.Backup, err)
+		}
+		if err := batch.Put(e.Key, e.Value); err != nil {
+			return err
+		}
+		items++
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	var hash common.Hash
+	hasher.Read(hash[:])
+	if hash != r.Hash || items != r.Items {
+		return fmt.Errorf("range %x in backup %d mismatch: have %d items with hash %x, want %d items with hash %x",
+			[]byte(r.Prefix), r.Backup, items, hash, r.Items, r.Hash)
+	}
+	return batch.Write()
+}
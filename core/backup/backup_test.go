@@ -0,0 +1,246 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// makeBlocks creates a chain of empty blocks starting at the given number.
+func makeBlocks(parent common.Hash, start, count int) []*types.Block {
+	blocks := make([]*types.Block, count)
+	for i := range blocks {
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(start + i)),
+			Difficulty: big.NewInt(1),
+			Extra:      bytes.Repeat([]byte{0xff}, 512),
+		}
+		blocks[i] = types.NewBlockWithHeader(header)
+		parent = blocks[i].Hash()
+	}
+	return blocks
+}
+
+// freezeBlocks writes the blocks into the ancient store of the database.
+func freezeBlocks(t *testing.T, db ethdb.Database, blocks []*types.Block) {
+	if _, err := rawdb.WriteAncientBlocks(db, blocks, make([]types.Receipts, len(blocks)), big.NewInt(1)); err != nil {
+		t.Fatalf("failed to freeze blocks: %v", err)
+	}
+}
+
+// checkEqual ensures the key-value content of the two databases is identical
+// and that the ancient store of the restored database holds the given number
+// of items.
+func checkEqual(t *testing.T, want ethdb.KeyValueStore, have ethdb.Database, ancients uint64) {
+	t.Helper()
+
+	wantIt, haveIt := want.NewIterator(nil, nil), have.NewIterator(nil, nil)
+	defer wantIt.Release()
+	defer haveIt.Release()
+
+	for wantIt.Next() {
+		if !haveIt.Next() {
+			t.Fatalf("missing key %x", wantIt.Key())
+		}
+		if !bytes.Equal(wantIt.Key(), haveIt.Key()) || !bytes.Equal(wantIt.Value(), haveIt.Value()) {
+			t.Fatalf("entry mismatch: have %x=%x, want %x=%x", haveIt.Key(), haveIt.Value(), wantIt.Key(), wantIt.Value())
+		}
+	}
+	if haveIt.Next() {
+		t.Fatalf("extra key %x", haveIt.Key())
+	}
+	if frozen, _ := have.Ancients(); frozen != ancients {
+		t.Fatalf("ancient items mismatch: have %d, want %d", frozen, ancients)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	var (
+		kvdb   = rawdb.NewMemoryDatabase()
+		dir    = t.TempDir()
+		blocks = makeBlocks(common.Hash{}, 0, 100)
+	)
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, filepath.Join(dir, "ancient"), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	freezeBlocks(t, db, blocks[:50])
+	for _, block := range blocks[50:] {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	rawdb.WriteHeadBlockHash(db, blocks[99].Hash())
+	rawdb.WriteHeaderNumber(db, blocks[99].Hash(), 99)
+
+	// Create a full backup and ensure everything got shipped
+	backups := filepath.Join(dir, "backups")
+	first, err := Backup(db, backups, false)
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	if first.Parent != nil || first.ShippedRanges != len(first.Ranges) || first.ShippedFiles != len(first.Files) {
+		t.Fatalf("first backup not full: parent %v, ranges %d/%d, files %d/%d", first.Parent,
+			first.ShippedRanges, len(first.Ranges), first.ShippedFiles, len(first.Files))
+	}
+	if first.Ancients != 50 || first.HeadNumber == nil || *first.HeadNumber != 99 {
+		t.Fatalf("checkpoint mismatch: ancients %d, head %v", first.Ancients, first.HeadNumber)
+	}
+	// Modify a single range, ensure only the changes get shipped by an
+	// incremental backup.
+	db.Put([]byte("LastFast"), blocks[60].Hash().Bytes())
+
+	second, err := Backup(db, backups, false)
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	if second.Parent == nil || *second.Parent != first.ID {
+		t.Fatalf("incremental backup parent mismatch: have %v, want %d", second.Parent, first.ID)
+	}
+	if second.ShippedRanges != 1 {
+		t.Fatalf("shipped ranges mismatch: have %d, want 1", second.ShippedRanges)
+	}
+	if second.ShippedFiles == len(second.Files) {
+		t.Fatalf("all %d freezer files shipped by incremental backup", second.ShippedFiles)
+	}
+	// Freeze some more items, ensure the grown data files get shipped
+	freezeBlocks(t, db, blocks[50:60])
+
+	third, err := Backup(db, backups, false)
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	if third.ShippedRanges != 0 || third.ShippedFiles != len(third.Files) {
+		t.Fatalf("shipped content mismatch: ranges %d, files %d/%d", third.ShippedRanges, third.ShippedFiles, len(third.Files))
+	}
+	// Take a full backup too, ensuring it doesn't reference any previous one
+	fourth, err := Backup(db, backups, true)
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	for _, r := range fourth.Ranges {
+		if r.Backup != fourth.ID {
+			t.Fatalf("full backup references range of backup %d", r.Backup)
+		}
+	}
+	// Restore the last incremental backup and check the content
+	id := third.ID
+	restoredKV := rawdb.NewMemoryDatabase()
+	if _, err := Restore(backups, &id, restoredKV, filepath.Join(dir, "restored")); err != nil {
+		t.Fatalf("failed to restore backup: %v", err)
+	}
+	restored, err := rawdb.NewDatabaseWithFreezer(restoredKV, filepath.Join(dir, "restored"), "", true)
+	if err != nil {
+		t.Fatalf("failed to open restored database: %v", err)
+	}
+	defer restored.Close()
+	checkEqual(t, kvdb, restored, 60)
+
+	if _, err := Restore(backups, nil, restoredKV, filepath.Join(dir, "other")); err != errNotEmpty {
+		t.Fatalf("restore into non-empty database: have %v, want %v", err, errNotEmpty)
+	}
+}
+
+// Tests that freezer data files rewritten without changing their size, e.g. by
+// recompressing a table, are shipped again by incremental backups.
+func TestBackupRewrittenDataFile(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		blocks = makeBlocks(common.Hash{}, 0, 10)
+	)
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), filepath.Join(dir, "ancient"), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+	freezeBlocks(t, db, blocks)
+
+	backups := filepath.Join(dir, "backups")
+	first, err := Backup(db, backups, false)
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	// Overwrite the content of a data file in place, keeping its size.
+	var name string
+	for _, f := range first.Files {
+		if isDataFile(f.Name) && f.Size > 0 {
+			name = f.Name
+			break
+		}
+	}
+	if name == "" {
+		t.Fatal("no freezer data file found")
+	}
+	root, _ := db.AncientDatadir()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read data file: %v", err)
+	}
+	blob[0] ^= 0xff
+	if err := os.WriteFile(path, blob, 0644); err != nil {
+		t.Fatalf("failed to rewrite data file: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("failed to update modification time: %v", err)
+	}
+	second, err := Backup(db, backups, false)
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	for _, f := range second.Files {
+		if f.Name == name && f.Backup != second.ID {
+			t.Fatalf("rewritten data file %s not shipped, referencing backup %d", f.Name, f.Backup)
+		}
+	}
+}
+
+func TestBackupWithoutFreezer(t *testing.T) {
+	var (
+		db  = rawdb.NewMemoryDatabase()
+		dir = t.TempDir()
+	)
+	db.Put([]byte{0x01}, []byte{0x01})
+	db.Put([]byte{0x01, 0x02, 0x03}, []byte{0x02})
+	db.Put([]byte{0x02, 0x01}, []byte{0x03})
+
+	manifest, err := Backup(db, dir, false)
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	if len(manifest.Ranges) != 3 || len(manifest.Files) != 0 {
+		t.Fatalf("backup content mismatch: ranges %d, files %d", len(manifest.Ranges), len(manifest.Files))
+	}
+	restored := rawdb.NewMemoryDatabase()
+	if _, err := Restore(dir, nil, restored, filepath.Join(dir, "ancient")); err != nil {
+		t.Fatalf("failed to restore backup: %v", err)
+	}
+	checkEqual(t, db, rawdb.NewDatabase(restored), 0)
+}
@@ -77,6 +77,7 @@ var (
 	INVALIDBLOCKHASH = "INVALID_BLOCK_HASH"
 
 	GenericServerError       = &EngineAPIError{code: -32000, msg: "Server error"}
+	InvalidParams            = &EngineAPIError{code: -32602, msg: "Invalid parameters"}
 	UnknownPayload           = &EngineAPIError{code: -38001, msg: "Unknown payload"}
 	InvalidForkChoiceState   = &EngineAPIError{code: -38002, msg: "Invalid forkchoice state"}
 	InvalidPayloadAttributes = &EngineAPIError{code: -38003, msg: "Invalid payload attributes"}
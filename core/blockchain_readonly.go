@@ -0,0 +1,155 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// reloadEventLimit is the maximum number of blocks announced by a single head
+// reload. Followers falling further behind only receive the most recent events.
+const reloadEventLimit = TriesInMemory
+
+// NewReadOnlyBlockChain returns a blockchain following a database which is
+// written by another process. The chain configuration is loaded from the
+// database, nothing is ever written into it and all import and rewind methods
+// return an error. The chain markers need to be refreshed via ReloadHead to
+// observe the progress made by the writer.
+//
+// Note, the state is only available for the blocks the writer has flushed to
+// disk, the recent states held in the writer's memory are not accessible.
+func NewReadOnlyBlockChain(db ethdb.Database, cacheConfig *CacheConfig, engine consensus.Engine, vmConfig vm.Config) (*BlockChain, error) {
+	if cacheConfig == nil {
+		cacheConfig = defaultCacheConfig
+	}
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil, ErrNoGenesis
+	}
+	chainConfig := rawdb.ReadChainConfig(db, genesis)
+	if chainConfig == nil {
+		return nil, errors.New("missing chain config")
+	}
+	bc, err := newBlockChain(db, chainConfig, cacheConfig, engine, vmConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	bc.readonly = true
+
+	if err := bc.loadLastState(); err != nil {
+		return nil, err
+	}
+	return bc, nil
+}
+
+// ReadOnly returns whether the blockchain follows a database written by another
+// process.
+func (bc *BlockChain) ReadOnly() bool {
+	return bc.readonly
+}
+
+// ReloadHead reloads the chain markers of a read-only blockchain from the
+// database, announcing the blocks that became canonical since the last reload.
+func (bc *BlockChain) ReloadHead() error {
+	if !bc.readonly {
+		return errors.New("blockchain is not read-only")
+	}
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	hash := rawdb.ReadHeadBlockHash(bc.db)
+	head := bc.GetBlockByHash(hash)
+	if head == nil {
+		return fmt.Errorf("head block %x missing", hash)
+	}
+	// Update the auxiliary markers, falling back to the head block like the
+	// initial load does.
+	if header := bc.GetHeaderByHash(rawdb.ReadHeadHeaderHash(bc.db)); header != nil {
+		bc.hc.SetCurrentHeader(header)
+	} else {
+		bc.hc.SetCurrentHeader(head.Header())
+	}
+	if block := bc.GetBlockByHash(rawdb.ReadHeadFastBlockHash(bc.db)); block != nil {
+		bc.currentFastBlock.Store(block)
+		headFastBlockGauge.Update(int64(block.NumberU64()))
+	}
+	if block := bc.GetBlockByHash(rawdb.ReadFinalizedBlockHash(bc.db)); block != nil {
+		bc.currentFinalizedBlock.Store(block)
+		headFinalizedBlockGauge.Update(int64(block.NumberU64()))
+		bc.currentSafeBlock.Store(block)
+		headSafeBlockGauge.Update(int64(block.NumberU64()))
+	}
+	current := bc.CurrentBlock()
+	if head.Hash() == current.Hash() {
+		return nil
+	}
+	// The head changed, gather the blocks that became canonical and the ones
+	// dropped by a reorg, up to the announcement limit.
+	var (
+		newChain, oldChain types.Blocks
+		newBlock, oldBlock = head, current
+	)
+	for newBlock != nil && oldBlock != nil && newBlock.Hash() != oldBlock.Hash() && len(newChain)+len(oldChain) < 2*reloadEventLimit {
+		newNumber, oldNumber := newBlock.NumberU64(), oldBlock.NumberU64()
+		if newNumber >= oldNumber {
+			newChain = append(newChain, newBlock)
+			newBlock = bc.GetBlock(newBlock.ParentHash(), newNumber-1)
+		}
+		if oldNumber >= newNumber {
+			oldChain = append(oldChain, oldBlock)
+			oldBlock = bc.GetBlock(oldBlock.ParentHash(), oldNumber-1)
+		}
+	}
+	if len(newChain) > reloadEventLimit {
+		newChain = newChain[:reloadEventLimit]
+	}
+	// Switch over to the new head and announce the changes
+	bc.txLookupCache.Purge()
+	bc.currentBlock.Store(head)
+	headBlockGauge.Update(int64(head.NumberU64()))
+
+	var deletedLogs []*types.Log
+	for _, block := range oldChain {
+		deletedLogs = append(deletedLogs, bc.collectLogs(block.Hash(), true)...)
+	}
+	if len(deletedLogs) > 0 {
+		bc.rmLogsFeed.Send(RemovedLogsEvent{deletedLogs})
+	}
+	for i := len(newChain) - 1; i >= 0; i-- {
+		block := newChain[i]
+		logs := bc.collectLogs(block.Hash(), false)
+		bc.chainFeed.Send(ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
+		if len(logs) > 0 {
+			bc.logsFeed.Send(logs)
+		}
+	}
+	bc.chainHeadFeed.Send(ChainHeadEvent{Block: head})
+
+	log.Debug("Reloaded chain head", "number", head.Number(), "hash", head.Hash(), "blocks", len(newChain), "dropped", len(oldChain))
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Tests that a read-only blockchain follows the head of a chain written by
+// another instance, announcing both extensions and reorgs.
+func TestReadOnlyBlockChain(t *testing.T) {
+	chain, canonblocks, sideblocks, _, err := getLongAndShortChains()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(canonblocks[:10]); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	follower, err := NewReadOnlyBlockChain(chain.db, nil, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create read-only chain: %v", err)
+	}
+	defer follower.Stop()
+
+	if head := follower.CurrentBlock(); head.Hash() != canonblocks[9].Hash() {
+		t.Fatalf("head mismatch: have %d, want %d", head.NumberU64(), canonblocks[9].NumberU64())
+	}
+	if _, err := follower.InsertChain(canonblocks[10:]); err != errChainReadOnly {
+		t.Fatalf("insertion into read-only chain: have %v, want %v", err, errChainReadOnly)
+	}
+	if err := follower.SetHead(0); err != errChainReadOnly {
+		t.Fatalf("rewinding read-only chain: have %v, want %v", err, errChainReadOnly)
+	}
+	events := make(chan ChainEvent, 256)
+	sub := follower.SubscribeChainEvent(events)
+	defer sub.Unsubscribe()
+
+	// Extend the chain via the writer, ensure the follower catches up
+	if n, err := chain.InsertChain(canonblocks[10:]); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	if err := follower.ReloadHead(); err != nil {
+		t.Fatalf("failed to reload head: %v", err)
+	}
+	if head := follower.CurrentBlock(); head.Hash() != canonblocks[len(canonblocks)-1].Hash() {
+		t.Fatalf("head mismatch: have %d, want %d", head.NumberU64(), len(canonblocks))
+	}
+	for i, block := range canonblocks[10:] {
+		if ev := <-events; ev.Hash != block.Hash() {
+			t.Fatalf("event %d: hash mismatch: have %x, want %x", i, ev.Hash, block.Hash())
+		}
+	}
+	// Reorg the chain via the writer, ensure the follower switches over
+	if _, err := chain.InsertChain(sideblocks); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	if err := follower.ReloadHead(); err != nil {
+		t.Fatalf("failed to reload head: %v", err)
+	}
+	if head := follower.CurrentBlock(); head.Hash() != sideblocks[len(sideblocks)-1].Hash() {
+		t.Fatalf("head mismatch after reorg: have %x, want %x", head.Hash(), sideblocks[len(sideblocks)-1].Hash())
+	}
+	for i, block := range sideblocks[4:] {
+		if ev := <-events; ev.Hash != block.Hash() {
+			t.Fatalf("reorg event %d: hash mismatch: have %x, want %x", i, ev.Hash, block.Hash())
+		}
+	}
+	if block := follower.GetBlockByNumber(canonblocks[len(canonblocks)-1].NumberU64()); block != nil {
+		t.Fatalf("dropped canonical block still retrievable by number")
+	}
+}
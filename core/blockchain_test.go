@@ -4007,3 +4007,54 @@ func TestTxIndexer(t *testing.T) {
 		os.RemoveAll(frdir)
 	}
 }
+
+// TestWithdrawals checks that withdrawals in post-Shanghai blocks are imported
+// along with the blocks and credited to their recipients.
+func TestWithdrawals(t *testing.T) {
+	chainConfig := *params.TestChainConfig
+	chainConfig.TerminalTotalDifficulty = big.NewInt(0)
+	chainConfig.ShanghaiTime = new(uint64)
+
+	var (
+		recipient = common.Address{0xaa}
+		genesis   = &Genesis{
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Config:  &chainConfig,
+		}
+		engine = beacon.New(ethash.NewFaker())
+	)
+	_, blocks, _ := GenerateChainWithGenesis(genesis, engine, 3, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		b.SetDifficulty(common.Big0) // the chain maker cannot track the total difficulty
+		b.AddWithdrawal(&types.Withdrawal{Validator: uint64(i), Address: recipient, Amount: 10})
+		if i == 2 {
+			b.AddWithdrawal(&types.Withdrawal{Validator: 42, Address: recipient, Amount: 5})
+		}
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	head := chain.CurrentBlock()
+	if have := len(head.Withdrawals()); have != 2 {
+		t.Fatalf("head withdrawal count mismatch: have %d, want 2", have)
+	}
+	if have := head.Withdrawals()[1].Index; have != 3 {
+		t.Fatalf("withdrawal index mismatch: have %d, want 3", have)
+	}
+	state, _ := chain.State()
+	want := new(big.Int).Mul(big.NewInt(35), big.NewInt(params.GWei))
+	if have := state.GetBalance(recipient); have.Cmp(want) != 0 {
+		t.Fatalf("recipient balance mismatch: have %v, want %v", have, want)
+	}
+	// A block whose withdrawals don't match its header must be rejected
+	tampered := types.NewBlockWithHeader(blocks[0].Header()).WithBody(nil, nil).WithWithdrawals(blocks[1].Withdrawals())
+	if err := chain.Validator().ValidateBody(tampered); err == nil {
+		t.Fatal("expected error for mismatching withdrawals")
+	}
+}
@@ -0,0 +1,571 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package integrity implements a database integrity checker, which can be run
+// both offline and against the database of a live node.
+//
+// The checker walks the state tries of recent blocks, re-hashing every node and
+// cross-checking the accounts and storage slots against the snapshot, validates
+// every item of the ancient store against its index and the canonical hashes,
+// and finally verifies the canonical hash mappings against the headers. All the
+// corruption found is collected into a machine-readable report.
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// The list of database components an issue can be reported for.
+const (
+	ComponentTrie      = "trie"
+	ComponentSnapshot  = "snapshot"
+	ComponentFreezer   = "freezer"
+	ComponentCanonical = "canonical"
+)
+
+const (
+	// throttleBatch is the number of items checked between two throttling pauses.
+	throttleBatch = 1024
+
+	// ancientBatch is the number of frozen blocks verified in one go.
+	ancientBatch = 1024
+
+	// maxRootSearch is the maximum number of blocks to step back from the head
+	// when searching for recent state roots available in the database.
+	maxRootSearch = 128
+)
+
+// emptyCode is the known hash of the empty EVM bytecode.
+var emptyCode = crypto.Keccak256Hash(nil)
+
+// Config contains the settings of an integrity check run.
+type Config struct {
+	Roots         int           // Number of recent state roots to verify (0 = skip state checks)
+	SkipFreezer   bool          // Whether to skip the ancient store checks
+	SkipCanonical bool          // Whether to skip the canonical chain checks
+	Throttle      time.Duration // Pause inserted after every batch of checked items
+	MaxIssues     int           // Maximum number of issues to retain in the report (0 = unlimited)
+}
+
+// DefaultConfig contains the default settings for an integrity check run.
+var DefaultConfig = Config{
+	Roots:     1,
+	MaxIssues: 1000,
+}
+
+// Issue describes a single corruption found in the database.
+type Issue struct {
+	Component string       `json:"component"`
+	Root      *common.Hash `json:"root,omitempty"`
+	Account   *common.Hash `json:"account,omitempty"`
+	Slot      *common.Hash `json:"slot,omitempty"`
+	Node      *common.Hash `json:"node,omitempty"`
+	Table     string       `json:"table,omitempty"`
+	Number    *uint64      `json:"number,omitempty"`
+	Message   string       `json:"message"`
+}
+
+// Report is the outcome of an integrity check run.
+type Report struct {
+	Started       time.Time     `json:"started"`
+	Elapsed       time.Duration `json:"elapsed"`
+	Done          bool          `json:"done"`
+	Error         string        `json:"error,omitempty"` // Reason of the premature termination, if any
+	Head          uint64        `json:"head"`
+	Roots         []common.Hash `json:"roots"`         // State roots verified
+	SnapshotRoots []common.Hash `json:"snapshotRoots"` // State roots cross-checked against the snapshot
+	TrieNodes     uint64        `json:"trieNodes"`
+	Accounts      uint64        `json:"accounts"`
+	Slots         uint64        `json:"slots"`
+	Codes         uint64        `json:"codes"`
+	Ancients      uint64        `json:"ancients"`  // Number of frozen blocks verified
+	Canonical     uint64        `json:"canonical"` // Number of canonical hash mappings verified
+	Issues        []*Issue      `json:"issues"`
+	Truncated     bool          `json:"truncated"` // Whether issues were dropped due to the limit
+}
+
+// copy creates a deep copy of the report.
+func (r *Report) copy() *Report {
+	cpy := *r
+	cpy.Roots = append([]common.Hash(nil), r.Roots...)
+	cpy.SnapshotRoots = append([]common.Hash(nil), r.SnapshotRoots...)
+	cpy.Issues = append([]*Issue(nil), r.Issues...)
+	return &cpy
+}
+
+// Checker verifies the integrity of a chain database.
+type Checker struct {
+	db     ethdb.Database
+	triedb *trie.Database
+	snaps  *snapshot.Tree // Optional snapshot tree to cross-check the state against
+	config Config
+
+	work   int // Number of items checked since the last throttling pause
+	lock   sync.Mutex
+	report Report
+}
+
+// New creates an integrity checker for the given database. The trie database is
+// used to resolve the state trie nodes (it may hold recent nodes not yet flushed
+// to disk), the snapshot tree is optional.
+func New(db ethdb.Database, triedb *trie.Database, snaps *snapshot.Tree, config Config) *Checker {
+	return &Checker{
+		db:     db,
+		triedb: triedb,
+		snaps:  snaps,
+		config: config,
+	}
+}
+
+// Report returns a copy of the current, potentially partial, report.
+func (c *Checker) Report() *Report {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	report := c.report.copy()
+	if !report.Done {
+		report.Elapsed = time.Since(report.Started)
+	}
+	return report
+}
+
+// Run executes the integrity checks, blocking until they are done or the context
+// is cancelled, returning the final report.
+func (c *Checker) Run(ctx context.Context) *Report {
+	start := time.Now()
+	c.update(func(r *Report) { r.Started = start })
+
+	err := c.run(ctx)
+
+	c.update(func(r *Report) {
+		r.Done = true
+		r.Elapsed = time.Since(start)
+		if err != nil {
+			r.Error = err.Error()
+		}
+	})
+	report := c.Report()
+	log.Info("Database integrity check finished", "issues", len(report.Issues), "elapsed", common.PrettyDuration(report.Elapsed), "err", err)
+	return report
+}
+
+func (c *Checker) run(ctx context.Context) error {
+	head := rawdb.ReadHeadHeaderHash(c.db)
+	number := rawdb.ReadHeaderNumber(c.db, head)
+	if number == nil {
+		return fmt.Errorf("head header %x unavailable", head)
+	}
+	c.update(func(r *Report) { r.Head = *number })
+
+	if c.config.Roots > 0 {
+		for _, root := range c.recentRoots(*number) {
+			if err := c.checkState(ctx, root); err != nil {
+				return err
+			}
+		}
+	}
+	frozen, err := c.db.Ancients()
+	if err != nil {
+		frozen = 0 // No ancient store attached
+	}
+	tail, err := c.db.Tail()
+	if err != nil {
+		tail = 0
+	}
+	if !c.config.SkipFreezer && frozen > tail {
+		if err := c.checkAncients(ctx, tail, frozen); err != nil {
+			return err
+		}
+	}
+	if !c.config.SkipCanonical {
+		if err := c.checkCanonical(ctx, tail, *number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update runs the given function on the report while holding the lock.
+func (c *Checker) update(fn func(r *Report)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	fn(&c.report)
+}
+
+// addIssue appends an issue to the report, honouring the configured limit.
+func (c *Checker) addIssue(issue *Issue) {
+	log.Warn("Database corruption detected", "component", issue.Component, "msg", issue.Message)
+
+	c.update(func(r *Report) {
+		if c.config.MaxIssues > 0 && len(r.Issues) >= c.config.MaxIssues {
+			r.Truncated = true
+			return
+		}
+		r.Issues = append(r.Issues, issue)
+	})
+}
+
+// throttle is invoked after every checked item, pausing the checker if a batch
+// of items was completed and aborting if the context was cancelled.
+func (c *Checker) throttle(ctx context.Context) error {
+	if c.work++; c.work < throttleBatch {
+		return nil
+	}
+	c.work = 0
+	if c.config.Throttle == 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(c.config.Throttle)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// recentRoots collects the configured number of most recent state roots which
+// are available in the database, starting from the given block.
+func (c *Checker) recentRoots(number uint64) []common.Hash {
+	var roots []common.Hash
+	for i := uint64(0); i < maxRootSearch && i <= number && len(roots) < c.config.Roots; i++ {
+		header := rawdb.ReadHeader(c.db, rawdb.ReadCanonicalHash(c.db, number-i), number-i)
+		if header == nil {
+			continue
+		}
+		if _, err := c.triedb.Node(header.Root); err != nil {
+			continue
+		}
+		roots = append(roots, header.Root)
+	}
+	return roots
+}
+
+// checkNode ensures that the trie node with the given hash is present and that
+// its content matches the hash.
+func (c *Checker) checkNode(hasher crypto.KeccakState, hash common.Hash) error {
+	blob, err := c.triedb.Node(hash)
+	if err != nil || len(blob) == 0 {
+		return fmt.Errorf("missing trie node")
+	}
+	var got common.Hash
+	hasher.Reset()
+	hasher.Write(blob)
+	hasher.Read(got[:])
+	if got != hash {
+		return fmt.Errorf("trie node hash mismatch: have %x", got)
+	}
+	return nil
+}
+
+// guard runs the given trie walk, converting the panics raised by the trie
+// package on undecodable nodes into issues.
+func (c *Checker) guard(issue *Issue, walk func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			issue.Message = fmt.Sprint(r)
+			c.addIssue(issue)
+			err = nil
+		}
+	}()
+	return walk()
+}
+
+// checkState walks the state trie with the given root, verifying every trie node
+// and cross-checking the leaves with the snapshot if it's available.
+func (c *Checker) checkState(ctx context.Context, root common.Hash) error {
+	log.Info("Verifying state", "root", root)
+	c.update(func(r *Report) { r.Roots = append(r.Roots, root) })
+
+	return c.guard(&Issue{Component: ComponentTrie, Root: &root}, func() error {
+		return c.walkState(ctx, root)
+	})
+}
+
+// walkState iterates over the account trie with the given root.
+func (c *Checker) walkState(ctx context.Context, root common.Hash) error {
+	accTrie, err := trie.NewStateTrie(trie.StateTrieID(root), c.triedb)
+	if err != nil {
+		c.addIssue(&Issue{Component: ComponentTrie, Root: &root, Message: err.Error()})
+		return nil
+	}
+	var accSnap *snapWalker
+	if c.snaps != nil && c.snaps.Snapshot(root) != nil {
+		if it, err := c.snaps.AccountIterator(root, common.Hash{}); err != nil {
+			log.Warn("Snapshot unavailable for cross-check", "root", root, "err", err)
+		} else {
+			defer it.Release()
+			accSnap = newSnapWalker(it)
+			c.update(func(r *Report) { r.SnapshotRoots = append(r.SnapshotRoots, root) })
+		}
+	}
+	var (
+		hasher         = crypto.NewKeccakState()
+		accIt          = accTrie.NodeIterator(nil)
+		onExtraAccount = func(extra common.Hash) {
+			c.addIssue(&Issue{Component: ComponentSnapshot, Root: &root, Account: &extra, Message: "account not present in trie"})
+		}
+	)
+	for accIt.Next(true) {
+		if hash := accIt.Hash(); hash != (common.Hash{}) {
+			if err := c.checkNode(hasher, hash); err != nil {
+				c.addIssue(&Issue{Component: ComponentTrie, Root: &root, Node: &hash, Message: err.Error()})
+			}
+			c.update(func(r *Report) { r.TrieNodes++ })
+		}
+		if !accIt.Leaf() {
+			if err := c.throttle(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+		account := common.BytesToHash(accIt.LeafKey())
+		c.update(func(r *Report) { r.Accounts++ })
+
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIt.LeafBlob(), &acc); err != nil {
+			c.addIssue(&Issue{Component: ComponentTrie, Root: &root, Account: &account, Message: fmt.Sprintf("invalid account: %v", err)})
+			continue
+		}
+		// Cross-check the account with the snapshot
+		var slotSnap *snapWalker
+		if accSnap != nil {
+			found, err := accSnap.seek(account, onExtraAccount)
+			switch {
+			case err != nil:
+				log.Warn("Snapshot cross-check aborted", "root", root, "err", err)
+				accSnap = nil
+			case !found:
+				c.addIssue(&Issue{Component: ComponentSnapshot, Root: &root, Account: &account, Message: "account missing from snapshot"})
+			default:
+				full, err := snapshot.FullAccountRLP(accSnap.it.(snapshot.AccountIterator).Account())
+				if err != nil || !bytes.Equal(full, accIt.LeafBlob()) {
+					c.addIssue(&Issue{Component: ComponentSnapshot, Root: &root, Account: &account, Message: "account mismatch with trie"})
+				}
+				accSnap.step()
+
+				if it, err := c.snaps.StorageIterator(root, account, common.Hash{}); err != nil {
+					c.addIssue(&Issue{Component: ComponentSnapshot, Root: &root, Account: &account, Message: fmt.Sprintf("storage unavailable: %v", err)})
+				} else {
+					slotSnap = newSnapWalker(it)
+				}
+			}
+		}
+		err := c.guard(&Issue{Component: ComponentTrie, Root: &root, Account: &account}, func() error {
+			return c.checkStorage(ctx, hasher, root, account, acc.Root, slotSnap)
+		})
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(acc.CodeHash, emptyCode.Bytes()) {
+			if !rawdb.HasCode(c.db, common.BytesToHash(acc.CodeHash)) {
+				c.addIssue(&Issue{Component: ComponentTrie, Root: &root, Account: &account, Message: fmt.Sprintf("missing code %x", acc.CodeHash)})
+			}
+			c.update(func(r *Report) { r.Codes++ })
+		}
+		if err := c.throttle(ctx); err != nil {
+			return err
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		c.addIssue(&Issue{Component: ComponentTrie, Root: &root, Message: err.Error()})
+	}
+	if accSnap != nil {
+		if err := accSnap.drain(onExtraAccount); err != nil {
+			log.Warn("Snapshot cross-check aborted", "root", root, "err", err)
+		}
+	}
+	return nil
+}
+
+// checkStorage walks the storage trie of an account, verifying every trie node
+// and cross-checking the slots with the snapshot if it's available.
+func (c *Checker) checkStorage(ctx context.Context, hasher crypto.KeccakState, root common.Hash, account common.Hash, storageRoot common.Hash, slotSnap *snapWalker) error {
+	if slotSnap != nil {
+		defer slotSnap.it.Release()
+	}
+	onExtra := func(extra common.Hash) {
+		c.addIssue(&Issue{Component: ComponentSnapshot, Root: &root, Account: &account, Slot: &extra, Message: "slot not present in trie"})
+	}
+	if storageRoot == types.EmptyRootHash {
+		if slotSnap != nil {
+			if err := slotSnap.drain(onExtra); err != nil {
+				log.Warn("Snapshot storage cross-check aborted", "root", root, "account", account, "err", err)
+			}
+		}
+		return nil
+	}
+	storageTrie, err := trie.NewStateTrie(trie.StorageTrieID(root, account, storageRoot), c.triedb)
+	if err != nil {
+		c.addIssue(&Issue{Component: ComponentTrie, Root: &root, Account: &account, Message: err.Error()})
+		return nil
+	}
+	it := storageTrie.NodeIterator(nil)
+	for it.Next(true) {
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if err := c.checkNode(hasher, hash); err != nil {
+				c.addIssue(&Issue{Component: ComponentTrie, Root: &root, Account: &account, Node: &hash, Message: err.Error()})
+			}
+			c.update(func(r *Report) { r.TrieNodes++ })
+		}
+		if it.Leaf() {
+			slot := common.BytesToHash(it.LeafKey())
+			c.update(func(r *Report) { r.Slots++ })
+
+			if slotSnap != nil {
+				found, err := slotSnap.seek(slot, onExtra)
+				switch {
+				case err != nil:
+					log.Warn("Snapshot storage cross-check aborted", "root", root, "account", account, "err", err)
+					slotSnap = nil
+				case !found:
+					c.addIssue(&Issue{Component: ComponentSnapshot, Root: &root, Account: &account, Slot: &slot, Message: "slot missing from snapshot"})
+				default:
+					if !bytes.Equal(slotSnap.it.(snapshot.StorageIterator).Slot(), it.LeafBlob()) {
+						c.addIssue(&Issue{Component: ComponentSnapshot, Root: &root, Account: &account, Slot: &slot, Message: "slot mismatch with trie"})
+					}
+					slotSnap.step()
+				}
+			}
+		}
+		if err := c.throttle(ctx); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		c.addIssue(&Issue{Component: ComponentTrie, Root: &root, Account: &account, Message: err.Error()})
+	}
+	if slotSnap != nil {
+		if err := slotSnap.drain(onExtra); err != nil {
+			log.Warn("Snapshot storage cross-check aborted", "root", root, "account", account, "err", err)
+		}
+	}
+	return nil
+}
+
+// checkAncients verifies the items of the ancient store in range [tail, frozen).
+func (c *Checker) checkAncients(ctx context.Context, tail, frozen uint64) error {
+	log.Info("Verifying ancient store", "tail", tail, "frozen", frozen)
+
+	for number := tail; number < frozen; number += ancientBatch {
+		count := uint64(ancientBatch)
+		if number+count > frozen {
+			count = frozen - number
+		}
+		rawdb.VerifyChainAncients(c.db, number, count, func(kind string, number uint64, err error) {
+			c.addIssue(&Issue{Component: ComponentFreezer, Table: kind, Number: &number, Message: err.Error()})
+		})
+		c.update(func(r *Report) { r.Ancients += count })
+
+		c.work += int(count) - 1
+		if err := c.throttle(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCanonical verifies the canonical hash mappings in range [tail, head]
+// against the headers, ensuring that they form a continuous chain.
+func (c *Checker) checkCanonical(ctx context.Context, tail, head uint64) error {
+	log.Info("Verifying canonical chain", "tail", tail, "head", head)
+
+	var parent common.Hash
+	for number := tail; number <= head; number++ {
+		n := number
+		issue := func(format string, args ...interface{}) {
+			c.addIssue(&Issue{Component: ComponentCanonical, Number: &n, Message: fmt.Sprintf(format, args...)})
+		}
+		hash := rawdb.ReadCanonicalHash(c.db, number)
+		c.update(func(r *Report) { r.Canonical++ })
+
+		switch header := rawdb.ReadHeader(c.db, hash, number); {
+		case hash == (common.Hash{}):
+			issue("missing canonical hash")
+		case header == nil:
+			issue("missing header %x", hash)
+		case header.Hash() != hash:
+			issue("header hash mismatch: have %x, want %x", header.Hash(), hash)
+		case number > tail && parent != (common.Hash{}) && header.ParentHash != parent:
+			issue("parent hash mismatch: have %x, want %x", header.ParentHash, parent)
+		default:
+			if stored := rawdb.ReadHeaderNumber(c.db, hash); stored == nil || *stored != number {
+				issue("missing or invalid hash to number mapping for %x", hash)
+			}
+		}
+		parent = hash
+
+		if err := c.throttle(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapWalker steps over a snapshot iterator in lockstep with the trie leaves.
+type snapWalker struct {
+	it    snapshot.Iterator
+	valid bool // Whether the iterator is positioned on an entry
+}
+
+func newSnapWalker(it snapshot.Iterator) *snapWalker {
+	return &snapWalker{it: it, valid: it.Next()}
+}
+
+// seek moves the walker forward up to the given key, invoking onExtra for all
+// the entries skipped over. It returns whether the walker is positioned at key,
+// or an error if the iteration failed (e.g. the snapshot layer became stale).
+func (w *snapWalker) seek(key common.Hash, onExtra func(common.Hash)) (bool, error) {
+	for w.valid && bytes.Compare(w.it.Hash().Bytes(), key.Bytes()) < 0 {
+		onExtra(w.it.Hash())
+		w.valid = w.it.Next()
+	}
+	if err := w.it.Error(); err != nil {
+		return false, err
+	}
+	return w.valid && w.it.Hash() == key, nil
+}
+
+// step moves the walker to the next entry.
+func (w *snapWalker) step() {
+	w.valid = w.it.Next()
+}
+
+// drain steps over all remaining entries, invoking onExtra for each of them.
+func (w *snapWalker) drain(onExtra func(common.Hash)) error {
+	for w.valid {
+		onExtra(w.it.Hash())
+		w.valid = w.it.Next()
+	}
+	return w.it.Error()
+}
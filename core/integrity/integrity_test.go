@@ -0,0 +1,175 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package integrity
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// newTestDatabase creates a database with a single genesis-like block on top of
+// a small state containing accounts, storage and code, along with the snapshot.
+func newTestDatabase(t *testing.T) (ethdb.Database, *trie.Database, *snapshot.Tree, *types.Header) {
+	db := rawdb.NewMemoryDatabase()
+	sdb := state.NewDatabase(db)
+	statedb, _ := state.New(common.Hash{}, sdb, nil)
+	for i := byte(1); i <= 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.SetBalance(addr, big.NewInt(int64(i)))
+		if i%4 == 0 {
+			statedb.SetCode(addr, []byte{i, i})
+			for j := byte(1); j <= 8; j++ {
+				statedb.SetState(addr, common.BytesToHash([]byte{j}), common.BytesToHash([]byte{i, j}))
+			}
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to flush trie: %v", err)
+	}
+	header := &types.Header{Number: big.NewInt(0), Root: root, Difficulty: big.NewInt(1)}
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteCanonicalHash(db, header.Hash(), 0)
+	rawdb.WriteHeadHeaderHash(db, header.Hash())
+	rawdb.WriteHeadBlockHash(db, header.Hash())
+
+	snaps, err := snapshot.New(snapshot.Config{CacheSize: 16}, db, sdb.TrieDB(), root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	return db, sdb.TrieDB(), snaps, header
+}
+
+func countIssues(report *Report, component string) int {
+	var count int
+	for _, issue := range report.Issues {
+		if issue.Component == component {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCleanDatabase(t *testing.T) {
+	db, triedb, snaps, header := newTestDatabase(t)
+
+	report := New(db, triedb, snaps, DefaultConfig).Run(context.Background())
+	if !report.Done || report.Error != "" {
+		t.Fatalf("check not completed: done %v, err %q", report.Done, report.Error)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("issues reported on clean database: %v", report.Issues)
+	}
+	if len(report.Roots) != 1 || report.Roots[0] != header.Root {
+		t.Fatalf("verified roots mismatch: have %v, want %v", report.Roots, header.Root)
+	}
+	if len(report.SnapshotRoots) != 1 {
+		t.Fatalf("snapshot not cross-checked")
+	}
+	if report.Accounts != 16 || report.Slots != 32 || report.Codes != 4 {
+		t.Fatalf("stats mismatch: accounts %d, slots %d, codes %d", report.Accounts, report.Slots, report.Codes)
+	}
+	if report.Canonical != 1 {
+		t.Fatalf("canonical mappings mismatch: have %d, want 1", report.Canonical)
+	}
+}
+
+func TestSnapshotCorruption(t *testing.T) {
+	db, triedb, snaps, _ := newTestDatabase(t)
+
+	// Inject an account only present in the snapshot and modify an existing one
+	rawdb.WriteAccountSnapshot(db, common.Hash{0x01}, snapshot.SlimAccountRLP(1, big.NewInt(1), types.EmptyRootHash, emptyCode.Bytes()))
+
+	addr := crypto.Keccak256Hash(common.BytesToAddress([]byte{1}).Bytes())
+	rawdb.WriteAccountSnapshot(db, addr, snapshot.SlimAccountRLP(0, big.NewInt(1000), types.EmptyRootHash, emptyCode.Bytes()))
+
+	report := New(db, triedb, snaps, DefaultConfig).Run(context.Background())
+	if n := countIssues(report, ComponentSnapshot); n != 2 {
+		t.Fatalf("snapshot issues mismatch: have %d, want 2: %v", n, report.Issues)
+	}
+	if n := countIssues(report, ComponentTrie); n != 0 {
+		t.Fatalf("unexpected trie issues: %d", n)
+	}
+}
+
+func TestTrieCorruption(t *testing.T) {
+	db, _, _, _ := newTestDatabase(t)
+
+	// Overwrite the content of a storage trie root with junk
+	addr := common.BytesToAddress([]byte{4})
+	statedb, _ := state.New(rawdb.ReadHeadHeader(db).Root, state.NewDatabase(db), nil)
+	storageRoot := statedb.StorageTrie(addr).Hash()
+	rawdb.WriteTrieNode(db, storageRoot, []byte{0xde, 0xad})
+
+	config := DefaultConfig
+	config.SkipCanonical = true
+	report := New(db, trie.NewDatabase(db), nil, config).Run(context.Background())
+	if countIssues(report, ComponentTrie) == 0 {
+		t.Fatalf("trie corruption not detected")
+	}
+	if len(report.SnapshotRoots) != 0 {
+		t.Fatalf("snapshot cross-checked without snapshot tree")
+	}
+}
+
+func TestCanonicalCorruption(t *testing.T) {
+	db, triedb, snaps, header := newTestDatabase(t)
+
+	child := &types.Header{Number: big.NewInt(1), ParentHash: common.Hash{0xff}, Root: header.Root, Difficulty: big.NewInt(1)}
+	rawdb.WriteHeader(db, child)
+	rawdb.WriteCanonicalHash(db, child.Hash(), 1)
+	rawdb.WriteCanonicalHash(db, common.Hash{0xaa}, 2)
+	rawdb.WriteHeaderNumber(db, common.Hash{0xaa}, 2)
+	rawdb.WriteHeadHeaderHash(db, common.Hash{0xaa})
+
+	config := DefaultConfig
+	config.Roots = 0
+	report := New(db, triedb, snaps, config).Run(context.Background())
+	if n := countIssues(report, ComponentCanonical); n != 2 {
+		t.Fatalf("canonical issues mismatch: have %d, want 2: %v", n, report.Issues)
+	}
+	if report.Head != 2 || report.Canonical != 3 {
+		t.Fatalf("canonical stats mismatch: head %d, checked %d", report.Head, report.Canonical)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	db, triedb, snaps, _ := newTestDatabase(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	checker := New(db, triedb, snaps, Config{Roots: 1})
+	checker.work = throttleBatch // Force a cancellation check on the first item
+	report := checker.Run(ctx)
+	if report.Error == "" {
+		t.Fatalf("cancelled check reported success")
+	}
+}
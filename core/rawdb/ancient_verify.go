@@ -0,0 +1,120 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// chainFreezerTables is the list of chain freezer tables in a deterministic order.
+var chainFreezerTables = []string{
+	chainFreezerHashTable,
+	chainFreezerHeaderTable,
+	chainFreezerBodiesTable,
+	chainFreezerReceiptTable,
+	chainFreezerDifficultyTable,
+}
+
+// verifyRangeBytes is the maximum amount of data retrieved in one go from a
+// single ancient table during verification.
+const verifyRangeBytes = 16 * 1024 * 1024
+
+// VerifyChainAncients checks the integrity of the frozen chain items in the
+// range [start, start+count). Every item of every chain table is retrieved
+// through its index entry from the data files, the list-typed items are checked
+// to be well formed RLP and the headers are decoded and cross-checked against
+// the canonical hash table. The onIssue callback is invoked for every corrupted
+// item found.
+func VerifyChainAncients(db ethdb.AncientReader, start, count uint64, onIssue func(kind string, number uint64, err error)) {
+	items := make(map[string][][]byte)
+	for _, kind := range chainFreezerTables {
+		var blobs [][]byte
+		for uint64(len(blobs)) < count {
+			next := start + uint64(len(blobs))
+			batch, err := db.AncientRange(kind, next, count-uint64(len(blobs)), verifyRangeBytes)
+			if err == nil {
+				blobs = append(blobs, batch...)
+				continue
+			}
+			// Retrieving the range failed, step over the first item individually
+			// to pinpoint the corrupted one(s).
+			blob, err := db.Ancient(kind, next)
+			if err != nil {
+				onIssue(kind, next, err)
+			}
+			blobs = append(blobs, blob)
+		}
+		items[kind] = blobs
+	}
+	for i := uint64(0); i < count; i++ {
+		number := start + i
+		for _, kind := range []string{chainFreezerBodiesTable, chainFreezerReceiptTable} {
+			if blob := items[kind][i]; blob != nil {
+				if err := checkRLPKind(blob, rlp.List); err != nil {
+					onIssue(kind, number, err)
+				}
+			}
+		}
+		if blob := items[chainFreezerDifficultyTable][i]; blob != nil {
+			if err := checkRLPKind(blob, rlp.String); err != nil {
+				onIssue(chainFreezerDifficultyTable, number, err)
+			}
+		}
+		hash, blob := items[chainFreezerHashTable][i], items[chainFreezerHeaderTable][i]
+		if hash == nil || blob == nil {
+			continue
+		}
+		if len(hash) != common.HashLength {
+			onIssue(chainFreezerHashTable, number, fmt.Errorf("invalid hash length %d", len(hash)))
+			continue
+		}
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(blob, header); err != nil {
+			onIssue(chainFreezerHeaderTable, number, err)
+			continue
+		}
+		if header.Number.Uint64() != number {
+			onIssue(chainFreezerHeaderTable, number, fmt.Errorf("header number mismatch: have %d", header.Number))
+		}
+		if have := crypto.Keccak256Hash(blob); have != common.BytesToHash(hash) {
+			onIssue(chainFreezerHashTable, number, fmt.Errorf("canonical hash mismatch: have %x, header %x", hash, have))
+		}
+	}
+}
+
+// checkRLPKind ensures the blob is a single RLP value of the expected kind.
+func checkRLPKind(blob []byte, want rlp.Kind) error {
+	kind, _, rest, err := rlp.Split(blob)
+	if kind == rlp.Byte {
+		kind = rlp.String // Single byte strings are encoded without a prefix
+	}
+	switch {
+	case err != nil:
+		return fmt.Errorf("malformed item: %v", err)
+	case kind != want:
+		return fmt.Errorf("unexpected item kind: have %v, want %v", kind, want)
+	case len(rest) > 0:
+		return fmt.Errorf("trailing %d bytes after item", len(rest))
+	}
+	return nil
+}
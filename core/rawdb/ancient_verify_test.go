@@ -0,0 +1,73 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestVerifyChainAncients(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend")
+	}
+	defer db.Close()
+
+	var (
+		blocks = makeTestBlocks(10, 2)
+		issues = make(map[uint64]string)
+	)
+	WriteAncientBlocks(db, blocks, make([]types.Receipts, len(blocks)), big.NewInt(100))
+
+	onIssue := func(kind string, number uint64, err error) { issues[number] = kind }
+	VerifyChainAncients(db, 0, uint64(len(blocks)), onIssue)
+	if len(issues) != 0 {
+		t.Fatalf("issues reported on healthy ancients: %v", issues)
+	}
+	// Append a block whose canonical hash doesn't match the header, along with
+	// a malformed body.
+	header, _ := rlp.EncodeToBytes(&types.Header{Number: big.NewInt(10)})
+	db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		op.AppendRaw(chainFreezerHashTable, 10, common.Hash{0x01}.Bytes())
+		op.AppendRaw(chainFreezerHeaderTable, 10, header)
+		op.AppendRaw(chainFreezerBodiesTable, 10, []byte{0x01})
+		op.AppendRaw(chainFreezerReceiptTable, 10, []byte{0xc0})
+		op.Append(chainFreezerDifficultyTable, 10, big.NewInt(1))
+		return nil
+	})
+	VerifyChainAncients(db, 0, 11, func(kind string, number uint64, err error) {
+		if number != 10 {
+			t.Errorf("unexpected issue at %d (%s): %v", number, kind, err)
+		}
+		issues[number] = issues[number] + kind + ","
+	})
+	if have, want := issues[10], chainFreezerBodiesTable+","+chainFreezerHashTable+","; have != want {
+		t.Fatalf("issues mismatch: have %q, want %q", have, want)
+	}
+	// Items beyond the frozen range are reported as out of bounds
+	issues = make(map[uint64]string)
+	VerifyChainAncients(db, 11, 1, onIssue)
+	if len(issues) != 1 {
+		t.Fatalf("missing items not reported: %v", issues)
+	}
+}
@@ -48,10 +48,11 @@ type chainFreezer struct {
 	// so take advantage of that (https://golang.org/pkg/sync/atomic/#pkg-note-BUG).
 	threshold uint64 // Number of recent blocks not to freeze (params.FullImmutabilityThreshold apart from tests)
 
-	*Freezer
-	quit    chan struct{}
-	wg      sync.WaitGroup
-	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
+	ethdb.AncientStore
+	readonly bool
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	trigger  chan chan struct{} // Manual blocking freeze trigger, test determinism
 }
 
 // newChainFreezer initializes the freezer for ancient chain data.
@@ -60,17 +61,24 @@ func newChainFreezer(datadir string, namespace string, readonly bool, maxTableSi
 	if err != nil {
 		return nil, err
 	}
+	return newChainFreezerWithStore(freezer, readonly), nil
+}
+
+// newChainFreezerWithStore initializes the chain freezer on top of an already
+// opened ancient store, which may live outside of the local process.
+func newChainFreezerWithStore(store ethdb.AncientStore, readonly bool) *chainFreezer {
 	return &chainFreezer{
-		Freezer:   freezer,
-		threshold: params.FullImmutabilityThreshold,
-		quit:      make(chan struct{}),
-		trigger:   make(chan chan struct{}),
-	}, nil
+		AncientStore: store,
+		readonly:     readonly,
+		threshold:    params.FullImmutabilityThreshold,
+		quit:         make(chan struct{}),
+		trigger:      make(chan chan struct{}),
+	}
 }
 
 // Close closes the chain freezer instance and terminates the background thread.
 func (f *chainFreezer) Close() error {
-	err := f.Freezer.Close()
+	err := f.AncientStore.Close()
 	select {
 	case <-f.quit:
 	default:
@@ -126,7 +134,12 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 		}
 		number := ReadHeaderNumber(nfdb, hash)
 		threshold := atomic.LoadUint64(&f.threshold)
-		frozen := atomic.LoadUint64(&f.frozen)
+		frozen, err := f.Ancients()
+		if err != nil {
+			log.Error("Failed to retrieve frozen item count", "err", err)
+			backoff = true
+			continue
+		}
 		switch {
 		case number == nil:
 			log.Error("Current full block number unavailable", "hash", hash)
@@ -187,7 +200,7 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 
 		// Wipe out side chains also and track dangling side chains
 		var dangling []common.Hash
-		frozen = atomic.LoadUint64(&f.frozen) // Needs reload after during freezeRange
+		frozen, _ = f.Ancients() // Needs reload after during freezeRange
 		for number := first; number < frozen; number++ {
 			// Always keep the genesis block in active database
 			if number != 0 {
@@ -202,6 +202,46 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace st
 	if err != nil {
 		return nil, err
 	}
+	if err := combineFreezer(db, frdb); err != nil {
+		frdb.Close()
+		return nil, err
+	}
+	return &freezerdb{
+		ancientRoot:   ancient,
+		KeyValueStore: db,
+		AncientStore:  frdb,
+	}, nil
+}
+
+// NewDatabaseWithAncientStore creates a high level database on top of a given
+// key-value data store, using an externally provided ancient store (e.g. one
+// served by a remote freezer process) as the destination of immutable chain
+// segments. The ancient store is owned by the returned database and will be
+// closed along with it.
+func NewDatabaseWithAncientStore(db ethdb.KeyValueStore, store ethdb.AncientStore, readonly bool) (ethdb.Database, error) {
+	frdb := newChainFreezerWithStore(store, readonly)
+	if err := combineFreezer(db, frdb); err != nil {
+		frdb.Close()
+		return nil, err
+	}
+	return &freezerdb{
+		KeyValueStore: db,
+		AncientStore:  frdb,
+	}, nil
+}
+
+// NewChainAncientStore opens the plain chain freezer tables located in the
+// given root ancient directory, without the background thread freezing data
+// from a key-value store. It's meant to be used for serving the ancient chain
+// data to other processes.
+func NewChainAncientStore(ancient string, namespace string, readonly bool) (*Freezer, error) {
+	return NewFreezer(resolveChainFreezerDir(ancient), namespace, readonly, freezerTableSize, chainFreezerNoSnappy)
+}
+
+// combineFreezer cross validates the content of the key-value store and the
+// chain freezer and, if they are compatible, starts the background freezing
+// thread moving data from the former into the latter.
+func combineFreezer(db ethdb.KeyValueStore, frdb *chainFreezer) error {
 	// Since the freezer can be stored separately from the user's key-value database,
 	// there's a fairly high probability that the user requests invalid combinations
 	// of the freezer and database. Ensure that we don't shoot ourselves in the foot
@@ -231,9 +271,9 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace st
 			// the freezer and the key-value store.
 			frgenesis, err := frdb.Ancient(chainFreezerHashTable, 0)
 			if err != nil {
-				return nil, fmt.Errorf("failed to retrieve genesis from ancient %v", err)
+				return fmt.Errorf("failed to retrieve genesis from ancient %v", err)
 			} else if !bytes.Equal(kvgenesis, frgenesis) {
-				return nil, fmt.Errorf("genesis mismatch: %#x (leveldb) != %#x (ancients)", kvgenesis, frgenesis)
+				return fmt.Errorf("genesis mismatch: %#x (leveldb) != %#x (ancients)", kvgenesis, frgenesis)
 			}
 			// Key-value store and freezer belong to the same network. Ensure that they
 			// are contiguous, otherwise we might end up with a non-functional freezer.
@@ -241,7 +281,7 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace st
 				// Subsequent header after the freezer limit is missing from the database.
 				// Reject startup if the database has a more recent head.
 				if ldbNum := *ReadHeaderNumber(db, ReadHeadHeaderHash(db)); ldbNum > frozen-1 {
-					return nil, fmt.Errorf("gap in the chain between ancients (#%d) and leveldb (#%d) ", frozen, ldbNum)
+					return fmt.Errorf("gap in the chain between ancients (#%d) and leveldb (#%d) ", frozen, ldbNum)
 				}
 				// Database contains only older data than the freezer, this happens if the
 				// state was wiped and reinited from an existing freezer.
@@ -258,7 +298,7 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace st
 				// Key-value store contains more data than the genesis block, make sure we
 				// didn't freeze anything yet.
 				if kvblob, _ := db.Get(headerHashKey(1)); len(kvblob) == 0 {
-					return nil, errors.New("ancient chain segments already extracted, please set --datadir.ancient to the correct path")
+					return errors.New("ancient chain segments already extracted, please set --datadir.ancient to the correct path")
 				}
 				// Block #1 is still in the database, we're allowed to init a new freezer
 			}
@@ -274,11 +314,7 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace st
 			frdb.wg.Done()
 		}()
 	}
-	return &freezerdb{
-		ancientRoot:   ancient,
-		KeyValueStore: db,
-		AncientStore:  frdb,
-	}, nil
+	return nil
 }
 
 // NewMemoryDatabase creates an ephemeral in-memory key-value database without a
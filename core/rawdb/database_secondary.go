@@ -0,0 +1,417 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// secondaryResource is an opened instance of either the key-value store or the
+// chain freezer of a primary. It is reference counted, so that it can be closed
+// after being replaced by a fresher instance once all the iterators and
+// snapshots created from it are released.
+type secondaryResource struct {
+	kv      ethdb.KeyValueStore
+	freezer *Freezer
+
+	lock    sync.Mutex
+	refs    int
+	retired bool
+}
+
+// acquire increments the reference count of the resource.
+func (r *secondaryResource) acquire() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.refs++
+}
+
+// release decrements the reference count of the resource, closing it if it's
+// been retired and is no longer referenced.
+func (r *secondaryResource) release() {
+	r.lock.Lock()
+	r.refs--
+	done := r.refs == 0 && r.retired
+	r.lock.Unlock()
+
+	if done {
+		r.close()
+	}
+}
+
+// retire marks the resource as replaced, closing it if it's not referenced.
+func (r *secondaryResource) retire() {
+	r.lock.Lock()
+	r.retired = true
+	done := r.refs == 0
+	r.lock.Unlock()
+
+	if done {
+		r.close()
+	}
+}
+
+func (r *secondaryResource) close() {
+	if r.kv != nil {
+		r.kv.Close()
+	}
+	if r.freezer != nil {
+		r.freezer.Close()
+	}
+}
+
+// secondarydb is a read-only database following the key-value store and chain
+// freezer of a primary instance running in another process. Neither leveldb
+// nor the freezer support observing the writes of another process, so both are
+// reopened periodically to catch up with the primary.
+type secondarydb struct {
+	file    string // Path of the primary's key-value store
+	ancient string // Path of the primary's root ancient directory
+	freezer string // Path of the primary's chain freezer
+	cache   int
+	handles int
+
+	lock     sync.RWMutex       // Protects the current instances during refreshes
+	kv       *secondaryResource // Most recently opened key-value store
+	ancients *secondaryResource // Most recently opened chain freezer
+	stamp    string             // Freezer index file stamp at the time of opening
+
+	quit chan chan error
+}
+
+// NewLevelDBSecondaryDatabase opens the key-value store and the chain freezer
+// of a primary instance running in another process as a read-only database.
+// Since the writes of the primary are not observed automatically, the database
+// is reopened every refresh interval to catch up with the primary.
+func NewLevelDBSecondaryDatabase(file string, cache int, handles int, ancient string, refresh time.Duration) (ethdb.Database, error) {
+	db := &secondarydb{
+		file:    file,
+		ancient: ancient,
+		freezer: resolveChainFreezerDir(ancient),
+		cache:   cache,
+		handles: handles,
+		quit:    make(chan chan error),
+	}
+	if err := db.refresh(); err != nil {
+		return nil, err
+	}
+	go db.loop(refresh)
+	return db, nil
+}
+
+// loop periodically reopens the database until closed.
+func (db *secondarydb) loop(refresh time.Duration) {
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := db.refresh(); err != nil {
+				log.Warn("Failed to catch up with primary database", "err", err)
+			}
+		case errc := <-db.quit:
+			errc <- nil
+			return
+		}
+	}
+}
+
+// freezerStamp returns a summary of the chain freezer index files. As long as
+// the stamp is unchanged, no items have been added or removed.
+func (db *secondarydb) freezerStamp() string {
+	names := make([]string, 0, len(chainFreezerNoSnappy))
+	for name := range chainFreezerNoSnappy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var stamp string
+	for _, name := range names {
+		idx := name + ".cidx"
+		if chainFreezerNoSnappy[name] {
+			idx = name + ".ridx"
+		}
+		if stat, err := os.Stat(filepath.Join(db.freezer, idx)); err == nil {
+			stamp += fmt.Sprintf("%s:%d:%d;", idx, stat.Size(), stat.ModTime().UnixNano())
+		}
+	}
+	return stamp
+}
+
+// refresh reopens the key-value store, and the chain freezer if it has changed
+// since it was last opened.
+//
+// The primary deletes items from the key-value store after moving them into
+// the freezer, so the key-value store needs to be opened first, otherwise the
+// items frozen in between would be missing from both.
+func (db *secondarydb) refresh() error {
+	kvdb, err := leveldb.NewSecondary(db.file, db.cache, db.handles)
+	if err != nil {
+		return err
+	}
+	kv := &secondaryResource{kv: kvdb}
+
+	var ancients *secondaryResource
+	stamp := db.freezerStamp()
+	if db.ancients == nil || stamp != db.stamp {
+		freezer, err := NewSecondaryFreezer(db.freezer, freezerTableSize, chainFreezerNoSnappy)
+		if err != nil {
+			kv.close()
+			return err
+		}
+		ancients = &secondaryResource{freezer: freezer}
+	}
+	db.lock.Lock()
+	oldKV, oldAncients := db.kv, db.ancients
+	db.kv = kv
+	if ancients != nil {
+		db.ancients, db.stamp = ancients, stamp
+	} else {
+		oldAncients = nil
+	}
+	db.lock.Unlock()
+
+	if oldKV != nil {
+		oldKV.retire()
+	}
+	if oldAncients != nil {
+		oldAncients.retire()
+	}
+	return nil
+}
+
+// acquireKV returns the current key-value store, referenced.
+func (db *secondarydb) acquireKV() *secondaryResource {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	db.kv.acquire()
+	return db.kv
+}
+
+// acquireAncients returns the current chain freezer, referenced.
+func (db *secondarydb) acquireAncients() *secondaryResource {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	db.ancients.acquire()
+	return db.ancients
+}
+
+// Has retrieves if a key is present in the key-value data store.
+func (db *secondarydb) Has(key []byte) (bool, error) {
+	r := db.acquireKV()
+	defer r.release()
+	return r.kv.Has(key)
+}
+
+// Get retrieves the given key if it's present in the key-value data store.
+func (db *secondarydb) Get(key []byte) ([]byte, error) {
+	r := db.acquireKV()
+	defer r.release()
+	return r.kv.Get(key)
+}
+
+// Put is rejected by the read-only key-value store.
+func (db *secondarydb) Put(key []byte, value []byte) error {
+	return errReadOnly
+}
+
+// Delete is rejected by the read-only key-value store.
+func (db *secondarydb) Delete(key []byte) error {
+	return errReadOnly
+}
+
+// NewBatch creates a write-only key-value store batch. Writing it out is
+// rejected by the read-only key-value store.
+func (db *secondarydb) NewBatch() ethdb.Batch {
+	r := db.acquireKV()
+	defer r.release()
+	return r.kv.NewBatch()
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+func (db *secondarydb) NewBatchWithSize(size int) ethdb.Batch {
+	r := db.acquireKV()
+	defer r.release()
+	return r.kv.NewBatchWithSize(size)
+}
+
+// secondaryIterator is an iterator over an instance of the key-value store,
+// dereferencing the instance when released.
+type secondaryIterator struct {
+	ethdb.Iterator
+	resource *secondaryResource
+	once     sync.Once
+}
+
+// Release releases associated resources.
+func (it *secondaryIterator) Release() {
+	it.Iterator.Release()
+	it.once.Do(it.resource.release)
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset of database
+// content with a particular key prefix, starting at a particular initial key.
+func (db *secondarydb) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	r := db.acquireKV()
+	return &secondaryIterator{Iterator: r.kv.NewIterator(prefix, start), resource: r}
+}
+
+// secondarySnapshot is a snapshot of an instance of the key-value store,
+// dereferencing the instance when released.
+type secondarySnapshot struct {
+	ethdb.Snapshot
+	resource *secondaryResource
+	once     sync.Once
+}
+
+// Release releases associated resources.
+func (snap *secondarySnapshot) Release() {
+	snap.Snapshot.Release()
+	snap.once.Do(snap.resource.release)
+}
+
+// NewSnapshot creates a database snapshot based on the current state.
+func (db *secondarydb) NewSnapshot() (ethdb.Snapshot, error) {
+	r := db.acquireKV()
+	snap, err := r.kv.NewSnapshot()
+	if err != nil {
+		r.release()
+		return nil, err
+	}
+	return &secondarySnapshot{Snapshot: snap, resource: r}, nil
+}
+
+// Stat returns a particular internal stat of the key-value store.
+func (db *secondarydb) Stat(property string) (string, error) {
+	r := db.acquireKV()
+	defer r.release()
+	return r.kv.Stat(property)
+}
+
+// Compact is rejected by the read-only key-value store.
+func (db *secondarydb) Compact(start []byte, limit []byte) error {
+	return errReadOnly
+}
+
+// HasAncient returns an indicator whether the specified data exists in the
+// ancient store.
+func (db *secondarydb) HasAncient(kind string, number uint64) (bool, error) {
+	r := db.acquireAncients()
+	defer r.release()
+	return r.freezer.HasAncient(kind, number)
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (db *secondarydb) Ancient(kind string, number uint64) ([]byte, error) {
+	r := db.acquireAncients()
+	defer r.release()
+	return r.freezer.Ancient(kind, number)
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index 'start'.
+func (db *secondarydb) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	r := db.acquireAncients()
+	defer r.release()
+	return r.freezer.AncientRange(kind, start, count, maxBytes)
+}
+
+// Ancients returns the ancient item numbers in the ancient store.
+func (db *secondarydb) Ancients() (uint64, error) {
+	r := db.acquireAncients()
+	defer r.release()
+	return r.freezer.Ancients()
+}
+
+// Tail returns the number of first stored item in the freezer.
+func (db *secondarydb) Tail() (uint64, error) {
+	r := db.acquireAncients()
+	defer r.release()
+	return r.freezer.Tail()
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (db *secondarydb) AncientSize(kind string) (uint64, error) {
+	r := db.acquireAncients()
+	defer r.release()
+	return r.freezer.AncientSize(kind)
+}
+
+// ReadAncients runs the given read operation on a consistent view of the
+// ancient store.
+func (db *secondarydb) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	r := db.acquireAncients()
+	defer r.release()
+	return r.freezer.ReadAncients(fn)
+}
+
+// ModifyAncients is rejected by the read-only ancient store.
+func (db *secondarydb) ModifyAncients(func(ethdb.AncientWriteOp) error) (int64, error) {
+	return 0, errReadOnly
+}
+
+// TruncateHead is rejected by the read-only ancient store.
+func (db *secondarydb) TruncateHead(items uint64) error {
+	return errReadOnly
+}
+
+// TruncateTail is rejected by the read-only ancient store.
+func (db *secondarydb) TruncateTail(items uint64) error {
+	return errReadOnly
+}
+
+// Sync is rejected by the read-only ancient store.
+func (db *secondarydb) Sync() error {
+	return errReadOnly
+}
+
+// MigrateTable is rejected by the read-only ancient store.
+func (db *secondarydb) MigrateTable(kind string, convert convertLegacyFn) error {
+	return errReadOnly
+}
+
+// AncientDatadir returns the path of the root ancient directory.
+func (db *secondarydb) AncientDatadir() (string, error) {
+	return db.ancient, nil
+}
+
+// Close stops following the primary and releases the opened instances once
+// they are no longer in use.
+func (db *secondarydb) Close() error {
+	errc := make(chan error)
+	db.quit <- errc
+	err := <-errc
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.kv.retire()
+	db.ancients.retire()
+	return err
+}
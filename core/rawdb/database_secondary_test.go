@@ -0,0 +1,89 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestSecondaryDatabase(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		file    = filepath.Join(dir, "chaindata")
+		ancient = filepath.Join(file, "ancient")
+		blocks  = makeTestBlocks(20, 2)
+	)
+	primary, err := NewLevelDBDatabaseWithFreezer(file, 16, 16, ancient, "", false)
+	if err != nil {
+		t.Fatalf("failed to open primary: %v", err)
+	}
+	defer primary.Close()
+
+	WriteAncientBlocks(primary, blocks[:10], make([]types.Receipts, 10), big.NewInt(100))
+	WriteBlock(primary, blocks[10])
+	WriteHeadBlockHash(primary, blocks[10].Hash())
+
+	db, err := NewLevelDBSecondaryDatabase(file, 16, 16, ancient, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open secondary: %v", err)
+	}
+	defer db.Close()
+
+	if head := ReadHeadBlockHash(db); head != blocks[10].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head, blocks[10].Hash())
+	}
+	if frozen, _ := db.Ancients(); frozen != 10 {
+		t.Fatalf("frozen items mismatch: have %d, want 10", frozen)
+	}
+	if err := db.Put([]byte("key"), []byte("value")); err == nil {
+		t.Fatalf("write into secondary succeeded")
+	}
+	// Keep an iterator open across the refresh to ensure the retired instance
+	// stays usable.
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	// Move the primary forward, ensure it's only visible after catching up
+	WriteAncientBlocks(primary, blocks[10:15], make([]types.Receipts, 5), big.NewInt(100))
+	DeleteBlock(primary, blocks[10].Hash(), 10)
+	WriteBlock(primary, blocks[15])
+	WriteHeadBlockHash(primary, blocks[15].Hash())
+
+	if head := ReadHeadBlockHash(db); head != blocks[10].Hash() {
+		t.Fatalf("secondary observed head before catching up")
+	}
+	if err := db.(*secondarydb).refresh(); err != nil {
+		t.Fatalf("failed to catch up: %v", err)
+	}
+	if head := ReadHeadBlockHash(db); head != blocks[15].Hash() {
+		t.Fatalf("head mismatch after catching up: have %x, want %x", head, blocks[15].Hash())
+	}
+	if frozen, _ := db.Ancients(); frozen != 15 {
+		t.Fatalf("frozen items mismatch after catching up: have %d, want 15", frozen)
+	}
+	if ReadBlock(db, blocks[10].Hash(), 10) == nil {
+		t.Fatalf("frozen block missing after catching up")
+	}
+	if !it.Next() {
+		t.Fatalf("iterator over retired instance exhausted")
+	}
+}
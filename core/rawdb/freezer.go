@@ -73,6 +73,8 @@ type Freezer struct {
 	writeLock  sync.RWMutex
 	writeBatch *freezerBatch
 
+	recompressing int32 // Flag whether a table recompression is in progress (atomic)
+
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
 	instanceLock fileutil.Releaser        // File-system lock to prevent double opens
@@ -104,6 +106,13 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	if err != nil {
 		return nil, err
 	}
+	// Complete or discard any interrupted table recompression
+	if !readonly {
+		if err := recoverRecompression(datadir); err != nil {
+			lock.Release()
+			return nil, err
+		}
+	}
 	// Open all the supported data tables
 	freezer := &Freezer{
 		readonly:     readonly,
@@ -147,6 +156,52 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	return freezer, nil
 }
 
+// NewSecondaryFreezer opens a freezer in read-only mode while another process
+// may be holding it open and appending items. The freezer lock is not acquired
+// and the items being written during opening are not exposed. The opened
+// freezer doesn't observe any newer items, it needs to be reopened for that, so
+// no metrics are collected for it.
+func NewSecondaryFreezer(datadir string, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	freezer := &Freezer{
+		readonly: true,
+		tables:   make(map[string]*freezerTable),
+	}
+	for name, disableSnappy := range tables {
+		table, err := newSecondaryTable(datadir, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, maxTableSize, disableSnappy)
+		if err != nil {
+			for _, table := range freezer.tables {
+				table.Close()
+			}
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	// The writer might be in the middle of appending an item to all the tables,
+	// only expose the items present in all of them.
+	var (
+		head = uint64(math.MaxUint64)
+		tail = uint64(0)
+	)
+	for _, table := range freezer.tables {
+		if items := atomic.LoadUint64(&table.items); items < head {
+			head = items
+		}
+		if hidden := atomic.LoadUint64(&table.itemHidden); hidden > tail {
+			tail = hidden
+		}
+	}
+	if len(freezer.tables) == 0 {
+		head = 0
+	}
+	atomic.StoreUint64(&freezer.frozen, head)
+	atomic.StoreUint64(&freezer.tail, tail)
+
+	freezer.writeBatch = newFreezerBatch(freezer)
+
+	log.Debug("Opened secondary ancient database", "database", datadir, "items", head)
+	return freezer, nil
+}
+
 // Close terminates the chain freezer, unmapping all the data files.
 func (f *Freezer) Close() error {
 	f.writeLock.Lock()
@@ -159,8 +214,10 @@ func (f *Freezer) Close() error {
 				errs = append(errs, err)
 			}
 		}
-		if err := f.instanceLock.Release(); err != nil {
-			errs = append(errs, err)
+		if f.instanceLock != nil {
+			if err := f.instanceLock.Release(); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	})
 	if errs != nil {
@@ -0,0 +1,196 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// freezerCodec is the compression scheme of the items in a freezer table.
+type freezerCodec uint8
+
+const (
+	codecLegacy freezerCodec = iota // Implied by the table configuration, used by legacy metadata
+	codecNone                       // Items are stored as is
+	codecSnappy                     // Items are compressed with snappy in block format
+	codecZstd                       // Items are compressed with zstd, optionally using a dictionary
+)
+
+// String implements the stringer interface.
+func (c freezerCodec) String() string {
+	switch c {
+	case codecLegacy:
+		return "legacy"
+	case codecNone:
+		return "none"
+	case codecSnappy:
+		return "snappy"
+	case codecZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(c))
+	}
+}
+
+// parseFreezerCodec parses the name of a freezer table codec.
+func parseFreezerCodec(name string) (freezerCodec, error) {
+	switch name {
+	case "none":
+		return codecNone, nil
+	case "snappy":
+		return codecSnappy, nil
+	case "zstd":
+		return codecZstd, nil
+	default:
+		return codecLegacy, fmt.Errorf("unknown freezer codec %q", name)
+	}
+}
+
+// zstdDictMagic is the magic number of dictionaries in the zstd dictionary format
+// (e.g. the ones trained by 'zstd --train'). Dictionaries without it are used as
+// raw content dictionaries.
+var zstdDictMagic = []byte{0x37, 0xa4, 0x30, 0xec}
+
+// zstdRawDictID is the dictionary id recorded in the frames compressed with a raw
+// content dictionary. Each table has at most one dictionary, so a constant suffices.
+const zstdRawDictID = 0x67657468
+
+// itemCodec compresses and decompresses the items of a freezer table. The methods
+// are safe for concurrent use.
+type itemCodec interface {
+	// compress compresses the data, using the space of dst if large enough.
+	compress(dst []byte, data []byte) []byte
+
+	// decompress decompresses the data into a newly allocated slice.
+	decompress(data []byte) ([]byte, error)
+
+	// decompressedSize returns the size of the decompressed data, or an estimate
+	// if it can't be determined without decompressing.
+	decompressedSize(data []byte) int
+
+	// close releases any resources held by the codec.
+	close()
+}
+
+// newItemCodec creates the item codec of the given scheme.
+func newItemCodec(codec freezerCodec, dict []byte) (itemCodec, error) {
+	if len(dict) > 0 && codec != codecZstd {
+		return nil, fmt.Errorf("dictionary not supported by %v codec", codec)
+	}
+	switch codec {
+	case codecNone:
+		return noneCodec{}, nil
+	case codecSnappy:
+		return snappyCodec{}, nil
+	case codecZstd:
+		return newZstdCodec(dict)
+	default:
+		return nil, fmt.Errorf("unsupported freezer codec %v", codec)
+	}
+}
+
+// noneCodec stores the items uncompressed.
+type noneCodec struct{}
+
+func (noneCodec) compress(dst []byte, data []byte) []byte { return data }
+func (noneCodec) decompress(data []byte) ([]byte, error)  { return data, nil }
+func (noneCodec) decompressedSize(data []byte) int        { return len(data) }
+func (noneCodec) close()                                  {}
+
+// snappyCodec compresses the items with snappy in block format.
+type snappyCodec struct{}
+
+func (snappyCodec) compress(dst []byte, data []byte) []byte {
+	// The snappy library does not care what the capacity of the buffer is,
+	// but only checks the length. If the length is too small, it will
+	// allocate a brand new buffer.
+	// To avoid that, we check the required size here, and grow the size of the
+	// buffer to utilize the full capacity.
+	if n := snappy.MaxEncodedLen(len(data)); cap(dst) < n {
+		dst = make([]byte, n)
+	}
+	return snappy.Encode(dst[:cap(dst)], data)
+}
+
+func (snappyCodec) decompress(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
+func (snappyCodec) decompressedSize(data []byte) int {
+	size, _ := snappy.DecodedLen(data)
+	return size
+}
+
+func (snappyCodec) close() {}
+
+// zstdCodec compresses the items with zstd, each item being a single frame.
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// newZstdCodec creates a zstd codec, using the given dictionary if non-empty.
+func newZstdCodec(dict []byte) (*zstdCodec, error) {
+	var (
+		eopts = []zstd.EOption{zstd.WithEncoderLevel(zstd.SpeedBetterCompression), zstd.WithEncoderConcurrency(1)}
+		dopts []zstd.DOption
+	)
+	switch {
+	case len(dict) == 0:
+	case bytes.HasPrefix(dict, zstdDictMagic):
+		eopts = append(eopts, zstd.WithEncoderDict(dict))
+		dopts = append(dopts, zstd.WithDecoderDicts(dict))
+	default:
+		eopts = append(eopts, zstd.WithEncoderDictRaw(zstdRawDictID, dict))
+		dopts = append(dopts, zstd.WithDecoderDictRaw(zstdRawDictID, dict))
+	}
+	encoder, err := zstd.NewWriter(nil, eopts...)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil, dopts...)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+	return &zstdCodec{encoder: encoder, decoder: decoder}, nil
+}
+
+func (c *zstdCodec) compress(dst []byte, data []byte) []byte {
+	return c.encoder.EncodeAll(data, dst[:0])
+}
+
+func (c *zstdCodec) decompress(data []byte) ([]byte, error) {
+	return c.decoder.DecodeAll(data, nil)
+}
+
+func (c *zstdCodec) decompressedSize(data []byte) int {
+	var header zstd.Header
+	if err := header.Decode(data); err != nil || !header.HasFCS {
+		return len(data)
+	}
+	return int(header.FrameContentSize)
+}
+
+func (c *zstdCodec) close() {
+	c.encoder.Close()
+	c.decoder.Close()
+}
@@ -24,7 +24,10 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
-const freezerVersion = 1 // The initial version tag of freezer table metadata
+const (
+	freezerVersion      = 1 // The initial version tag of freezer table metadata
+	freezerVersionCodec = 2 // The version tag of metadata recording the table codec
+)
 
 // freezerTableMeta wraps all the metadata of the freezer table.
 type freezerTableMeta struct {
@@ -36,6 +39,24 @@ type freezerTableMeta struct {
 	// plus the number of items hidden in the table, so it should never
 	// be lower than the "actual tail".
 	VirtualTail uint64
+
+	// Codec is the compression scheme of the items. Metadata of the initial
+	// version doesn't record it, the scheme is implied by the table config.
+	Codec freezerCodec `rlp:"optional"`
+
+	// Dict is the compression dictionary used by the codec, if any.
+	Dict []byte `rlp:"optional"`
+}
+
+// newCodecMetadata initializes the metadata object of a table with an explicitly
+// recorded codec.
+func newCodecMetadata(tail uint64, codec freezerCodec, dict []byte) *freezerTableMeta {
+	return &freezerTableMeta{
+		Version:     freezerVersionCodec,
+		VirtualTail: tail,
+		Codec:       codec,
+		Dict:        dict,
+	}
 }
 
 // newMetadata initializes the metadata object with the given virtual tail.
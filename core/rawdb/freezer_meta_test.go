@@ -17,8 +17,11 @@
 package rawdb
 
 import (
+	"bytes"
 	"os"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 func TestReadWriteFreezerTableMeta(t *testing.T) {
@@ -58,3 +61,48 @@ func TestInitializeFreezerTableMeta(t *testing.T) {
 		t.Fatalf("Unexpected virtual tail field")
 	}
 }
+
+func TestReadWriteFreezerTableCodecMeta(t *testing.T) {
+	f, err := os.CreateTemp(os.TempDir(), "*")
+	if err != nil {
+		t.Fatalf("Failed to create file %v", err)
+	}
+	err = writeMetadata(f, newCodecMetadata(100, codecZstd, []byte{1, 2, 3}))
+	if err != nil {
+		t.Fatalf("Failed to write metadata %v", err)
+	}
+	meta, err := readMetadata(f)
+	if err != nil {
+		t.Fatalf("Failed to read metadata %v", err)
+	}
+	if meta.Version != freezerVersionCodec {
+		t.Fatalf("Unexpected version field")
+	}
+	if meta.VirtualTail != uint64(100) {
+		t.Fatalf("Unexpected virtual tail field")
+	}
+	if meta.Codec != codecZstd || !bytes.Equal(meta.Dict, []byte{1, 2, 3}) {
+		t.Fatalf("Unexpected codec fields: %v %x", meta.Codec, meta.Dict)
+	}
+	// Legacy metadata must decode without a codec
+	if err := writeMetadata(f, newMetadata(5)); err != nil {
+		t.Fatalf("Failed to write metadata %v", err)
+	}
+	if err := f.Truncate(int64(len(mustEncodeMeta(t, newMetadata(5))))); err != nil {
+		t.Fatal(err)
+	}
+	if meta, err = readMetadata(f); err != nil {
+		t.Fatalf("Failed to read metadata %v", err)
+	}
+	if meta.Codec != codecLegacy || meta.Dict != nil {
+		t.Fatalf("Unexpected codec fields in legacy metadata: %v %x", meta.Codec, meta.Dict)
+	}
+}
+
+func mustEncodeMeta(t *testing.T, meta *freezerTableMeta) []byte {
+	blob, err := rlp.EncodeToBytes(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob
+}
@@ -0,0 +1,347 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// recompressDir is the directory a table is recompressed into.
+	recompressDir = "recompress"
+
+	// recompressedDir is the directory holding a fully recompressed table
+	// until its files are moved over the original ones. Renaming the working
+	// directory to it is the commit point of a recompression.
+	recompressedDir = "recompressed"
+
+	// maxDictSamples is the maximum number of items sampled for a dictionary.
+	maxDictSamples = 1 << 16
+)
+
+var errNoAncientFreezer = errors.New("database has no local ancient freezer")
+
+// RecompressTable rewrites all the items of the given table with the given
+// codec and optional zstd dictionary. The items are copied while the freezer
+// stays fully operational, the writers are only blocked while catching up with
+// the items appended meanwhile and swapping the table files.
+func (f *Freezer) RecompressTable(kind string, codec string, dict []byte) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	table, ok := f.tables[kind]
+	if !ok {
+		return errUnknownTable
+	}
+	c, err := parseFreezerCodec(codec)
+	if err != nil {
+		return err
+	}
+	if !atomic.CompareAndSwapInt32(&f.recompressing, 0, 1) {
+		return errors.New("recompression already in progress")
+	}
+	defer atomic.StoreInt32(&f.recompressing, 0)
+
+	if (c == codecNone) != table.noCompression {
+		return fmt.Errorf("table %s can't switch between compressed and raw storage", kind)
+	}
+	// Validate the codec before spending time on the copy
+	coder, err := newItemCodec(c, dict)
+	if err != nil {
+		return err
+	}
+	coder.close()
+
+	// Like the migration, recompression doesn't handle tail-deleted tables.
+	if atomic.LoadUint64(&table.itemOffset) > 0 || atomic.LoadUint64(&table.itemHidden) > 0 {
+		return fmt.Errorf("recompression not supported for tail-deleted freezers")
+	}
+	path := filepath.Join(table.path, recompressDir)
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	newTable, err := newTable(path, kind, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, table.maxFileSize, table.noCompression, false)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(path)
+	defer newTable.Close()
+
+	meta := newCodecMetadata(0, c, dict)
+	if err := writeMetadata(newTable.meta, meta); err != nil {
+		return err
+	}
+	if err := newTable.loadCodec(meta); err != nil {
+		return err
+	}
+	log.Info("Recompressing freezer table", "table", kind, "from", table.codec, "to", c, "dict", len(dict))
+
+	// Copy over the bulk of the items without holding the write lock
+	var (
+		start       = time.Now()
+		truncations = table.truncationCount()
+	)
+	if err := copyTableItems(table, newTable, start); err != nil {
+		return err
+	}
+	// Block the writers, catch up with the new items and swap the files
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	if atomic.LoadUint64(&table.itemHidden) > 0 {
+		return fmt.Errorf("table %s tail truncated during recompression", kind)
+	}
+	if table.truncationCount() != truncations {
+		// The copied items might have been replaced, start over
+		log.Info("Freezer table truncated during recompression, restarting", "table", kind)
+		if err := newTable.truncateHead(0); err != nil {
+			return err
+		}
+	}
+	if err := copyTableItems(table, newTable, start); err != nil {
+		return err
+	}
+	if err := newTable.Sync(); err != nil {
+		return err
+	}
+	if err := newTable.Close(); err != nil {
+		return err
+	}
+	done := filepath.Join(table.path, recompressedDir)
+	if err := os.RemoveAll(done); err != nil {
+		return err
+	}
+	if err := os.Rename(path, done); err != nil {
+		return err
+	}
+	if err := table.replaceFiles(done); err != nil {
+		return err
+	}
+	size, _ := table.size()
+	log.Info("Recompressed freezer table", "table", kind, "codec", c, "size", common.StorageSize(size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// copyTableItems appends the items of the source table missing from the
+// destination table.
+func copyTableItems(src, dst *freezerTable, start time.Time) error {
+	var (
+		items  = atomic.LoadUint64(&src.items)
+		batch  = dst.newBatch()
+		logged = time.Now()
+	)
+	for i := atomic.LoadUint64(&dst.items); i < items; {
+		data, err := src.RetrieveItems(i, 1024, 1024*1024)
+		if err != nil {
+			return err
+		}
+		for j, item := range data {
+			if err := batch.AppendRaw(i+uint64(j), item); err != nil {
+				return err
+			}
+		}
+		i += uint64(len(data))
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Recompressing freezer table", "table", src.name, "items", i, "total", items, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return batch.commit()
+}
+
+// SampleDictionary assembles a raw content dictionary of the given size for the
+// zstd codec from the items of the given table. The items are sampled evenly
+// across the whole table, no matter how many of them fit into the dictionary.
+func (f *Freezer) SampleDictionary(kind string, size int) ([]byte, error) {
+	table, ok := f.tables[kind]
+	if !ok {
+		return nil, errUnknownTable
+	}
+	var (
+		tail = atomic.LoadUint64(&table.itemHidden)
+		span = atomic.LoadUint64(&table.items) - tail
+		dict []byte
+	)
+	// Visit the midpoint first, then the quarters, eighths and so on
+	for div := uint64(2); len(dict) < size && div/2 <= span && div <= maxDictSamples; div *= 2 {
+		for j := uint64(1); j < div && len(dict) < size; j += 2 {
+			item, err := table.Retrieve(tail + span*j/div)
+			if err != nil {
+				return nil, err
+			}
+			dict = append(dict, item...)
+		}
+	}
+	if len(dict) == 0 {
+		return nil, fmt.Errorf("table %s has no items to sample", kind)
+	}
+	if len(dict) > size {
+		dict = dict[:size]
+	}
+	return dict, nil
+}
+
+// truncationCount returns the number of times the table head was truncated.
+func (t *freezerTable) truncationCount() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.truncations
+}
+
+// replaceFiles replaces all the files of the table with the ones of a completed
+// recompression and reopens the table.
+func (t *freezerTable) replaceFiles(done string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	oldSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	t.releaseFilesAfter(0, false)
+	t.releaseFile(0)
+	t.head = nil
+	t.index.Close()
+	t.meta.Close()
+
+	if err := finishRecompression(t.path, done); err != nil {
+		return err
+	}
+	if t.index, err = openFreezerFileForAppend(t.index.Name()); err != nil {
+		return err
+	}
+	if t.meta, err = openFreezerFileForAppend(t.meta.Name()); err != nil {
+		return err
+	}
+	// Concurrent readers might still be decompressing with the old codec,
+	// leave it to the garbage collector instead of closing it.
+	t.coder = nil
+	if err := t.repair(); err != nil {
+		return err
+	}
+	newSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	t.sizeGauge.Inc(int64(newSize) - int64(oldSize))
+	return nil
+}
+
+// finishRecompression moves the files of a completed recompression into the
+// freezer directory, deleting the data files of the original table beyond the
+// new head. It is idempotent, so an interrupted attempt can be finished later.
+func finishRecompression(datadir string, done string) error {
+	entries, err := os.ReadDir(done)
+	if err != nil {
+		return err
+	}
+	// Find the index file, which is moved last to be able to resume
+	var name, index, ext string
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".ridx":
+			name, index, ext = strings.TrimSuffix(entry.Name(), ".ridx"), entry.Name(), "rdat"
+		case ".cidx":
+			name, index, ext = strings.TrimSuffix(entry.Name(), ".cidx"), entry.Name(), "cdat"
+		}
+	}
+	if index == "" {
+		// Everything was moved already
+		return os.RemoveAll(done)
+	}
+	blob, err := os.ReadFile(filepath.Join(done, index))
+	if err != nil {
+		return err
+	}
+	if len(blob) < indexEntrySize {
+		return fmt.Errorf("recompressed index %s is empty", index)
+	}
+	var last indexEntry
+	last.unmarshalBinary(blob[len(blob)-len(blob)%indexEntrySize-indexEntrySize:])
+
+	// Delete the original data files beyond the new head
+	for num := last.filenum + 1; ; num++ {
+		err := os.Remove(filepath.Join(datadir, fmt.Sprintf("%s.%04d.%s", name, num, ext)))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if entry.Name() == index {
+			continue
+		}
+		if err := os.Rename(filepath.Join(done, entry.Name()), filepath.Join(datadir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(filepath.Join(done, index), filepath.Join(datadir, index)); err != nil {
+		return err
+	}
+	log.Info("Replaced freezer table files with recompressed ones", "table", name)
+	return os.Remove(done)
+}
+
+// recoverRecompression finishes a recompression interrupted after its commit
+// point and discards the ones interrupted before.
+func recoverRecompression(datadir string) error {
+	done := filepath.Join(datadir, recompressedDir)
+	if _, err := os.Stat(done); err == nil {
+		if err := finishRecompression(datadir, done); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(filepath.Join(datadir, recompressDir))
+}
+
+// RecompressAncientTable recompresses a table of the chain freezer of the
+// given database while it is in use. If dictSize is non-zero and no dictionary
+// is given, one is sampled from the table.
+func RecompressAncientTable(db ethdb.Database, kind string, codec string, dict []byte, dictSize int) error {
+	frdb, ok := db.(*freezerdb)
+	if !ok {
+		return errNoAncientFreezer
+	}
+	chain, ok := frdb.AncientStore.(*chainFreezer)
+	if !ok {
+		return errNoAncientFreezer
+	}
+	f, ok := chain.AncientStore.(*Freezer)
+	if !ok {
+		return errNoAncientFreezer
+	}
+	if len(dict) == 0 && dictSize > 0 {
+		var err error
+		if dict, err = f.SampleDictionary(kind, dictSize); err != nil {
+			return err
+		}
+	}
+	return f.RecompressTable(kind, codec, dict)
+}
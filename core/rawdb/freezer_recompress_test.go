@@ -0,0 +1,286 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var recompressTestTableDef = map[string]bool{"test": false}
+
+// recompressTestItem returns a compressible item with a shared structure.
+func recompressTestItem(i uint64) []byte {
+	return []byte(strings.Repeat(fmt.Sprintf("receipt %d status 1 gas %d;", i, i*21000), int(i%7)+1))
+}
+
+func appendRecompressTestItems(t *testing.T, f *Freezer, from, to uint64) {
+	t.Helper()
+
+	_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := from; i < to; i++ {
+			if err := op.AppendRaw("test", i, recompressTestItem(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("ModifyAncients failed:", err)
+	}
+}
+
+func checkRecompressTestItems(t *testing.T, f *Freezer, items uint64) {
+	t.Helper()
+
+	checkAncientCount(t, f, "test", items)
+	for i := uint64(0); i < items; i++ {
+		blob, err := f.Ancient("test", i)
+		if err != nil {
+			t.Fatalf("failed to read item %d: %v", i, err)
+		}
+		if !bytes.Equal(blob, recompressTestItem(i)) {
+			t.Fatalf("item %d mismatch: have %q", i, blob)
+		}
+	}
+}
+
+func TestFreezerRecompress(t *testing.T) {
+	t.Parallel()
+
+	f, dir := newFreezerForTesting(t, recompressTestTableDef)
+	appendRecompressTestItems(t, f, 0, 500)
+	if codec := f.tables["test"].codec; codec != codecSnappy || !f.tables["test"].legacyMeta {
+		t.Fatalf("new table codec %v, legacy %v", codec, f.tables["test"].legacyMeta)
+	}
+	// Recompress with zstd, first without and then with a dictionary
+	if err := f.RecompressTable("test", "zstd", nil); err != nil {
+		t.Fatal("recompression failed:", err)
+	}
+	checkRecompressTestItems(t, f, 500)
+
+	dict, err := f.SampleDictionary("test", 1024)
+	if err != nil {
+		t.Fatal("dictionary sampling failed:", err)
+	}
+	if len(dict) != 1024 {
+		t.Fatalf("dictionary size %d, want 1024", len(dict))
+	}
+	if err := f.RecompressTable("test", "zstd", dict); err != nil {
+		t.Fatal("recompression failed:", err)
+	}
+	checkRecompressTestItems(t, f, 500)
+
+	// Append items with the new codec, including through the reused write batch
+	appendRecompressTestItems(t, f, 500, 600)
+	checkRecompressTestItems(t, f, 600)
+	if _, err := os.Stat(filepath.Join(dir, recompressedDir)); !os.IsNotExist(err) {
+		t.Fatal("recompression directory left behind")
+	}
+	f.Close()
+
+	// Reopen the freezer and check the codec is picked up from the metadata
+	f, err = NewFreezer(dir, "", false, 2049, recompressTestTableDef)
+	if err != nil {
+		t.Fatal("can't reopen freezer:", err)
+	}
+	defer f.Close()
+
+	table := f.tables["test"]
+	if table.codec != codecZstd || !bytes.Equal(table.codecDict, dict) || table.legacyMeta {
+		t.Fatalf("reopened table codec %v, dict %d bytes, legacy %v", table.codec, len(table.codecDict), table.legacyMeta)
+	}
+	checkRecompressTestItems(t, f, 600)
+
+	// Tail truncation must retain the codec
+	if err := f.TruncateTail(1); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := readMetadata(table.meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Codec != codecZstd || meta.VirtualTail != 1 {
+		t.Fatalf("metadata after tail truncation: codec %v, tail %d", meta.Codec, meta.VirtualTail)
+	}
+}
+
+func TestFreezerRecompressErrors(t *testing.T) {
+	t.Parallel()
+
+	f, _ := newFreezerForTesting(t, map[string]bool{"test": false, "raw": true})
+	defer f.Close()
+
+	if err := f.RecompressTable("unknown", "zstd", nil); err != errUnknownTable {
+		t.Fatalf("unknown table: have %v", err)
+	}
+	if err := f.RecompressTable("test", "lz4", nil); err == nil {
+		t.Fatal("unknown codec accepted")
+	}
+	if err := f.RecompressTable("test", "none", nil); err == nil {
+		t.Fatal("switching to raw storage accepted")
+	}
+	if err := f.RecompressTable("raw", "zstd", nil); err == nil {
+		t.Fatal("switching to compressed storage accepted")
+	}
+	if err := f.RecompressTable("test", "snappy", []byte{1, 2, 3}); err == nil {
+		t.Fatal("dictionary accepted for snappy")
+	}
+}
+
+// This test recompresses a table while it is concurrently read and appended to.
+func TestFreezerRecompressConcurrent(t *testing.T) {
+	t.Parallel()
+
+	f, _ := newFreezerForTesting(t, recompressTestTableDef)
+	defer f.Close()
+	appendRecompressTestItems(t, f, 0, 2000)
+
+	var (
+		wg   sync.WaitGroup
+		stop = make(chan struct{})
+		errc = make(chan error, 3)
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := uint64(2000); ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+				return op.AppendRaw("test", i, recompressTestItem(i))
+			})
+			if err != nil {
+				errc <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := uint64(0); ; i = (i + 7) % 2000 {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			blob, err := f.Ancient("test", i)
+			if err != nil {
+				errc <- err
+				return
+			}
+			if !bytes.Equal(blob, recompressTestItem(i)) {
+				errc <- fmt.Errorf("item %d mismatch", i)
+				return
+			}
+		}
+	}()
+	errc <- f.RecompressTable("test", "zstd", nil)
+	close(stop)
+	wg.Wait()
+	close(errc)
+
+	for err := range errc {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	frozen, _ := f.Ancients()
+	checkRecompressTestItems(t, f, frozen)
+	if codec := f.tables["test"].codec; codec != codecZstd {
+		t.Fatalf("table codec %v, want zstd", codec)
+	}
+}
+
+// This test checks that a recompression interrupted after its commit point is
+// completed when the freezer is reopened, and one interrupted before is dropped.
+func TestFreezerRecompressRecovery(t *testing.T) {
+	t.Parallel()
+
+	// Create two identical freezers and recompress one of them
+	f, dir := newFreezerForTesting(t, recompressTestTableDef)
+	appendRecompressTestItems(t, f, 0, 300)
+	f.Close()
+
+	g, gdir := newFreezerForTesting(t, recompressTestTableDef)
+	appendRecompressTestItems(t, g, 0, 300)
+	if err := g.RecompressTable("test", "zstd", nil); err != nil {
+		t.Fatal("recompression failed:", err)
+	}
+	g.Close()
+
+	// Place the recompressed files into the first freezer as if it crashed
+	// after already moving some of them over.
+	done := filepath.Join(dir, recompressedDir)
+	if err := os.Mkdir(done, 0755); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(gdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "test.") {
+			continue
+		}
+		target := done
+		if i%2 == 0 && strings.HasSuffix(entry.Name(), ".cdat") {
+			target = dir
+		}
+		blob, err := os.ReadFile(filepath.Join(gdir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(target, entry.Name()), blob, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Leave an incomplete attempt behind too
+	if err := os.MkdirAll(filepath.Join(dir, recompressDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err = NewFreezer(dir, "", false, 2049, recompressTestTableDef)
+	if err != nil {
+		t.Fatal("can't reopen freezer:", err)
+	}
+	defer f.Close()
+
+	if codec := f.tables["test"].codec; codec != codecZstd {
+		t.Fatalf("table codec %v, want zstd", codec)
+	}
+	checkRecompressTestItems(t, f, 300)
+	for _, name := range []string{recompressDir, recompressedDir} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("directory %s left behind", name)
+		}
+	}
+	// No original data files beyond the new head may remain
+	files, _ := filepath.Glob(filepath.Join(dir, "test.*.cdat"))
+	if want, _ := filepath.Glob(filepath.Join(gdir, "test.*.cdat")); len(files) != len(want) {
+		t.Fatalf("data files: have %d, want %d", len(files), len(want))
+	}
+}
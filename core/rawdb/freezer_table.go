@@ -30,7 +30,6 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
-	"github.com/golang/snappy"
 )
 
 var (
@@ -44,6 +43,10 @@ var (
 
 	// errNotSupported is returned if the database doesn't support the required operation.
 	errNotSupported = errors.New("this operation is not supported")
+
+	// errCorruptIndex is returned if two sequential index entries of the table
+	// describe an item with a negative size.
+	errCorruptIndex = errors.New("corrupt index entry")
 )
 
 // indexEntry contains the number/id of the file that the data resides in, as well as the
@@ -101,8 +104,14 @@ type freezerTable struct {
 	// should never be lower than itemOffset.
 	itemHidden uint64
 
-	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
+	noCompression bool         // if true, disables snappy compression. Note: does not work retroactively
+	codec         freezerCodec // Compression scheme of the items, recorded in the metadata
+	codecDict     []byte       // Compression dictionary used by the codec, if any
+	coder         itemCodec    // Compressor and decompressor of the items
+	legacyMeta    bool         // Whether the metadata predates recording the codec
+	truncations   uint64       // Number of head truncations, used to detect rewritten items
 	readonly      bool
+	secondary     bool   // if true, the table is concurrently written by another process
 	maxFileSize   uint32 // Max file size for data-files
 	name          string
 	path          string
@@ -132,6 +141,18 @@ func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerT
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	return openTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, noCompression, readonly, false)
+}
+
+// newSecondaryTable opens a freezer table in read-only mode while another process
+// may be appending to it. Instead of failing on (or repairing) the partially
+// written items at the head, the table only exposes the fully written ones.
+func newSecondaryTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression bool) (*freezerTable, error) {
+	return openTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, noCompression, true, true)
+}
+
+// openTable opens a freezer table in the given access mode.
+func openTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly, secondary bool) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
@@ -180,6 +201,7 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		logger:        log.New("database", path, "table", name),
 		noCompression: noCompression,
 		readonly:      readonly,
+		secondary:     secondary,
 		maxFileSize:   maxFilesize,
 	}
 	if err := tab.repair(); err != nil {
@@ -214,7 +236,7 @@ func (t *freezerTable) repair() error {
 		}
 	}
 	// Ensure the index is a multiple of indexEntrySize bytes
-	if overflow := stat.Size() % indexEntrySize; overflow != 0 {
+	if overflow := stat.Size() % indexEntrySize; overflow != 0 && !t.secondary {
 		truncateFreezerFile(t.index, stat.Size()-overflow) // New file can't trigger this path
 	}
 	// Retrieve the file sizes and prepare for truncation
@@ -223,6 +245,12 @@ func (t *freezerTable) repair() error {
 	}
 	offsetsSize := stat.Size()
 
+	// The index of a secondary table might be in the middle of an append,
+	// ignore the partially written entry there
+	if t.secondary {
+		offsetsSize -= offsetsSize % indexEntrySize
+	}
+
 	// Open the head file
 	var (
 		firstIndex  indexEntry
@@ -248,6 +276,9 @@ func (t *freezerTable) repair() error {
 		return err
 	}
 	t.itemHidden = meta.VirtualTail
+	if err := t.loadCodec(meta); err != nil {
+		return err
+	}
 
 	// Read the last index, use the default value in case the freezer is empty
 	if offsetsSize == indexEntrySize {
@@ -272,6 +303,12 @@ func (t *freezerTable) repair() error {
 	// Keep truncating both files until they come in sync
 	contentExp = int64(lastIndex.offset)
 	for contentExp != contentSize {
+		// Data appended to a secondary table's head before its index entry is
+		// written, ignore it
+		if t.secondary && contentExp < contentSize {
+			contentSize = contentExp
+			break
+		}
 		// Truncate the head file to the last offset pointer
 		if contentExp < contentSize {
 			t.logger.Warn("Truncating dangling head", "indexed", common.StorageSize(contentExp), "stored", common.StorageSize(contentSize))
@@ -333,11 +370,12 @@ func (t *freezerTable) repair() error {
 	t.headBytes = contentSize
 	t.headId = lastIndex.filenum
 
-	// Delete the leftover files because of head deletion
-	t.releaseFilesAfter(t.headId, true)
+	// Delete the leftover files because of head deletion. The files of
+	// a secondary table are owned by the writer, leave them be.
+	t.releaseFilesAfter(t.headId, !t.secondary)
 
 	// Delete the leftover files because of tail deletion
-	t.releaseFilesBefore(t.tailId, true)
+	t.releaseFilesBefore(t.tailId, !t.secondary)
 
 	// Close opened files and preopen all files
 	if err := t.preopen(); err != nil {
@@ -347,6 +385,40 @@ func (t *freezerTable) repair() error {
 	return nil
 }
 
+// loadCodec initializes the item codec from the table metadata. Legacy metadata
+// doesn't record the codec, the configured compression is used then.
+func (t *freezerTable) loadCodec(meta *freezerTableMeta) error {
+	codec := meta.Codec
+	if codec == codecLegacy {
+		codec = codecSnappy
+		if t.noCompression {
+			codec = codecNone
+		}
+	}
+	if (codec == codecNone) != t.noCompression {
+		return fmt.Errorf("table codec %v doesn't match the configured compression", codec)
+	}
+	coder, err := newItemCodec(codec, meta.Dict)
+	if err != nil {
+		return err
+	}
+	if t.coder != nil {
+		t.coder.close()
+	}
+	t.codec, t.codecDict, t.coder = codec, meta.Dict, coder
+	t.legacyMeta = meta.Codec == codecLegacy
+	return nil
+}
+
+// newMetadata creates the metadata of the table with the given virtual tail,
+// retaining the recorded codec.
+func (t *freezerTable) newMetadata(tail uint64) *freezerTableMeta {
+	if t.legacyMeta {
+		return newMetadata(tail)
+	}
+	return newCodecMetadata(tail, t.codec, t.codecDict)
+}
+
 // preopen opens all files that the freezer will need. This method should be called from an init-context,
 // since it assumes that it doesn't have to bother with locking
 // The rationale for doing preopen is to not have to do it from within Retrieve, thus not needing to ever
@@ -433,6 +505,7 @@ func (t *freezerTable) truncateHead(items uint64) error {
 	// All data files truncated, set internal counters and return
 	t.headBytes = int64(expected.offset)
 	atomic.StoreUint64(&t.items, items)
+	t.truncations++
 
 	// Retrieve the new size and update the total size counter
 	newSize, err := t.sizeNolock()
@@ -473,7 +546,7 @@ func (t *freezerTable) truncateTail(items uint64) error {
 	}
 	// Update the virtual tail marker and hidden these entries in table.
 	atomic.StoreUint64(&t.itemHidden, items)
-	if err := writeMetadata(t.meta, newMetadata(items)); err != nil {
+	if err := writeMetadata(t.meta, t.newMetadata(items)); err != nil {
 		return err
 	}
 	// Hidden items still fall in the current tail file, no data file
@@ -570,6 +643,11 @@ func (t *freezerTable) Close() error {
 	}
 	t.head = nil
 
+	if t.coder != nil {
+		t.coder.close()
+		t.coder = nil
+	}
+
 	if errs != nil {
 		return fmt.Errorf("%v", errs)
 	}
@@ -682,7 +760,7 @@ func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
 // item, it _will_ return one element and possibly overflow the maxBytes.
 func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, error) {
 	// First we read the 'raw' data, which might be compressed.
-	diskData, sizes, err := t.retrieveItems(start, count, maxBytes)
+	diskData, sizes, coder, err := t.retrieveItems(start, count, maxBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -695,22 +773,15 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 	for i, diskSize := range sizes {
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
-		decompressedSize := diskSize
-		if !t.noCompression {
-			decompressedSize, _ = snappy.DecodedLen(item)
-		}
+		decompressedSize := coder.decompressedSize(item)
 		if i > 0 && uint64(outputSize+decompressedSize) > maxBytes {
 			break
 		}
-		if !t.noCompression {
-			data, err := snappy.Decode(nil, item)
-			if err != nil {
-				return nil, err
-			}
-			output = append(output, data)
-		} else {
-			output = append(output, item)
+		data, err := coder.decompress(item)
+		if err != nil {
+			return nil, err
 		}
+		output = append(output, data)
 		outputSize += decompressedSize
 	}
 	return output, nil
@@ -718,14 +789,15 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 
 // retrieveItems reads up to 'count' items from the table. It reads at least
 // one item, but otherwise avoids reading more than maxBytes bytes.
-// It returns the (potentially compressed) data, and the sizes.
-func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []int, error) {
+// It returns the (potentially compressed) data, the sizes and the codec to
+// decompress the data with.
+func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []int, itemCodec, error) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
 	// Ensure the table and the item are accessible
 	if t.index == nil || t.head == nil {
-		return nil, nil, errClosed
+		return nil, nil, nil, errClosed
 	}
 	var (
 		items  = atomic.LoadUint64(&t.items)      // the total items(head + 1)
@@ -734,7 +806,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	// Ensure the start is written, not deleted from the tail, and that the
 	// caller actually wants something
 	if items <= start || hidden > start || count == 0 {
-		return nil, nil, errOutOfBounds
+		return nil, nil, nil, errOutOfBounds
 	}
 	if start+count > items {
 		count = items - start
@@ -763,7 +835,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	// Read all the indexes in one go
 	indices, err := t.getIndices(start, count)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	var (
 		sizes      []int               // The sizes for each element
@@ -776,13 +848,16 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		secondIndex := indices[i+1]
 		// Determine the size of the item.
 		offset1, offset2, _ := firstIndex.bounds(secondIndex)
+		if offset2 < offset1 || secondIndex.filenum < firstIndex.filenum {
+			return nil, nil, nil, fmt.Errorf("%w: item %d", errCorruptIndex, start+uint64(i))
+		}
 		size := int(offset2 - offset1)
 		// Crossing a file boundary?
 		if secondIndex.filenum != firstIndex.filenum {
 			// If we have unread data in the first file, we need to do that read now.
 			if unreadSize > 0 {
 				if err := readData(firstIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 				unreadSize = 0
 			}
@@ -793,7 +868,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 			// read this last item, but we need to do the deferred reads now.
 			if unreadSize > 0 {
 				if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 			}
 			break
@@ -805,12 +880,12 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		if i == len(indices)-2 || uint64(totalSize) > maxBytes {
 			// Last item, need to do the read now
 			if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			break
 		}
 	}
-	return output[:outputSize], sizes, nil
+	return output[:outputSize], sizes, t.coder, nil
 }
 
 // has returns an indicator whether the specified number data is still accessible
@@ -0,0 +1,178 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// AccountDiff is the change a transaction made to a single account.
+type AccountDiff struct {
+	Recreated bool     // Whether the account was overwritten, discarding its storage
+	Suicided  bool     // Whether the account self-destructed
+	Balance   *big.Int // Change of the balance, relative to the balance before the transaction
+
+	Nonce        uint64 // New nonce, if NonceChanged is set
+	NonceChanged bool
+
+	Code        []byte // New code, if CodeChanged is set
+	CodeHash    common.Hash
+	CodeChanged bool
+
+	Storage map[common.Hash]common.Hash // Storage slots written
+}
+
+// TxDiff is the set of changes a transaction made to the state. It excludes the
+// per-transaction data that does not outlive the transaction, i.e. the refund
+// counter, the access list and the transient storage.
+type TxDiff struct {
+	Addresses []common.Address // Modified accounts, in order of their first modification
+	Accounts  map[common.Address]*AccountDiff
+	Logs      []*types.Log
+	Preimages map[common.Hash][]byte
+}
+
+// TxDiff extracts the changes made by the current transaction from the journal.
+// It must be called before the transaction is finalised. Reverted changes are
+// not part of the diff. If the changes can't be represented faithfully, nil is
+// returned.
+func (s *StateDB) TxDiff() *TxDiff {
+	diff := &TxDiff{
+		Accounts:  make(map[common.Address]*AccountDiff),
+		Preimages: make(map[common.Hash][]byte),
+	}
+	account := func(addr common.Address) *AccountDiff {
+		acc, ok := diff.Accounts[addr]
+		if !ok {
+			acc = &AccountDiff{Storage: make(map[common.Hash]common.Hash)}
+			diff.Accounts[addr] = acc
+			diff.Addresses = append(diff.Addresses, addr)
+		}
+		return acc
+	}
+	// The balance before the transaction is the one recorded by the first
+	// change of it.
+	origins := make(map[common.Address]*big.Int)
+	origin := func(addr common.Address, prev *big.Int) {
+		if _, ok := origins[addr]; !ok {
+			origins[addr] = prev
+		}
+	}
+	for _, entry := range s.journal.entries {
+		switch ch := entry.(type) {
+		case createObjectChange:
+			account(*ch.account)
+			origin(*ch.account, new(big.Int))
+		case resetObjectChange:
+			account(ch.prev.address).Recreated = true
+			origin(ch.prev.address, ch.prev.data.Balance)
+		case suicideChange:
+			account(*ch.account)
+			origin(*ch.account, ch.prevbalance)
+		case balanceChange:
+			account(*ch.account)
+			origin(*ch.account, ch.prev)
+		case nonceChange:
+			account(*ch.account).NonceChanged = true
+		case codeChange:
+			account(*ch.account).CodeChanged = true
+		case storageChange:
+			// The written value is filled in from the live object below
+			account(*ch.account).Storage[ch.key] = common.Hash{}
+		case touchChange:
+			account(*ch.account)
+		case addPreimageChange:
+			diff.Preimages[ch.hash] = s.preimages[ch.hash]
+		}
+	}
+	// Every modified account must be finalised after the transaction, and vice
+	// versa. This doesn't hold for resurrections of untouched accounts, nor for
+	// the RIPEMD precompile, which are left to sequential execution.
+	if len(diff.Accounts) != len(s.journal.dirties) {
+		return nil
+	}
+	for addr := range s.journal.dirties {
+		if _, ok := diff.Accounts[addr]; !ok {
+			return nil
+		}
+	}
+	for addr, acc := range diff.Accounts {
+		obj, ok := s.stateObjects[addr]
+		if !ok {
+			return nil
+		}
+		acc.Suicided = obj.suicided
+		acc.Balance = new(big.Int)
+		if prev, ok := origins[addr]; ok {
+			acc.Balance.Sub(obj.Balance(), prev)
+		}
+		acc.Nonce = obj.Nonce()
+		if acc.CodeChanged {
+			acc.Code, acc.CodeHash = obj.code, common.BytesToHash(obj.CodeHash())
+		}
+		for key := range acc.Storage {
+			acc.Storage[key] = obj.dirtyStorage[key]
+		}
+	}
+	diff.Logs = s.logs[s.thash]
+	return diff
+}
+
+// ApplyTxDiff applies the changes made by a transaction, which was executed on
+// another StateDB, to the current transaction. The diff needs to have been
+// extracted from a StateDB in which all accounts modified by the transaction,
+// except for the balance, had the same state as in this one. Balances are
+// applied as a difference, so that transactions only increasing it commute.
+//
+// Like after executing the transaction itself, the state needs to be finalised
+// afterwards.
+func (s *StateDB) ApplyTxDiff(diff *TxDiff) {
+	for _, addr := range diff.Addresses {
+		acc := diff.Accounts[addr]
+		if acc.Recreated {
+			s.CreateAccount(addr)
+		}
+		obj := s.GetOrNewStateObject(addr)
+		obj.touch()
+
+		if acc.Balance.Sign() != 0 {
+			obj.SetBalance(new(big.Int).Add(obj.Balance(), acc.Balance))
+		}
+		if acc.NonceChanged {
+			obj.SetNonce(acc.Nonce)
+		}
+		if acc.CodeChanged {
+			obj.SetCode(acc.CodeHash, acc.Code)
+		}
+		for key, value := range acc.Storage {
+			obj.SetState(s.db, key, value)
+		}
+		if acc.Suicided {
+			s.Suicide(addr)
+		}
+	}
+	for _, log := range diff.Logs {
+		cpy := *log
+		s.AddLog(&cpy)
+	}
+	for hash, preimage := range diff.Preimages {
+		s.AddPreimage(hash, preimage)
+	}
+}
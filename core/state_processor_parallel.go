@@ -0,0 +1,346 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	parallelTxMeter       = metrics.NewRegisteredMeter("chain/parallel/txs", nil)
+	parallelConflictMeter = metrics.NewRegisteredMeter("chain/parallel/conflicts", nil)
+)
+
+// accountRead is the state of an account before a transaction first accessed
+// it.
+type accountRead struct {
+	read     bool // Whether the transaction depends on the state, as opposed to only adding to the balance
+	exist    bool
+	balance  *big.Int
+	nonce    uint64
+	codeHash common.Hash
+}
+
+// readSet is the state a transaction depended on, as it was before the
+// transaction was executed.
+type readSet struct {
+	accounts map[common.Address]*accountRead
+	slots    map[common.Address]map[common.Hash]common.Hash
+	unsafe   bool // Whether the transaction depended on state that can't be validated
+}
+
+// valid reports whether the transaction would read the same state from statedb
+// as the state it was executed on, and hence behave identically.
+func (r *readSet) valid(statedb *state.StateDB) bool {
+	if r.unsafe {
+		return false
+	}
+	for addr, acc := range r.accounts {
+		if !acc.read {
+			continue
+		}
+		if statedb.Exist(addr) != acc.exist || statedb.GetNonce(addr) != acc.nonce ||
+			statedb.GetBalance(addr).Cmp(acc.balance) != 0 || statedb.GetCodeHash(addr) != acc.codeHash {
+			return false
+		}
+	}
+	for addr, slots := range r.slots {
+		for key, value := range slots {
+			if statedb.GetState(addr, key) != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// recordingStateDB is a vm.StateDB recording the state a transaction reads.
+// Adding to the balance of an account, e.g. paying the fees to the coinbase, is
+// not a read, as the change is applied relative to the balance the account
+// has when the transaction is committed.
+type recordingStateDB struct {
+	*state.StateDB
+	reads readSet
+}
+
+func newRecordingStateDB(statedb *state.StateDB) *recordingStateDB {
+	return &recordingStateDB{
+		StateDB: statedb,
+		reads: readSet{
+			accounts: make(map[common.Address]*accountRead),
+			slots:    make(map[common.Address]map[common.Hash]common.Hash),
+		},
+	}
+}
+
+// account records the state of an account on its first access.
+func (s *recordingStateDB) account(addr common.Address, read bool) {
+	acc, ok := s.reads.accounts[addr]
+	if !ok {
+		acc = &accountRead{
+			exist:    s.StateDB.Exist(addr),
+			balance:  s.StateDB.GetBalance(addr),
+			nonce:    s.StateDB.GetNonce(addr),
+			codeHash: s.StateDB.GetCodeHash(addr),
+		}
+		s.reads.accounts[addr] = acc
+	}
+	acc.read = acc.read || read
+}
+
+// slot records the value of a storage slot on its first access.
+func (s *recordingStateDB) slot(addr common.Address, key common.Hash) {
+	slots, ok := s.reads.slots[addr]
+	if !ok {
+		slots = make(map[common.Hash]common.Hash)
+		s.reads.slots[addr] = slots
+	}
+	if _, ok := slots[key]; !ok {
+		slots[key] = s.StateDB.GetCommittedState(addr, key)
+	}
+}
+
+func (s *recordingStateDB) CreateAccount(addr common.Address) {
+	s.account(addr, true)
+	s.StateDB.CreateAccount(addr)
+}
+
+func (s *recordingStateDB) SubBalance(addr common.Address, amount *big.Int) {
+	s.account(addr, true)
+	s.StateDB.SubBalance(addr, amount)
+}
+
+func (s *recordingStateDB) AddBalance(addr common.Address, amount *big.Int) {
+	s.account(addr, false)
+	s.StateDB.AddBalance(addr, amount)
+}
+
+func (s *recordingStateDB) GetBalance(addr common.Address) *big.Int {
+	s.account(addr, true)
+	return s.StateDB.GetBalance(addr)
+}
+
+func (s *recordingStateDB) GetNonce(addr common.Address) uint64 {
+	s.account(addr, true)
+	return s.StateDB.GetNonce(addr)
+}
+
+func (s *recordingStateDB) SetNonce(addr common.Address, nonce uint64) {
+	s.account(addr, true)
+	s.StateDB.SetNonce(addr, nonce)
+}
+
+func (s *recordingStateDB) GetCodeHash(addr common.Address) common.Hash {
+	s.account(addr, true)
+	return s.StateDB.GetCodeHash(addr)
+}
+
+func (s *recordingStateDB) GetCode(addr common.Address) []byte {
+	s.account(addr, true)
+	return s.StateDB.GetCode(addr)
+}
+
+func (s *recordingStateDB) SetCode(addr common.Address, code []byte) {
+	s.account(addr, true)
+	s.StateDB.SetCode(addr, code)
+}
+
+func (s *recordingStateDB) GetCodeSize(addr common.Address) int {
+	s.account(addr, true)
+	return s.StateDB.GetCodeSize(addr)
+}
+
+func (s *recordingStateDB) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	s.slot(addr, key)
+	return s.StateDB.GetCommittedState(addr, key)
+}
+
+func (s *recordingStateDB) GetState(addr common.Address, key common.Hash) common.Hash {
+	s.slot(addr, key)
+	return s.StateDB.GetState(addr, key)
+}
+
+func (s *recordingStateDB) SetState(addr common.Address, key, value common.Hash) {
+	s.slot(addr, key)
+	s.StateDB.SetState(addr, key, value)
+}
+
+func (s *recordingStateDB) Suicide(addr common.Address) bool {
+	s.account(addr, true)
+	return s.StateDB.Suicide(addr)
+}
+
+func (s *recordingStateDB) HasSuicided(addr common.Address) bool {
+	s.account(addr, true)
+	return s.StateDB.HasSuicided(addr)
+}
+
+func (s *recordingStateDB) Exist(addr common.Address) bool {
+	s.account(addr, true)
+	return s.StateDB.Exist(addr)
+}
+
+func (s *recordingStateDB) Empty(addr common.Address) bool {
+	s.account(addr, true)
+	return s.StateDB.Empty(addr)
+}
+
+func (s *recordingStateDB) ForEachStorage(addr common.Address, cb func(key, value common.Hash) bool) error {
+	s.reads.unsafe = true
+	return s.StateDB.ForEachStorage(addr, cb)
+}
+
+// speculativeResult is the outcome of executing a transaction against the state
+// at the start of the block.
+type speculativeResult struct {
+	msg    types.Message
+	msgErr error // Error converting the transaction into a message
+
+	result *ExecutionResult
+	err    error // Error applying the message, left to sequential execution to report
+	reads  readSet
+	diff   *state.TxDiff
+
+	done chan struct{}
+}
+
+// applyParallel executes the transactions of a block with optimistic
+// concurrency control: every transaction is executed speculatively in parallel
+// against the state at the start of the block, recording the state it reads.
+// The results are then committed in block order, as long as the state read by
+// a transaction was not modified by the transactions before it. Otherwise, the
+// transaction is executed again on the up-to-date state.
+//
+// The resulting state, receipts and logs are identical to the ones produced by
+// sequential execution.
+func (p *StateProcessor) applyParallel(block *types.Block, statedb *state.StateDB, cfg vm.Config, gp *GasPool, usedGas *uint64) (types.Receipts, []*types.Log, error) {
+	var (
+		header      = block.Header()
+		blockHash   = block.Hash()
+		blockNumber = block.Number()
+		txs         = block.Transactions()
+		signer      = types.MakeSigner(p.config, header.Number, header.Time)
+		base        = statedb.Copy()
+		results     = make([]*speculativeResult, len(txs))
+		tasks       = make(chan int, len(txs))
+		interrupt   int32
+	)
+	// Speculative executions only read from the base state, they don't need to
+	// preload the tries the block's changes are committed to.
+	base.StopPrefetcher()
+
+	for i := range txs {
+		results[i] = &speculativeResult{done: make(chan struct{})}
+		tasks <- i
+	}
+	close(tasks)
+
+	workers := runtime.NumCPU()
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	var pending sync.WaitGroup
+	pending.Add(workers)
+	for n := 0; n < workers; n++ {
+		go func() {
+			defer pending.Done()
+
+			blockContext := NewEVMBlockContext(header, p.bc, nil)
+			for i := range tasks {
+				if atomic.LoadInt32(&interrupt) == 0 {
+					p.applySpeculative(results[i], txs[i], i, signer, header, blockContext, base, cfg)
+				}
+				close(results[i].done)
+			}
+		}()
+	}
+	defer func() {
+		atomic.StoreInt32(&interrupt, 1)
+		pending.Wait()
+	}()
+
+	var (
+		receipts  types.Receipts
+		allLogs   []*types.Log
+		conflicts int
+		vmenv     = vm.NewEVM(NewEVMBlockContext(header, p.bc, nil), vm.TxContext{}, statedb, p.config, cfg)
+	)
+	for i, tx := range txs {
+		res := results[i]
+		<-res.done
+
+		if res.msgErr != nil {
+			return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), res.msgErr)
+		}
+		statedb.Prepare(tx.Hash(), i)
+
+		var receipt *types.Receipt
+		if res.err == nil && res.diff != nil && res.reads.valid(statedb) && gp.SubGas(res.msg.Gas()) == nil {
+			gp.AddGas(res.msg.Gas() - res.result.UsedGas)
+			statedb.ApplyTxDiff(res.diff)
+			receipt = newReceipt(res.msg, p.config, statedb, blockNumber, blockHash, tx, usedGas, res.result)
+		} else {
+			conflicts++
+
+			var err error
+			receipt, err = applyTransaction(res.msg, p.config, nil, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+		}
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	parallelTxMeter.Mark(int64(len(txs)))
+	parallelConflictMeter.Mark(int64(conflicts))
+	log.Debug("Executed transactions in parallel", "number", blockNumber, "hash", blockHash, "txs", len(txs), "conflicts", conflicts, "workers", workers)
+
+	return receipts, allLogs, nil
+}
+
+// applySpeculative executes a transaction on a copy of the given state, which
+// must not be modified concurrently.
+func (p *StateProcessor) applySpeculative(res *speculativeResult, tx *types.Transaction, index int, signer types.Signer, header *types.Header, blockContext vm.BlockContext, base *state.StateDB, cfg vm.Config) {
+	res.msg, res.msgErr = tx.AsMessage(signer, header.BaseFee)
+	if res.msgErr != nil {
+		return
+	}
+	statedb := base.Copy()
+	statedb.Prepare(tx.Hash(), index)
+
+	recorder := newRecordingStateDB(statedb)
+	evm := vm.NewEVM(blockContext, NewEVMTxContext(res.msg), recorder, p.config, cfg)
+
+	// The gas pool of the block is only checked on commit.
+	res.result, res.err = ApplyMessage(evm, res.msg, new(GasPool).AddGas(header.GasLimit))
+	if res.err != nil {
+		return
+	}
+	res.reads = recorder.reads
+	res.diff = statedb.TxDiff()
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestParallelStateProcessor(t *testing.T) {
+	var (
+		engine   = ethash.NewFaker()
+		signer   = types.LatestSigner(params.TestChainConfig)
+		coinbase = common.HexToAddress("0xc014ba5e")
+
+		counter  = common.HexToAddress("0xc0")
+		logger   = common.HexToAddress("0x10")
+		reader   = common.HexToAddress("0xbb")
+		destruct = common.HexToAddress("0xde")
+
+		keys  []*ecdsa.PrivateKey
+		alloc = GenesisAlloc{
+			// Increments slot 0 on every call
+			counter: {Code: []byte{
+				byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE),
+			}, Balance: new(big.Int)},
+			// Logs the call value
+			logger: {Code: []byte{
+				byte(vm.CALLVALUE), byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.LOG0),
+			}, Balance: new(big.Int)},
+			// Stores the balance of the coinbase
+			reader: {Code: []byte{
+				byte(vm.COINBASE), byte(vm.BALANCE), byte(vm.PUSH1), 0, byte(vm.SSTORE),
+			}, Balance: new(big.Int)},
+			// Self-destructs to the caller
+			destruct: {Code: []byte{byte(vm.CALLER), byte(vm.SELFDESTRUCT)}, Balance: big.NewInt(1000)},
+		}
+	)
+	for i := 0; i < 8; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = GenesisAccount{Balance: big.NewInt(params.Ether)}
+	}
+	gspec := &Genesis{Config: params.TestChainConfig, Alloc: alloc}
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 3, func(i int, b *BlockGen) {
+		b.SetCoinbase(coinbase)
+
+		send := func(key *ecdsa.PrivateKey, to *common.Address, value int64, data []byte) {
+			tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+				ChainID:   params.TestChainConfig.ChainID,
+				Nonce:     b.TxNonce(crypto.PubkeyToAddress(key.PublicKey)),
+				GasTipCap: big.NewInt(params.GWei),
+				GasFeeCap: new(big.Int).Add(b.BaseFee(), big.NewInt(params.GWei)),
+				Gas:       100000,
+				To:        to,
+				Value:     big.NewInt(value),
+				Data:      data,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.AddTx(tx)
+		}
+		// Independent transfers to fresh accounts
+		for j := 0; j < 4; j++ {
+			to := common.BigToAddress(big.NewInt(int64(0x1000*(i+1) + j)))
+			send(keys[j], &to, 1, nil)
+		}
+		// Dependent transactions of a single sender
+		for j := 0; j < 3; j++ {
+			send(keys[4], &logger, int64(j), nil)
+		}
+		// Conflicting storage accesses
+		send(keys[5], &counter, 0, nil)
+		send(keys[6], &counter, 0, nil)
+
+		// Read of the coinbase, which every transaction pays
+		send(keys[7], &reader, 0, nil)
+
+		// Contract creation and destruction
+		send(keys[0], nil, 0, []byte{byte(vm.PUSH1), 0xff, byte(vm.PUSH1), 0, byte(vm.SSTORE)})
+		if i == 1 {
+			send(keys[1], &destruct, 0, nil)
+		}
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	process := func(block *types.Block, parallel bool) (common.Hash, []byte, uint64) {
+		chain.cacheConfig.ParallelExecution = parallel
+
+		statedb, err := chain.StateAt(chain.GetHeaderByHash(block.ParentHash()).Root)
+		if err != nil {
+			t.Fatalf("failed to retrieve parent state: %v", err)
+		}
+		receipts, logs, usedGas, err := chain.Processor().Process(block, statedb, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: failed to process: %v", block.NumberU64(), err)
+		}
+		if len(logs) == 0 {
+			t.Fatalf("block %d: no logs", block.NumberU64())
+		}
+		// Marshal the receipts including their logs and derived fields.
+		enc, err := json.Marshal(receipts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return statedb.IntermediateRoot(true), enc, usedGas
+	}
+	for _, block := range blocks {
+		wantRoot, wantReceipts, wantGas := process(block, false)
+		haveRoot, haveReceipts, haveGas := process(block, true)
+		if haveRoot != wantRoot {
+			t.Errorf("block %d: root mismatch: have %x, want %x", block.NumberU64(), haveRoot, wantRoot)
+		}
+		if string(haveReceipts) != string(wantReceipts) {
+			t.Errorf("block %d: receipts mismatch\nhave: %s\nwant: %s", block.NumberU64(), haveReceipts, wantReceipts)
+		}
+		if haveGas != wantGas {
+			t.Errorf("block %d: gas mismatch: have %d, want %d", block.NumberU64(), haveGas, wantGas)
+		}
+		// Import the block, which validates the results against the header.
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to insert: %v", block.NumberU64(), err)
+		}
+	}
+}
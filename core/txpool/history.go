@@ -0,0 +1,190 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// maxTxEvents is the maximum number of lifecycle events retained for a
+	// single transaction. Older events are discarded first.
+	maxTxEvents = 32
+
+	// txEventChanSize is the number of lifecycle events buffered for delivery
+	// to subscribers. Events are dropped from the notifications, but not from
+	// the history, if subscribers fall behind.
+	txEventChanSize = 4096
+)
+
+// txEventDropMeter counts the lifecycle events not delivered to subscribers.
+var txEventDropMeter = metrics.NewRegisteredMeter("txpool/history/dropped", nil)
+
+// TxEventKind is the type of a transaction lifecycle event.
+type TxEventKind string
+
+const (
+	TxEventAdded    TxEventKind = "added"    // Accepted into the pool
+	TxEventReplaced TxEventKind = "replaced" // Replaced by a transaction with the same nonce
+	TxEventPromoted TxEventKind = "promoted" // Moved from the queue to the executable set
+	TxEventDemoted  TxEventKind = "demoted"  // Moved from the executable set back to the queue
+	TxEventEvicted  TxEventKind = "evicted"  // Dropped from the pool, for the recorded reason
+	TxEventIncluded TxEventKind = "included" // Dropped as its nonce was used up by the chain
+)
+
+// Reasons for the eviction of a transaction.
+const (
+	EvictUnderpriced  = "underpriced"   // Outbid by better paying transactions, or below the minimum price
+	EvictPendingLimit = "pending-limit" // Over the executable slots of the pool
+	EvictQueueLimit   = "queue-limit"   // Over the non-executable slots of the account or the pool
+	EvictLifetime     = "lifetime"      // Queued for longer than the pool lifetime
+	EvictUnpayable    = "unpayable"     // Cost exceeding the balance, or gas above the block limit
+	EvictRemoved      = "removed"       // Removed explicitly, e.g. an expired private transaction
+)
+
+// TxEvent is an event in the lifecycle of a transaction in the pool.
+type TxEvent struct {
+	Hash       common.Hash  `json:"hash"`
+	Kind       TxEventKind  `json:"kind"`
+	Reason     string       `json:"reason,omitempty"`     // Reason of an eviction
+	ReplacedBy *common.Hash `json:"replacedBy,omitempty"` // Replacement of the transaction
+	Time       time.Time    `json:"time"`
+}
+
+// txHistory records the lifecycle events of the most recent transactions of a
+// pool, and notifies subscribers of them. A nil history records nothing.
+type txHistory struct {
+	limit  int                        // Maximum number of transactions to retain events for
+	events map[common.Hash][]*TxEvent // Recorded events per transaction
+	order  []common.Hash              // Transactions in order of their first event
+	lock   sync.RWMutex
+
+	feed   event.Feed
+	scope  event.SubscriptionScope
+	notify chan *TxEvent
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newTxHistory creates a history retaining the events of the given number of
+// transactions, or nil if the limit is zero.
+func newTxHistory(limit int) *txHistory {
+	if limit <= 0 {
+		return nil
+	}
+	h := &txHistory{
+		limit:  limit,
+		events: make(map[common.Hash][]*TxEvent),
+		notify: make(chan *TxEvent, txEventChanSize),
+		quit:   make(chan struct{}),
+	}
+	h.wg.Add(1)
+	go h.loop()
+	return h
+}
+
+// loop delivers the recorded events to the subscribers.
+func (h *txHistory) loop() {
+	defer h.wg.Done()
+
+	for {
+		select {
+		case ev := <-h.notify:
+			h.feed.Send(*ev)
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// record adds an event to the history of a transaction. It never blocks on the
+// delivery to subscribers.
+func (h *txHistory) record(hash common.Hash, kind TxEventKind, reason string, replacedBy *common.Hash) {
+	if h == nil {
+		return
+	}
+	ev := &TxEvent{
+		Hash:       hash,
+		Kind:       kind,
+		Reason:     reason,
+		ReplacedBy: replacedBy,
+		Time:       time.Now(),
+	}
+	h.lock.Lock()
+	events, ok := h.events[hash]
+	if !ok {
+		// Make room for a new transaction by forgetting the oldest ones
+		for len(h.order) >= h.limit {
+			delete(h.events, h.order[0])
+			h.order = h.order[1:]
+		}
+		h.order = append(h.order, hash)
+	}
+	if len(events) >= maxTxEvents {
+		events = events[1:]
+	}
+	h.events[hash] = append(events, ev)
+	h.lock.Unlock()
+
+	select {
+	case h.notify <- ev:
+	default:
+		txEventDropMeter.Mark(1)
+	}
+}
+
+// history returns the recorded events of a transaction, oldest first.
+func (h *txHistory) history(hash common.Hash) []TxEvent {
+	if h == nil {
+		return nil
+	}
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	events := make([]TxEvent, 0, len(h.events[hash]))
+	for _, ev := range h.events[hash] {
+		events = append(events, *ev)
+	}
+	return events
+}
+
+// subscribe registers a subscription for the recorded events. On a nil history
+// the subscription never delivers any.
+func (h *txHistory) subscribe(ch chan<- TxEvent) event.Subscription {
+	if h == nil {
+		return event.NewSubscription(func(quit <-chan struct{}) error {
+			<-quit
+			return nil
+		})
+	}
+	return h.scope.Track(h.feed.Subscribe(ch))
+}
+
+// close terminates the delivery of events to subscribers.
+func (h *txHistory) close() {
+	if h == nil {
+		return
+	}
+	h.scope.Close()
+	close(h.quit)
+	h.wg.Wait()
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the lifecycle events of transactions are recorded and delivered
+// to subscribers.
+func TestTxHistory(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	events := make(chan TxEvent, 64)
+	sub := pool.SubscribeTxEvents(events)
+	defer sub.Unsubscribe()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	var (
+		queued      = pricedTransaction(1, 100000, big.NewInt(1), key)
+		pending     = pricedTransaction(0, 100000, big.NewInt(1), key)
+		replacement = pricedTransaction(1, 100000, big.NewInt(3), key)
+		cheap       = pricedTransaction(2, 100000, big.NewInt(2), key)
+	)
+	// Add a gapped transaction, then fill the gap to promote both and replace
+	// the promoted one.
+	for _, tx := range []*types.Transaction{queued, pending, replacement, cheap} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	// Mine the first transaction, then raise the price to evict the last one.
+	testSetNonce(pool, from, 1)
+	<-pool.requestReset(nil, nil)
+	pool.SetGasPrice(big.NewInt(3))
+
+	replacementHash := replacement.Hash()
+	tests := []struct {
+		tx   *types.Transaction
+		want []TxEvent
+	}{
+		{queued, []TxEvent{
+			{Kind: TxEventAdded},
+			{Kind: TxEventPromoted},
+			{Kind: TxEventReplaced, ReplacedBy: &replacementHash},
+		}},
+		{pending, []TxEvent{
+			{Kind: TxEventAdded},
+			{Kind: TxEventPromoted},
+			{Kind: TxEventIncluded},
+		}},
+		{replacement, []TxEvent{
+			{Kind: TxEventAdded},
+		}},
+		{cheap, []TxEvent{
+			{Kind: TxEventAdded},
+			{Kind: TxEventPromoted},
+			{Kind: TxEventEvicted, Reason: EvictUnderpriced},
+		}},
+	}
+	strip := func(events []TxEvent) []TxEvent {
+		stripped := make([]TxEvent, len(events))
+		for i, ev := range events {
+			stripped[i] = TxEvent{Kind: ev.Kind, Reason: ev.Reason, ReplacedBy: ev.ReplacedBy}
+		}
+		return stripped
+	}
+	total := 0
+	for i, test := range tests {
+		history := pool.TxHistory(test.tx.Hash())
+		for _, ev := range history {
+			if ev.Hash != test.tx.Hash() {
+				t.Errorf("tx %d: event hash mismatch: have %x, want %x", i, ev.Hash, test.tx.Hash())
+			}
+		}
+		if have := strip(history); !reflect.DeepEqual(have, test.want) {
+			t.Errorf("tx %d: history mismatch:\nhave %+v\nwant %+v", i, have, test.want)
+		}
+		total += len(test.want)
+	}
+	// Ensure all events have been delivered to the subscriber too.
+	for i := 0; i < total; i++ {
+		select {
+		case <-events:
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not delivered", i)
+		}
+	}
+}
+
+// Tests that the history only retains the events of the most recent
+// transactions, and a bounded number of events for each.
+func TestTxHistoryRetention(t *testing.T) {
+	t.Parallel()
+
+	history := newTxHistory(2)
+	defer history.close()
+
+	hashes := []common.Hash{{0x01}, {0x02}, {0x03}}
+	for _, hash := range hashes {
+		history.record(hash, TxEventAdded, "", nil)
+	}
+	if events := history.history(hashes[0]); len(events) != 0 {
+		t.Errorf("oldest transaction retained: %v", events)
+	}
+	for _, hash := range hashes[1:] {
+		if events := history.history(hash); len(events) != 1 {
+			t.Errorf("tx %x: event count mismatch: have %d, want 1", hash, len(events))
+		}
+	}
+	for i := 0; i < 2*maxTxEvents; i++ {
+		history.record(hashes[2], TxEventDemoted, "", nil)
+	}
+	events := history.history(hashes[2])
+	if len(events) != maxTxEvents {
+		t.Fatalf("event count mismatch: have %d, want %d", len(events), maxTxEvents)
+	}
+	if events[0].Kind != TxEventDemoted {
+		t.Errorf("oldest event retained: %v", events[0])
+	}
+	// A disabled history records nothing.
+	var disabled *txHistory
+	disabled.record(hashes[0], TxEventAdded, "", nil)
+	if events := disabled.history(hashes[0]); len(events) != 0 {
+		t.Errorf("disabled history recorded events: %v", events)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ValidationHook is an admission policy consulted for every transaction which
+// passed the built-in validation rules of the pool. Hooks are called with the
+// pool lock held, concurrently for lookups not modifying the pool, so they
+// must be fast and safe for concurrent use.
+type ValidationHook interface {
+	// Name identifies the hook in errors and metrics.
+	Name() string
+
+	// ValidateTx returns an error if the transaction may not enter the pool.
+	ValidateTx(tx *types.Transaction, ctx *ValidationContext) error
+}
+
+// ValidationContext carries the pool's view of a transaction under validation.
+type ValidationContext struct {
+	From       common.Address // Recovered sender of the transaction
+	Local      bool           // Whether the transaction is exempt from the pricing rules
+	Reinjected bool           // Whether the transaction re-enters the pool after a restart or reorg
+	State      *state.StateDB // State of the current head, must not be modified
+}
+
+// PolicyError is returned if a validation hook rejects a transaction.
+type PolicyError struct {
+	Hook string // Name of the rejecting hook
+	Err  error  // Reason of the rejection
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%v (%s): %v", ErrRejectedByPolicy, e.Hook, e.Err)
+}
+
+func (e *PolicyError) Unwrap() error { return e.Err }
+
+// Is makes the error match ErrRejectedByPolicy regardless of the reason.
+func (e *PolicyError) Is(target error) bool { return target == ErrRejectedByPolicy }
+
+// ErrorCode returns the JSON-RPC error code of a rejected transaction, as
+// defined by EIP-1474.
+func (e *PolicyError) ErrorCode() int { return -32003 }
+
+// ErrorData returns the rejecting hook and reason to JSON-RPC callers.
+func (e *PolicyError) ErrorData() interface{} {
+	return map[string]string{"hook": e.Hook, "reason": e.Err.Error()}
+}
+
+// PolicyConfig are the settings of the built-in validation hooks of the pool.
+type PolicyConfig struct {
+	AllowSenders []common.Address // Only accept transactions from these senders, if any
+	DenySenders  []common.Address // Reject transactions from these senders
+	DenyCalls    []string         // Reject calls to contracts, as "address" or "address:selector"
+	SenderRate   uint64           // Maximum number of remote transactions accepted per sender per minute (0 = unlimited)
+	MinFeeCap    uint64           // Minimum fee cap to enforce for remote transactions (0 = disabled)
+}
+
+// hooks creates the built-in validation hooks enabled by the configuration. The
+// call rules are expected to be sanitized already.
+func (config *PolicyConfig) hooks() []ValidationHook {
+	var hooks []ValidationHook
+	if len(config.AllowSenders) > 0 || len(config.DenySenders) > 0 {
+		hooks = append(hooks, NewSenderFilter(config.AllowSenders, config.DenySenders))
+	}
+	if len(config.DenyCalls) > 0 {
+		filter, _ := NewCallFilter(config.DenyCalls)
+		hooks = append(hooks, filter)
+	}
+	if config.MinFeeCap > 0 {
+		hooks = append(hooks, NewFeeFilter(config.MinFeeCap))
+	}
+	if config.SenderRate > 0 {
+		hooks = append(hooks, NewRateLimiter(int(config.SenderRate), time.Minute))
+	}
+	return hooks
+}
+
+// meteredHook is a validation hook along with the meter of its rejections.
+type meteredHook struct {
+	hook     ValidationHook
+	rejected metrics.Meter
+}
+
+func newMeteredHooks(hooks []ValidationHook) []*meteredHook {
+	metered := make([]*meteredHook, len(hooks))
+	for i, hook := range hooks {
+		metered[i] = &meteredHook{
+			hook:     hook,
+			rejected: metrics.GetOrRegisterMeter("txpool/policy/"+hook.Name()+"/rejected", nil),
+		}
+	}
+	return metered
+}
+
+// SenderFilter is a validation hook accepting transactions based on their sender.
+type SenderFilter struct {
+	allow map[common.Address]struct{}
+	deny  map[common.Address]struct{}
+}
+
+// NewSenderFilter creates a hook rejecting the transactions of the denied
+// senders, and if any senders are allowed, of all others.
+func NewSenderFilter(allow, deny []common.Address) *SenderFilter {
+	filter := &SenderFilter{
+		allow: make(map[common.Address]struct{}),
+		deny:  make(map[common.Address]struct{}),
+	}
+	for _, addr := range allow {
+		filter.allow[addr] = struct{}{}
+	}
+	for _, addr := range deny {
+		filter.deny[addr] = struct{}{}
+	}
+	return filter
+}
+
+func (f *SenderFilter) Name() string { return "sender" }
+
+func (f *SenderFilter) ValidateTx(tx *types.Transaction, ctx *ValidationContext) error {
+	if _, ok := f.deny[ctx.From]; ok {
+		return fmt.Errorf("sender %v denied", ctx.From)
+	}
+	if _, ok := f.allow[ctx.From]; len(f.allow) > 0 && !ok {
+		return fmt.Errorf("sender %v not allowed", ctx.From)
+	}
+	return nil
+}
+
+// CallFilter is a validation hook rejecting calls to specific contracts, or to
+// specific methods of them.
+type CallFilter struct {
+	rules map[common.Address][][]byte // Denied selectors per contract, nil for all calls
+}
+
+// parseCallRule parses a call rule in the form "address" or "address:selector".
+func parseCallRule(rule string) (common.Address, []byte, error) {
+	addr, selector := strings.TrimSpace(rule), ""
+	if i := strings.Index(addr, ":"); i >= 0 {
+		addr, selector = addr[:i], addr[i+1:]
+	}
+	if !common.IsHexAddress(addr) {
+		return common.Address{}, nil, fmt.Errorf("invalid address in call rule %q", rule)
+	}
+	if selector == "" {
+		return common.HexToAddress(addr), nil, nil
+	}
+	sel, err := hexutil.Decode(selector)
+	if err != nil || len(sel) != 4 {
+		return common.Address{}, nil, fmt.Errorf("invalid selector in call rule %q", rule)
+	}
+	return common.HexToAddress(addr), sel, nil
+}
+
+// NewCallFilter creates a hook rejecting the calls matching any of the given
+// rules. A rule in the form "address" denies all calls to the contract, while
+// "address:selector" denies the calls of a single method.
+func NewCallFilter(rules []string) (*CallFilter, error) {
+	filter := &CallFilter{rules: make(map[common.Address][][]byte)}
+	for _, rule := range rules {
+		addr, selector, err := parseCallRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		selectors, known := filter.rules[addr]
+		switch {
+		case known && selectors == nil:
+			// All calls denied already
+		case selector == nil:
+			filter.rules[addr] = nil
+		default:
+			filter.rules[addr] = append(selectors, selector)
+		}
+	}
+	return filter, nil
+}
+
+func (f *CallFilter) Name() string { return "call" }
+
+func (f *CallFilter) ValidateTx(tx *types.Transaction, ctx *ValidationContext) error {
+	if tx.To() == nil {
+		return nil
+	}
+	selectors, ok := f.rules[*tx.To()]
+	if !ok {
+		return nil
+	}
+	if selectors == nil {
+		return fmt.Errorf("calls to %v denied", *tx.To())
+	}
+	data := tx.Data()
+	if len(data) < 4 {
+		return nil
+	}
+	for _, selector := range selectors {
+		if bytes.Equal(data[:4], selector) {
+			return fmt.Errorf("method %x of %v denied", selector, *tx.To())
+		}
+	}
+	return nil
+}
+
+// FeeFilter is a validation hook rejecting remote transactions whose fee cap
+// is below a minimum, on top of the tip enforced by the pool's price limit.
+type FeeFilter struct {
+	minFeeCap uint64
+}
+
+// NewFeeFilter creates a hook enforcing the given minimum fee cap.
+func NewFeeFilter(minFeeCap uint64) *FeeFilter {
+	return &FeeFilter{minFeeCap: minFeeCap}
+}
+
+func (f *FeeFilter) Name() string { return "fee" }
+
+func (f *FeeFilter) ValidateTx(tx *types.Transaction, ctx *ValidationContext) error {
+	if !ctx.Local && tx.GasFeeCap().Cmp(new(big.Int).SetUint64(f.minFeeCap)) < 0 {
+		return fmt.Errorf("fee cap %v below minimum %d", tx.GasFeeCap(), f.minFeeCap)
+	}
+	return nil
+}
+
+// RateLimiter is a validation hook limiting the number of remote transactions
+// accepted per sender within a sliding time window. A transaction is counted
+// once, no matter how often it's validated. Transactions re-entering the pool,
+// reloaded from the journal or re-added after a reorg, were already admitted
+// before and are not counted at all.
+type RateLimiter struct {
+	limit    int
+	interval time.Duration
+
+	seen  map[common.Address][]rateEntry // Recently validated transactions per sender
+	swept time.Time                      // Last time the expired entries of all senders were dropped
+	lock  sync.Mutex
+}
+
+// rateEntry is a transaction counted against the limit of its sender.
+type rateEntry struct {
+	hash common.Hash
+	time time.Time
+}
+
+// NewRateLimiter creates a hook accepting at most limit remote transactions
+// per sender within the given interval.
+func NewRateLimiter(limit int, interval time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:    limit,
+		interval: interval,
+		seen:     make(map[common.Address][]rateEntry),
+		swept:    time.Now(),
+	}
+}
+
+func (l *RateLimiter) Name() string { return "rate" }
+
+func (l *RateLimiter) ValidateTx(tx *types.Transaction, ctx *ValidationContext) error {
+	if ctx.Local || ctx.Reinjected {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.swept) > l.interval {
+		for addr, entries := range l.seen {
+			if entries = l.expire(entries, now); len(entries) == 0 {
+				delete(l.seen, addr)
+			} else {
+				l.seen[addr] = entries
+			}
+		}
+		l.swept = now
+	}
+	entries := l.expire(l.seen[ctx.From], now)
+	for _, entry := range entries {
+		if entry.hash == tx.Hash() {
+			l.seen[ctx.From] = entries
+			return nil
+		}
+	}
+	if len(entries) >= l.limit {
+		l.seen[ctx.From] = entries
+		return fmt.Errorf("sender %v exceeded %d transactions per %v", ctx.From, l.limit, l.interval)
+	}
+	l.seen[ctx.From] = append(entries, rateEntry{hash: tx.Hash(), time: now})
+	return nil
+}
+
+// expire drops the entries older than the rate interval.
+func (l *RateLimiter) expire(entries []rateEntry, now time.Time) []rateEntry {
+	for len(entries) > 0 && now.Sub(entries[0].time) > l.interval {
+		entries = entries[1:]
+	}
+	return entries
+}
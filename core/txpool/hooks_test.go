@@ -0,0 +1,202 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// balanceHook is a custom validation hook requiring senders to hold a minimum
+// balance, as an example of a policy relying on the head state.
+type balanceHook struct {
+	min *big.Int
+}
+
+func (h *balanceHook) Name() string { return "balance" }
+
+func (h *balanceHook) ValidateTx(tx *types.Transaction, ctx *ValidationContext) error {
+	if ctx.State.GetBalance(ctx.From).Cmp(h.min) < 0 {
+		return errors.New("balance too low")
+	}
+	return nil
+}
+
+// Tests that the built-in and custom validation hooks reject transactions, and
+// report which hook did.
+func TestValidationHooks(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _    = crypto.GenerateKey()
+		caller, _ = crypto.GenerateKey()
+		denied, _ = crypto.GenerateKey()
+		poor, _   = crypto.GenerateKey()
+		target    = common.HexToAddress("0xc0ffee")
+	)
+	call := func(nonce uint64, to common.Address, data []byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(0), 100000, big.NewInt(1), data), types.HomesteadSigner{}, caller)
+		return tx
+	}
+	config := testTxPoolConfig
+	config.Policy = PolicyConfig{
+		DenySenders: []common.Address{crypto.PubkeyToAddress(denied.PublicKey)},
+		DenyCalls:   []string{target.Hex() + ":0xa9059cbb", "invalid"},
+		SenderRate:  3,
+		MinFeeCap:   2,
+	}
+	config.Hooks = []ValidationHook{&balanceHook{min: big.NewInt(1000000)}}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{10000000, statedb, new(event.Feed)}
+	pool := NewLegacyPool(config, params.TestChainConfig, blockchain)
+	<-pool.initDoneCh
+	defer pool.Stop()
+
+	for _, k := range []*ecdsa.PrivateKey{key, caller, denied} {
+		testAddBalance(pool, crypto.PubkeyToAddress(k.PublicKey), big.NewInt(1000000000))
+	}
+	testAddBalance(pool, crypto.PubkeyToAddress(poor.PublicKey), big.NewInt(500000))
+
+	tests := []struct {
+		tx    *types.Transaction
+		local bool
+		hook  string // Rejecting hook, empty if accepted
+	}{
+		{pricedTransaction(0, 100000, big.NewInt(2), denied), true, "sender"},
+		{call(0, target, common.FromHex("0xa9059cbb00")), true, "call"},
+		{call(0, target, common.FromHex("0x095ea7b300")), true, ""},
+		{pricedTransaction(1, 100000, big.NewInt(1), key), false, "fee"},
+		{pricedTransaction(1, 100000, big.NewInt(2), poor), false, "balance"},
+		{pricedTransaction(1, 100000, big.NewInt(2), key), false, ""},
+		{pricedTransaction(2, 100000, big.NewInt(2), key), false, ""},
+		{pricedTransaction(3, 100000, big.NewInt(2), key), false, ""},
+		{pricedTransaction(4, 100000, big.NewInt(2), key), false, "rate"},
+	}
+	for i, test := range tests {
+		var err error
+		if test.local {
+			err = pool.AddLocal(test.tx)
+		} else {
+			err = pool.addRemoteSync(test.tx)
+		}
+		if test.hook == "" {
+			if err != nil {
+				t.Errorf("test %d: transaction rejected: %v", i, err)
+			}
+			continue
+		}
+		var perr *PolicyError
+		if !errors.As(err, &perr) || !errors.Is(err, ErrRejectedByPolicy) {
+			t.Errorf("test %d: error mismatch: have %v, want policy error", i, err)
+			continue
+		}
+		if perr.Hook != test.hook {
+			t.Errorf("test %d: rejecting hook mismatch: have %s, want %s", i, perr.Hook, test.hook)
+		}
+		if code := perr.ErrorCode(); code != -32003 {
+			t.Errorf("test %d: error code mismatch: have %d, want %d", i, code, -32003)
+		}
+	}
+	// Transactions counted against the rate limit already may be revalidated.
+	if err := pool.Validate(tests[len(tests)-2].tx, false); err != nil {
+		t.Errorf("revalidation rejected: %v", err)
+	}
+}
+
+// Tests that the rate limiter admits new transactions once the older ones left
+// the window.
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		limiter = NewRateLimiter(1, 50*time.Millisecond)
+		key, _  = crypto.GenerateKey()
+		ctx     = &ValidationContext{From: crypto.PubkeyToAddress(key.PublicKey)}
+	)
+	if err := limiter.ValidateTx(transaction(0, 100000, key), ctx); err != nil {
+		t.Fatalf("first transaction rejected: %v", err)
+	}
+	if err := limiter.ValidateTx(transaction(1, 100000, key), ctx); err == nil {
+		t.Fatalf("transaction over the limit accepted")
+	}
+	if err := limiter.ValidateTx(transaction(1, 100000, key), &ValidationContext{From: ctx.From, Local: true}); err != nil {
+		t.Fatalf("local transaction rejected: %v", err)
+	}
+	if err := limiter.ValidateTx(transaction(1, 100000, key), &ValidationContext{From: ctx.From, Reinjected: true}); err != nil {
+		t.Fatalf("reinjected transaction rejected: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := limiter.ValidateTx(transaction(1, 100000, key), ctx); err != nil {
+		t.Fatalf("transaction after the window rejected: %v", err)
+	}
+}
+
+// Tests that transactions reloaded from the full journal are not counted against
+// the rate limit, which would otherwise drop them on every restart.
+func TestRateLimiterJournalReload(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+	config.FullJournal = filepath.Join(t.TempDir(), "pool.rlp")
+	config.FullJournalAge = time.Hour
+
+	pool := NewLegacyPool(config, params.TestChainConfig, blockchain)
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	txs := []*types.Transaction{transaction(0, 100000, key), transaction(1, 100000, key), transaction(2, 100000, key)}
+	for i, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	pool.Stop()
+
+	// Restart with a rate limit below the number of journaled transactions.
+	config.Policy.SenderRate = 1
+	pool = NewLegacyPool(config, params.TestChainConfig, &testBlockChain{1000000, statedb, new(event.Feed)})
+	defer pool.Stop()
+
+	if pending, queued := pool.Stats(); pending != len(txs) || queued != 0 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/0", pending, queued, len(txs))
+	}
+	// Fresh submissions are still limited.
+	if err := pool.AddRemotesSync([]*types.Transaction{transaction(3, 100000, key)})[0]; err != nil {
+		t.Fatalf("fresh transaction rejected: %v", err)
+	}
+	if err := pool.AddRemotesSync([]*types.Transaction{transaction(4, 100000, key)})[0]; !errors.Is(err, ErrRejectedByPolicy) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrRejectedByPolicy)
+	}
+}
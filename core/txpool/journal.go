@@ -21,6 +21,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -179,3 +180,106 @@ func (journal *journal) close() error {
 	}
 	return err
 }
+
+// fullJournalEntry is a transaction stored in the full pool journal, along with
+// the time it was first seen.
+type fullJournalEntry struct {
+	Time uint64 // Unix time the transaction was first seen
+	Tx   *types.Transaction
+}
+
+// fullJournal is a snapshot of the entire transaction pool, remote and queued
+// transactions included, with the aim of serving a populated pool right after
+// node restarts. Contrary to the local journal, it is not appended to, but
+// regenerated periodically and on shutdown.
+type fullJournal struct {
+	path string // Filesystem path to store the transactions at
+}
+
+// newFullJournal creates a new full pool journal stored at the given path.
+func newFullJournal(path string) *fullJournal {
+	return &fullJournal{
+		path: path,
+	}
+}
+
+// load parses a full pool journal dump from disk, and loads the transactions
+// first seen within the given age into the pool, which revalidates them.
+func (journal *fullJournal) load(maxAge time.Duration, add func([]*types.Transaction) []error) error {
+	input, err := os.Open(journal.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Skip the parsing if the journal file doesn't exist at all
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	var (
+		stream = rlp.NewStream(input, 0)
+		cutoff = time.Now().Add(-maxAge)
+
+		total, stale, dropped int
+		failure               error
+		batch                 types.Transactions
+	)
+	loadBatch := func(txs types.Transactions) {
+		for _, err := range add(txs) {
+			if err != nil && !errors.Is(err, ErrAlreadyKnown) {
+				log.Debug("Failed to add journaled transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	for {
+		var entry fullJournalEntry
+		if err = stream.Decode(&entry); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			if batch.Len() > 0 {
+				loadBatch(batch)
+			}
+			break
+		}
+		total++
+
+		seen := time.Unix(int64(entry.Time), 0)
+		if seen.Before(cutoff) {
+			stale++
+			continue
+		}
+		entry.Tx.SetTime(seen)
+		if batch = append(batch, entry.Tx); batch.Len() > 1024 {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	log.Info("Loaded transaction pool journal", "transactions", total, "stale", stale, "dropped", dropped)
+
+	return failure
+}
+
+// write regenerates the full pool journal with the given transactions.
+func (journal *fullJournal) write(txs []*types.Transaction) error {
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		entry := &fullJournalEntry{Time: uint64(tx.Time().Unix()), Tx: tx}
+		if err = rlp.Encode(replacement, entry); err != nil {
+			replacement.Close()
+			return err
+		}
+	}
+	if err = replacement.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(journal.path+".new", journal.path); err != nil {
+		return err
+	}
+	log.Info("Regenerated transaction pool journal", "transactions", len(txs))
+	return nil
+}
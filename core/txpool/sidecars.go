@@ -0,0 +1,92 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// blobsGauge tracks the number of data blobs held by the sidecar store.
+var blobsGauge = metrics.NewRegisteredGauge("txpool/blobs", nil)
+
+// sidecarStore keeps the blob sidecars of the pooled blob transactions. The
+// transactions themselves are tracked by the pool without their sidecars, so
+// that sorting, pricing and block building never copy the bulky blob data
+// around; the sidecars are only reattached when a transaction is handed out
+// for network propagation.
+type sidecarStore struct {
+	sidecars map[common.Hash]*types.BlobTxSidecar
+	blobs    int
+	lock     sync.RWMutex
+}
+
+// newSidecarStore creates an empty blob sidecar store.
+func newSidecarStore() *sidecarStore {
+	return &sidecarStore{
+		sidecars: make(map[common.Hash]*types.BlobTxSidecar),
+	}
+}
+
+// put stores the sidecar of the transaction with the given hash.
+func (s *sidecarStore) put(hash common.Hash, sidecar *types.BlobTxSidecar) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if old, ok := s.sidecars[hash]; ok {
+		s.blobs -= len(old.Blobs)
+	}
+	s.sidecars[hash] = sidecar
+	s.blobs += len(sidecar.Blobs)
+	blobsGauge.Update(int64(s.blobs))
+}
+
+// get retrieves the sidecar of the transaction with the given hash, or nil if
+// the store does not hold one.
+func (s *sidecarStore) get(hash common.Hash) *types.BlobTxSidecar {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.sidecars[hash]
+}
+
+// remove drops the sidecar of the transaction with the given hash, returning
+// the number of blobs released.
+func (s *sidecarStore) remove(hash common.Hash) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sidecar, ok := s.sidecars[hash]
+	if !ok {
+		return 0
+	}
+	delete(s.sidecars, hash)
+	s.blobs -= len(sidecar.Blobs)
+	blobsGauge.Update(int64(s.blobs))
+	return len(sidecar.Blobs)
+}
+
+// count returns the number of blobs held in the store.
+func (s *sidecarStore) count() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.blobs
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	emptyBlob          = kzg4844.Blob{}
+	emptyBlobCommit, _ = kzg4844.BlobToCommitment(emptyBlob)
+	emptyBlobProof, _  = kzg4844.ComputeBlobProof(emptyBlob, emptyBlobCommit)
+)
+
+// cancunConfig returns a chain config with all forks up to Cancun enabled at
+// genesis.
+func cancunConfig() *params.ChainConfig {
+	cpy := *eip1559Config
+	cpy.ShanghaiTime = new(uint64)
+	cpy.CancunTime = new(uint64)
+	return &cpy
+}
+
+// blobTx creates a signed blob transaction carrying a single empty blob.
+func blobTx(nonce uint64, key *ecdsa.PrivateKey, withSidecar bool) *types.Transaction {
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{emptyBlob},
+		Commitments: []kzg4844.Commitment{emptyBlobCommit},
+		Proofs:      []kzg4844.Proof{emptyBlobProof},
+	}
+	inner := &types.BlobTx{
+		ChainID:    params.TestChainConfig.ChainID,
+		Nonce:      nonce,
+		GasTipCap:  big.NewInt(1),
+		GasFeeCap:  big.NewInt(1),
+		Gas:        100000,
+		To:         common.Address{},
+		Value:      big.NewInt(100),
+		BlobFeeCap: big.NewInt(1),
+		BlobHashes: sidecar.BlobHashes(),
+	}
+	if withSidecar {
+		inner.Sidecar = sidecar
+	}
+	return types.MustSignNewTx(key, types.NewCancunSigner(params.TestChainConfig.ChainID), inner)
+}
+
+// Tests that blob transactions are only accepted after Cancun.
+func TestBlobTxBeforeCancun(t *testing.T) {
+	t.Parallel()
+
+	// Schedule Cancun in the far future so the pool's signer already knows the
+	// blob transaction type, but the fork itself is not yet active.
+	config := cancunConfig()
+	future := uint64(1 << 62)
+	config.CancunTime = &future
+
+	pool, key := setupPoolWithConfig(config)
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	if err := pool.AddRemote(blobTx(0, key, true)); !errors.Is(err, core.ErrTxTypeNotSupported) {
+		t.Fatalf("blob transaction error mismatch: have %v, want %v", err, core.ErrTxTypeNotSupported)
+	}
+}
+
+// Tests that blob transactions need a valid sidecar to enter the pool, that the
+// sidecar is kept aside and reattached on retrieval, and that it is dropped
+// together with the transaction.
+func TestBlobTxSidecars(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPoolWithConfig(cancunConfig())
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	// A blob transaction without its sidecar must be rejected
+	if err := pool.AddRemote(blobTx(0, key, false)); !errors.Is(err, ErrMissingBlobSidecar) {
+		t.Fatalf("sidecar-less transaction error mismatch: have %v, want %v", err, ErrMissingBlobSidecar)
+	}
+	// A sidecar that doesn't match the versioned hashes must be rejected
+	tx := blobTx(0, key, true)
+	bad := tx.WithBlobTxSidecar(&types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{0x01}},
+		Commitments: []kzg4844.Commitment{emptyBlobCommit},
+		Proofs:      []kzg4844.Proof{emptyBlobProof},
+	})
+	if err := pool.AddRemote(bad); err == nil {
+		t.Fatalf("transaction with invalid sidecar accepted")
+	}
+	// A well formed blob transaction is accepted and stored stripped
+	if err := pool.addRemoteSync(tx); err != nil {
+		t.Fatalf("failed to add blob transaction: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	for _, txs := range pool.Pending(false) {
+		for _, ptx := range txs {
+			if ptx.BlobTxSidecar() != nil {
+				t.Fatalf("pending transaction carries its sidecar")
+			}
+		}
+	}
+	if have := pool.Get(tx.Hash()); have == nil || have.BlobTxSidecar() == nil {
+		t.Fatalf("retrieved transaction misses its sidecar")
+	}
+	if have := pool.all.Blobs(); have != 1 {
+		t.Fatalf("tracked blob count mismatch: have %d, want %d", have, 1)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Dropping the transaction drops the sidecar too
+	pool.mu.Lock()
+	pool.removeTx(tx.Hash(), true)
+	pool.mu.Unlock()
+
+	if pool.Get(tx.Hash()) != nil {
+		t.Fatalf("removed transaction still retrievable")
+	}
+	if have := pool.all.Blobs(); have != 0 {
+		t.Fatalf("tracked blob count mismatch: have %d, want %d", have, 0)
+	}
+}
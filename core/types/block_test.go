@@ -18,6 +18,7 @@ package types
 
 import (
 	"bytes"
+	"encoding/json"
 	"hash"
 	"math/big"
 	"reflect"
@@ -342,3 +343,66 @@ func TestRlpDecodeParentHash(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockWithdrawalsEncoding(t *testing.T) {
+	header := &Header{
+		Difficulty: big.NewInt(0),
+		Number:     big.NewInt(1),
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+	}
+	withdrawals := []*Withdrawal{
+		{Index: 0, Validator: 1, Address: common.Address{0xaa}, Amount: 32},
+		{Index: 1, Validator: 2, Address: common.Address{0xbb}, Amount: math.MaxUint64},
+	}
+	block := NewBlockWithWithdrawals(header, nil, nil, nil, withdrawals, newHasher())
+	if block.Header().WithdrawalsHash == nil {
+		t.Fatal("missing withdrawals hash")
+	}
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	var dec Block
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if dec.Hash() != block.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", dec.Hash(), block.Hash())
+	}
+	if !reflect.DeepEqual(dec.Withdrawals(), block.Withdrawals()) {
+		t.Errorf("withdrawals mismatch: have %v, want %v", dec.Withdrawals(), block.Withdrawals())
+	}
+	if dec.Header().EmptyBody() {
+		t.Error("block with withdrawals reported empty body")
+	}
+	// An empty withdrawal list must still commit to a root, unlike a nil one
+	empty := NewBlockWithWithdrawals(header, nil, nil, nil, []*Withdrawal{}, newHasher())
+	if h := empty.Header().WithdrawalsHash; h == nil || *h != EmptyRootHash {
+		t.Errorf("empty withdrawals hash mismatch: have %v, want %x", h, EmptyRootHash)
+	}
+	if !empty.Header().EmptyBody() {
+		t.Error("block with empty withdrawals reported non-empty body")
+	}
+	if legacy := NewBlockWithWithdrawals(header, nil, nil, nil, nil, newHasher()); legacy.Header().WithdrawalsHash != nil {
+		t.Error("block without withdrawals has withdrawals hash")
+	}
+}
+
+func TestWithdrawalJSON(t *testing.T) {
+	w := &Withdrawal{Index: 3, Validator: 7, Address: common.Address{0xaa}, Amount: 1000}
+	enc, err := json.Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"index":"0x3","validatorIndex":"0x7","address":"0xaa00000000000000000000000000000000000000","amount":"0x3e8"}`
+	if string(enc) != want {
+		t.Fatalf("encoding mismatch: have %s, want %s", enc, want)
+	}
+	var dec Withdrawal
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&dec, w) {
+		t.Fatalf("decoding mismatch: have %v, want %v", dec, w)
+	}
+}
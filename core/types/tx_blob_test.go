@@ -0,0 +1,164 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	emptyBlob          = kzg4844.Blob{}
+	emptyBlobCommit, _ = kzg4844.BlobToCommitment(emptyBlob)
+	emptyBlobProof, _  = kzg4844.ComputeBlobProof(emptyBlob, emptyBlobCommit)
+)
+
+func createEmptyBlobTx(t *testing.T, withSidecar bool) *Transaction {
+	sidecar := &BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{emptyBlob},
+		Commitments: []kzg4844.Commitment{emptyBlobCommit},
+		Proofs:      []kzg4844.Proof{emptyBlobProof},
+	}
+	blobtx := &BlobTx{
+		ChainID:    big.NewInt(1),
+		Nonce:      5,
+		GasTipCap:  big.NewInt(22),
+		GasFeeCap:  big.NewInt(5),
+		Gas:        25000,
+		To:         common.Address{0x03, 0x04, 0x05},
+		Value:      big.NewInt(99),
+		Data:       make([]byte, 50),
+		BlobFeeCap: big.NewInt(15),
+		BlobHashes: sidecar.BlobHashes(),
+	}
+	if withSidecar {
+		blobtx.Sidecar = sidecar
+	}
+	key, _ := crypto.GenerateKey()
+	tx, err := SignNewTx(key, NewCancunSigner(blobtx.ChainID), blobtx)
+	if err != nil {
+		t.Fatalf("failed to sign blob transaction: %v", err)
+	}
+	return tx
+}
+
+func TestBlobTxEncoding(t *testing.T) {
+	tx := createEmptyBlobTx(t, true)
+
+	if tx.Type() != BlobTxType {
+		t.Fatalf("wrong type: have %d, want %d", tx.Type(), BlobTxType)
+	}
+	if have, want := tx.BlobGas(), uint64(params.BlobTxBlobGasPerBlob); have != want {
+		t.Errorf("wrong blob gas: have %d, want %d", have, want)
+	}
+	if err := tx.BlobTxSidecar().ValidateBlobs(tx.BlobHashes()); err != nil {
+		t.Fatalf("sidecar failed validation: %v", err)
+	}
+	// The network encoding carries the sidecar, the consensus one must not.
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	stripped := tx.WithoutBlobTxSidecar()
+	strippedEnc, err := stripped.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode stripped transaction: %v", err)
+	}
+	if len(strippedEnc) >= len(enc) {
+		t.Fatalf("stripped encoding not smaller: %d >= %d", len(strippedEnc), len(enc))
+	}
+	if tx.Hash() != stripped.Hash() {
+		t.Fatalf("sidecar changed the hash: %x != %x", tx.Hash(), stripped.Hash())
+	}
+	// Both encodings must decode back into the same transaction.
+	var dec, strippedDec Transaction
+	if err := dec.UnmarshalBinary(enc); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if err := strippedDec.UnmarshalBinary(strippedEnc); err != nil {
+		t.Fatalf("failed to decode stripped transaction: %v", err)
+	}
+	if dec.Hash() != tx.Hash() || strippedDec.Hash() != tx.Hash() {
+		t.Fatalf("hash mismatch after decoding")
+	}
+	if dec.BlobTxSidecar() == nil {
+		t.Fatalf("sidecar lost during decoding")
+	}
+	if strippedDec.BlobTxSidecar() != nil {
+		t.Fatalf("sidecar appeared during decoding")
+	}
+	// The sender must be recoverable with the Cancun signer, but not with older ones.
+	signer := NewCancunSigner(big.NewInt(1))
+	want, err := Sender(signer, tx)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if have, err := Sender(signer, &strippedDec); err != nil || have != want {
+		t.Fatalf("sender mismatch: have %x, want %x, err %v", have, want, err)
+	}
+	if _, err := Sender(NewLondonSigner(big.NewInt(1)), &strippedDec); err != ErrTxTypeNotSupported {
+		t.Fatalf("london signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+func TestBlobTxJSON(t *testing.T) {
+	tx := createEmptyBlobTx(t, false)
+
+	blob, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("failed to marshal transaction: %v", err)
+	}
+	var dec Transaction
+	if err := json.Unmarshal(blob, &dec); err != nil {
+		t.Fatalf("failed to unmarshal transaction: %v", err)
+	}
+	if dec.Hash() != tx.Hash() {
+		t.Fatalf("hash mismatch: have %x, want %x", dec.Hash(), tx.Hash())
+	}
+	if dec.BlobGasFeeCap().Cmp(tx.BlobGasFeeCap()) != 0 {
+		t.Fatalf("blob fee cap mismatch: have %v, want %v", dec.BlobGasFeeCap(), tx.BlobGasFeeCap())
+	}
+}
+
+func TestBlobTxSidecarValidation(t *testing.T) {
+	tx := createEmptyBlobTx(t, true)
+	sidecar := tx.BlobTxSidecar()
+
+	// Wrong number of hashes
+	if err := sidecar.ValidateBlobs(nil); err == nil {
+		t.Errorf("missing hashes accepted")
+	}
+	// Mismatching versioned hash
+	if err := sidecar.ValidateBlobs([]common.Hash{{0x01}}); err == nil {
+		t.Errorf("mismatching hash accepted")
+	}
+	// Bad proof
+	bad := &BlobTxSidecar{
+		Blobs:       sidecar.Blobs,
+		Commitments: sidecar.Commitments,
+		Proofs:      []kzg4844.Proof{{0x01}},
+	}
+	if err := bad.ValidateBlobs(tx.BlobHashes()); err == nil {
+		t.Errorf("invalid proof accepted")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+const (
+	// analysisCacheLimit is the maximum total size in bytes of the analysed
+	// contract codes retained across EVM instances.
+	analysisCacheLimit = 64 * 1024 * 1024
+
+	// seenCodeEntries is the number of code hashes remembered as executed once,
+	// which makes them eligible for decoding the next time they are run.
+	seenCodeEntries = 16384
+)
+
+var (
+	analyses = newAnalysisCache(analysisCacheLimit)
+
+	analysisHitMeter  = metrics.NewRegisteredMeter("evm/analysis/hit", nil)
+	analysisMissMeter = metrics.NewRegisteredMeter("evm/analysis/miss", nil)
+	analysisSizeGauge = metrics.NewRegisteredGauge("evm/analysis/size", nil)
+)
+
+// codeAnalysis is the cached result of analysing a piece of contract code. The
+// JUMPDEST bitmap is independent of the fork, whereas the decoded program is
+// only valid for the instruction set it was built with.
+//
+// Cached analyses are shared between goroutines and must not be modified after
+// they were added to the cache.
+type codeAnalysis struct {
+	bitmap  bitvec
+	jt      *JumpTable
+	program *program
+}
+
+// size returns the approximate memory used by the analysis.
+func (a *codeAnalysis) size() uint64 {
+	size := uint64(len(a.bitmap))
+	if a.program != nil {
+		size += a.program.size
+	}
+	return size
+}
+
+// analysisCache is an LRU cache of code analyses keyed by code hash, limited by
+// the total size of the analyses instead of their number.
+type analysisCache struct {
+	entries *simplelru.LRU // Code hash -> *codeAnalysis
+	seen    *simplelru.LRU // Hashes of code executed once, but not decoded yet
+	size    uint64         // Total size of the cached analyses
+	limit   uint64         // Maximum total size of the cached analyses
+	lock    sync.Mutex
+}
+
+// newAnalysisCache creates an analysis cache retaining up to limit bytes.
+func newAnalysisCache(limit uint64) *analysisCache {
+	entries, _ := simplelru.NewLRU(math.MaxInt32, nil)
+	seen, _ := simplelru.NewLRU(seenCodeEntries, nil)
+	return &analysisCache{entries: entries, seen: seen, limit: limit}
+}
+
+// get retrieves the analysis of the given code hash, if cached.
+func (c *analysisCache) get(codeHash common.Hash) (*codeAnalysis, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if cached, ok := c.entries.Get(codeHash); ok {
+		return cached.(*codeAnalysis), true
+	}
+	return nil, false
+}
+
+// add inserts or replaces the analysis of the given code hash, evicting the
+// least recently used analyses until the cache fits in its limit again. An
+// analysis larger than the whole cache is not retained at all.
+func (c *analysisCache) add(codeHash common.Hash, analysis *codeAnalysis) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.seen.Remove(codeHash)
+	if old, ok := c.entries.Peek(codeHash); ok {
+		c.size -= old.(*codeAnalysis).size()
+		c.entries.Remove(codeHash)
+	}
+	if size := analysis.size(); size <= c.limit {
+		c.entries.Add(codeHash, analysis)
+		c.size += size
+	}
+	for c.size > c.limit {
+		_, old, _ := c.entries.RemoveOldest()
+		c.size -= old.(*codeAnalysis).size()
+	}
+	analysisSizeGauge.Update(int64(c.size))
+}
+
+// remove drops the analysis of the given code hash and forgets that the code
+// was executed before.
+func (c *analysisCache) remove(codeHash common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.seen.Remove(codeHash)
+	if old, ok := c.entries.Peek(codeHash); ok {
+		c.size -= old.(*codeAnalysis).size()
+		c.entries.Remove(codeHash)
+	}
+	analysisSizeGauge.Update(int64(c.size))
+}
+
+// hot reports whether the given code was executed before, marking it executed
+// otherwise. Code with a cached analysis is always considered executed, since
+// JUMPDEST bitmaps are only created when running code.
+func (c *analysisCache) hot(codeHash common.Hash) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.entries.Contains(codeHash) || c.seen.Contains(codeHash) {
+		return true
+	}
+	c.seen.Add(codeHash, struct{}{})
+	return false
+}
+
+// cachedCodeBitmap returns the JUMPDEST analysis of the given code, either from
+// the global cache or by analysing and caching it.
+func cachedCodeBitmap(codeHash common.Hash, code []byte) bitvec {
+	if cached, ok := analyses.get(codeHash); ok {
+		analysisHitMeter.Mark(1)
+		return cached.bitmap
+	}
+	analysisMissMeter.Mark(1)
+
+	bitmap := codeBitmap(code)
+	analyses.add(codeHash, &codeAnalysis{bitmap: bitmap})
+	return bitmap
+}
+
+// cachedAnalysis returns the analysis of the given code including the program
+// decoded for the given instruction set, either from the global cache or by
+// analysing and caching it. A cached entry built for another instruction set,
+// e.g. before a fork, is replaced by the new one.
+//
+// Decoding is not charged for, so code is only decoded once it was executed
+// before: nil is returned for code run for the first time, which is left to the
+// reference loop of the interpreter.
+func cachedAnalysis(codeHash common.Hash, code []byte, jt *JumpTable) *codeAnalysis {
+	var bitmap bitvec
+	if cached, ok := analyses.get(codeHash); ok {
+		if cached.program != nil && cached.jt == jt {
+			analysisHitMeter.Mark(1)
+			return cached
+		}
+		bitmap = cached.bitmap
+	} else if !analyses.hot(codeHash) {
+		return nil
+	}
+	analysisMissMeter.Mark(1)
+
+	if bitmap == nil {
+		bitmap = codeBitmap(code)
+	}
+	analysis := &codeAnalysis{bitmap: bitmap, jt: jt, program: newProgram(code, bitmap, jt)}
+	analyses.add(codeHash, analysis)
+	return analysis
+}
@@ -58,10 +58,22 @@ type Contract struct {
 	CodeAddr *common.Address
 	Input    []byte
 
+	// Container is the parsed EOF container of Code, nil for legacy code.
+	Container   *Container
+	codeSection uint64           // Index of the EOF code section being executed
+	returnStack []*returnContext // Return addresses of the active EOF function calls
+
 	Gas   uint64
 	value *big.Int
 }
 
+// returnContext is a frame of the EOF return stack, pushed by CALLF and
+// popped by RETF.
+type returnContext struct {
+	section uint64
+	pc      uint64
+}
+
 // NewContract returns a new contract environment for the execution of EVM.
 func NewContract(caller ContractRef, object ContractRef, value *big.Int, gas uint64) *Contract {
 	c := &Contract{CallerAddress: caller.Address(), caller: caller, self: object}
@@ -110,9 +122,9 @@ func (c *Contract) isCode(udest uint64) bool {
 		// Does parent context have the analysis?
 		analysis, exist := c.jumpdests[c.CodeHash]
 		if !exist {
-			// Do the analysis and save in parent context
-			// We do not need to store it in c.analysis
-			analysis = codeBitmap(c.Code)
+			// Retrieve the analysis from the global cache and save in parent
+			// context. We do not need to store it in c.analysis
+			analysis = cachedCodeBitmap(c.CodeHash, c.Code)
 			c.jumpdests[c.CodeHash] = analysis
 		}
 		// Also stash it in current contract for faster access
@@ -129,6 +141,23 @@ func (c *Contract) isCode(udest uint64) bool {
 	return c.analysis.codeSegment(udest)
 }
 
+// program returns the code of the contract decoded for the given instruction
+// set, or nil if it should be run by the reference loop of the interpreter.
+// Programs are cached globally by code hash and only built for code that was
+// executed before. Code without a hash, i.e. the initcode of CREATE, is never
+// decoded, whereas the initcode of CREATE2 is cached like deployed code.
+func (c *Contract) program(jt *JumpTable) *program {
+	if c.CodeHash == (common.Hash{}) {
+		return nil
+	}
+	analysis := cachedAnalysis(c.CodeHash, c.Code, jt)
+	if analysis == nil {
+		return nil
+	}
+	c.analysis = analysis.bitmap
+	return analysis.program
+}
+
 // AsDelegate sets the contract to be a delegate call and returns the current
 // contract (for chaining calls)
 func (c *Contract) AsDelegate() *Contract {
@@ -141,15 +170,25 @@ func (c *Contract) AsDelegate() *Contract {
 	return c
 }
 
-// GetOp returns the n'th element in the contract's byte array
+// GetOp returns the n'th element in the contract's byte array. For EOF
+// contracts, n is relative to the code section being executed.
 func (c *Contract) GetOp(n uint64) OpCode {
-	if n < uint64(len(c.Code)) {
-		return OpCode(c.Code[n])
+	if code := c.executingCode(); n < uint64(len(code)) {
+		return OpCode(code[n])
 	}
 
 	return STOP
 }
 
+// executingCode returns the bytecode the program counter refers to: the whole
+// code for legacy contracts, the active code section for EOF ones.
+func (c *Contract) executingCode() []byte {
+	if c.Container != nil {
+		return c.Container.Code[c.codeSection]
+	}
+	return c.Code
+}
+
 // Caller returns the caller of the contract.
 //
 // Caller will recursively call caller when the contract is a delegate
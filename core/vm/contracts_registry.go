@@ -0,0 +1,114 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// ErrPrecompileReserved is returned when registering a custom precompile at
+	// an address used by one of the built-in precompiled contracts.
+	ErrPrecompileReserved = errors.New("address reserved for built-in precompile")
+
+	// ErrPrecompileRegistered is returned when registering a custom precompile at
+	// an address that already holds one.
+	ErrPrecompileRegistered = errors.New("precompile already registered")
+
+	// ErrPrecompileNotRegistered is returned when the chain config schedules a
+	// custom precompile at an address that holds none.
+	ErrPrecompileNotRegistered = errors.New("scheduled precompile not registered")
+)
+
+var (
+	customPrecompiles     = make(map[common.Address]PrecompiledContract)
+	customPrecompilesLock sync.RWMutex
+)
+
+// RegisterPrecompile registers a custom precompiled contract at the given
+// address. Registering a contract does not activate it: the precompile is only
+// callable from the block scheduled for its address in the chain config's
+// PrecompileBlocks, which keeps the activation part of the consensus rules.
+//
+// Custom precompiles are meant to be registered once, during the start-up of
+// the embedding program, before any EVM is created.
+func RegisterPrecompile(addr common.Address, p PrecompiledContract) error {
+	if _, ok := PrecompiledContractsCancun[addr]; ok {
+		return fmt.Errorf("%w: %v", ErrPrecompileReserved, addr)
+	}
+	if _, ok := PrecompiledContractsBLS[addr]; ok {
+		return fmt.Errorf("%w: %v", ErrPrecompileReserved, addr)
+	}
+	customPrecompilesLock.Lock()
+	defer customPrecompilesLock.Unlock()
+
+	if _, ok := customPrecompiles[addr]; ok {
+		return fmt.Errorf("%w: %v", ErrPrecompileRegistered, addr)
+	}
+	customPrecompiles[addr] = p
+	return nil
+}
+
+// CheckPrecompiles ensures that every custom precompile scheduled by the chain
+// config is registered. A node missing one would silently diverge from the rest
+// of the network once the precompile activates, so this is checked on start-up.
+func CheckPrecompiles(config *params.ChainConfig) error {
+	customPrecompilesLock.RLock()
+	defer customPrecompilesLock.RUnlock()
+
+	for addr := range config.PrecompileBlocks {
+		if _, ok := customPrecompiles[addr]; !ok {
+			return fmt.Errorf("%w: %v", ErrPrecompileNotRegistered, addr)
+		}
+	}
+	return nil
+}
+
+// customPrecompile returns the custom precompiled contract at the given address
+// if it is registered and active under the given rules.
+func customPrecompile(rules *params.Rules, addr common.Address) (PrecompiledContract, bool) {
+	for _, active := range rules.CustomPrecompiles {
+		if active == addr {
+			customPrecompilesLock.RLock()
+			defer customPrecompilesLock.RUnlock()
+
+			p, ok := customPrecompiles[addr]
+			return p, ok
+		}
+	}
+	return nil, false
+}
+
+// activeCustomPrecompiles returns the addresses of the custom precompiles that
+// are both registered and active under the given rules.
+func activeCustomPrecompiles(rules *params.Rules) []common.Address {
+	customPrecompilesLock.RLock()
+	defer customPrecompilesLock.RUnlock()
+
+	var addrs []common.Address
+	for _, addr := range rules.CustomPrecompiles {
+		if _, ok := customPrecompiles[addr]; ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,255 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// reverseBytes is a custom precompile returning its input reversed.
+type reverseBytes struct{}
+
+func (reverseBytes) RequiredGas(input []byte) uint64 { return 100 + uint64(len(input)) }
+
+func (reverseBytes) Run(input []byte) ([]byte, error) {
+	out := make([]byte, len(input))
+	for i, b := range input {
+		out[len(input)-1-i] = b
+	}
+	return out, nil
+}
+
+// registerTestPrecompile registers p at addr for the duration of the test.
+func registerTestPrecompile(t *testing.T, addr common.Address, p PrecompiledContract) {
+	t.Helper()
+	if err := RegisterPrecompile(addr, p); err != nil {
+		t.Fatalf("failed to register precompile: %v", err)
+	}
+	t.Cleanup(func() {
+		customPrecompilesLock.Lock()
+		delete(customPrecompiles, addr)
+		customPrecompilesLock.Unlock()
+	})
+}
+
+func TestRegisterPrecompile(t *testing.T) {
+	addr := common.HexToAddress("0x0100000000000000000000000000000000000001")
+	registerTestPrecompile(t, addr, reverseBytes{})
+
+	if err := RegisterPrecompile(addr, reverseBytes{}); !errors.Is(err, ErrPrecompileRegistered) {
+		t.Errorf("duplicate registration: have %v, want %v", err, ErrPrecompileRegistered)
+	}
+	if err := RegisterPrecompile(common.BytesToAddress([]byte{1}), reverseBytes{}); !errors.Is(err, ErrPrecompileReserved) {
+		t.Errorf("built-in address registration: have %v, want %v", err, ErrPrecompileReserved)
+	}
+	if err := RegisterPrecompile(common.BytesToAddress([]byte{12}), reverseBytes{}); !errors.Is(err, ErrPrecompileReserved) {
+		t.Errorf("BLS address registration: have %v, want %v", err, ErrPrecompileReserved)
+	}
+}
+
+func TestCheckPrecompiles(t *testing.T) {
+	var (
+		addr   = common.HexToAddress("0x0100000000000000000000000000000000000005")
+		config = *params.TestChainConfig
+	)
+	config.PrecompileBlocks = map[common.Address]*big.Int{addr: big.NewInt(10)}
+	if err := CheckPrecompiles(&config); !errors.Is(err, ErrPrecompileNotRegistered) {
+		t.Errorf("unregistered precompile: have %v, want %v", err, ErrPrecompileNotRegistered)
+	}
+	registerTestPrecompile(t, addr, reverseBytes{})
+	if err := CheckPrecompiles(&config); err != nil {
+		t.Errorf("registered precompile: have %v, want nil", err)
+	}
+}
+
+func TestCustomPrecompileActivation(t *testing.T) {
+	var (
+		addr       = common.HexToAddress("0x0100000000000000000000000000000000000002")
+		unassigned = common.HexToAddress("0x0100000000000000000000000000000000000003")
+		config     = *params.TestChainConfig
+	)
+	registerTestPrecompile(t, addr, reverseBytes{})
+	config.PrecompileBlocks = map[common.Address]*big.Int{
+		addr:       big.NewInt(10),
+		unassigned: big.NewInt(0), // scheduled, but never registered
+	}
+	contains := func(addrs []common.Address, addr common.Address) bool {
+		for _, a := range addrs {
+			if a == addr {
+				return true
+			}
+		}
+		return false
+	}
+	for _, tt := range []struct {
+		number int64
+		active bool
+	}{{9, false}, {10, true}, {11, true}} {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		evm := NewEVM(BlockContext{
+			BlockNumber: big.NewInt(tt.number),
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}, TxContext{}, statedb, &config, Config{})
+
+		if _, ok := evm.precompile(addr); ok != tt.active {
+			t.Errorf("block %d: precompile lookup mismatch: have %v, want %v", tt.number, ok, tt.active)
+		}
+		if _, ok := evm.precompile(unassigned); ok {
+			t.Errorf("block %d: unregistered precompile found", tt.number)
+		}
+		active := ActivePrecompiles(evm.chainRules)
+		if have := contains(active, addr); have != tt.active {
+			t.Errorf("block %d: active precompiles mismatch: have %v, want %v", tt.number, have, tt.active)
+		}
+		if contains(active, unassigned) {
+			t.Errorf("block %d: unregistered precompile reported active", tt.number)
+		}
+		ret, _, err := evm.Call(AccountRef(common.Address{}), addr, []byte{1, 2, 3}, 10000, new(big.Int))
+		if err != nil {
+			t.Fatalf("block %d: call failed: %v", tt.number, err)
+		}
+		want := []byte{}
+		if tt.active {
+			want = []byte{3, 2, 1}
+		}
+		if !bytes.Equal(ret, want) {
+			t.Errorf("block %d: call output mismatch: have %x, want %x", tt.number, ret, want)
+		}
+	}
+	// The built-in address lists must not have been modified.
+	if contains(PrecompiledAddressesCancun, addr) || contains(PrecompiledAddressesHomestead, addr) {
+		t.Fatal("custom precompile leaked into built-in address list")
+	}
+}
+
+// callCounter is a stateful precompile counting its invocations in slot 0 of
+// the executing account and recording the last caller in slot 1. Calls with
+// a non-empty input fail after modifying the state.
+type callCounter struct{}
+
+func (callCounter) RequiredGas(input []byte) uint64 { return 1000 }
+
+func (callCounter) Run(input []byte) ([]byte, error) {
+	panic("stateless execution of stateful precompile")
+}
+
+func (callCounter) RunStateful(evm *EVM, caller, self common.Address, value *big.Int, input []byte, readOnly bool) ([]byte, error) {
+	if readOnly {
+		return nil, ErrWriteProtection
+	}
+	if evm.StateDB.GetNonce(self) == 0 {
+		evm.StateDB.SetNonce(self, 1)
+	}
+	count := evm.StateDB.GetState(self, common.Hash{}).Big()
+	evm.StateDB.SetState(self, common.Hash{}, common.BigToHash(count.Add(count, common.Big1)))
+	evm.StateDB.SetState(self, common.Hash{31: 1}, caller.Hash())
+	evm.StateDB.AddLog(&types.Log{Address: self, Data: common.BigToHash(value).Bytes()})
+
+	if len(input) > 0 {
+		return nil, errors.New("call failed")
+	}
+	return caller.Bytes(), nil
+}
+
+func TestStatefulPrecompile(t *testing.T) {
+	var (
+		addr     = common.HexToAddress("0x0100000000000000000000000000000000000004")
+		origin   = common.HexToAddress("0x1000")
+		delegate = common.HexToAddress("0x2000")
+		config   = *params.TestChainConfig
+	)
+	registerTestPrecompile(t, addr, callCounter{})
+	config.PrecompileBlocks = map[common.Address]*big.Int{addr: big.NewInt(0)}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetBalance(origin, big.NewInt(1000))
+	// The delegate contract forwards every call to the precompile via DELEGATECALL.
+	code := []byte{
+		byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0,
+		byte(PUSH20),
+	}
+	code = append(code, addr.Bytes()...)
+	code = append(code, byte(GAS), byte(DELEGATECALL), byte(STOP))
+	statedb.SetCode(delegate, code)
+
+	evm := NewEVM(BlockContext{
+		BlockNumber: big.NewInt(1),
+		CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+	}, TxContext{}, statedb, &config, Config{})
+
+	// A plain call executes in the context of the precompile.
+	ret, gas, err := evm.Call(AccountRef(origin), addr, nil, 10000, big.NewInt(7))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if common.BytesToAddress(ret) != origin {
+		t.Errorf("caller mismatch: have %x, want %x", ret, origin)
+	}
+	if gas != 9000 {
+		t.Errorf("gas mismatch: have %d, want %d", gas, 9000)
+	}
+	if have := statedb.GetState(addr, common.Hash{}); have != common.BigToHash(common.Big1) {
+		t.Errorf("counter mismatch: have %x, want 1", have)
+	}
+	if logs := statedb.Logs(); len(logs) != 1 || common.BytesToHash(logs[0].Data) != common.BigToHash(big.NewInt(7)) {
+		t.Errorf("unexpected logs: %v", logs)
+	}
+	// A failing call reverts all state modifications of the precompile.
+	if _, gas, err := evm.Call(AccountRef(origin), addr, []byte{1}, 10000, big.NewInt(0)); err == nil || gas != 0 {
+		t.Fatalf("failing call: have gas %d, err %v", gas, err)
+	}
+	if have := statedb.GetState(addr, common.Hash{}); have != common.BigToHash(common.Big1) {
+		t.Errorf("counter not reverted: have %x, want 1", have)
+	}
+	if logs := statedb.Logs(); len(logs) != 1 {
+		t.Errorf("logs not reverted: have %d, want 1", len(logs))
+	}
+	// A static call must not modify the state.
+	if _, _, err := evm.StaticCall(AccountRef(origin), addr, nil, 10000); err != ErrWriteProtection {
+		t.Fatalf("static call: have %v, want %v", err, ErrWriteProtection)
+	}
+	// A delegated call executes in the context of the delegating contract, and
+	// sees its caller.
+	if _, _, err := evm.Call(AccountRef(origin), delegate, nil, 100000, big.NewInt(0)); err != nil {
+		t.Fatalf("delegated call failed: %v", err)
+	}
+	if have := statedb.GetState(delegate, common.Hash{}); have != common.BigToHash(common.Big1) {
+		t.Errorf("delegate counter mismatch: have %x, want 1", have)
+	}
+	if have := statedb.GetState(delegate, common.Hash{31: 1}); have != origin.Hash() {
+		t.Errorf("delegate caller mismatch: have %x, want %x", have, origin.Hash())
+	}
+	if have := statedb.GetState(addr, common.Hash{}); have != common.BigToHash(common.Big1) {
+		t.Errorf("precompile counter modified by delegated call: have %x, want 1", have)
+	}
+}
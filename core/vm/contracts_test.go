@@ -65,6 +65,8 @@ var allPrecompiles = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{16}):   &bls12381Pairing{},
 	common.BytesToAddress([]byte{17}):   &bls12381MapG1{},
 	common.BytesToAddress([]byte{18}):   &bls12381MapG2{},
+
+	common.BytesToAddress([]byte{0x0f, 0x0a}): &kzgPointEvaluation{},
 }
 
 // EIP-152 test vectors
@@ -272,6 +274,12 @@ func TestPrecompileBlake2FMalformedInput(t *testing.T) {
 
 func TestPrecompiledEcrecover(t *testing.T) { testJson("ecRecover", "01", t) }
 
+func TestPrecompiledPointEvaluation(t *testing.T)     { testJson("pointEvaluation", "0f0a", t) }
+func TestPrecompiledPointEvaluationFail(t *testing.T) { testJsonFail("pointEvaluation", "0f0a", t) }
+func BenchmarkPrecompiledPointEvaluation(b *testing.B) {
+	benchJson("pointEvaluation", "0f0a", b)
+}
+
 func testJson(name, addr string, t *testing.T) {
 	tests, err := loadJson(name)
 	if err != nil {
@@ -0,0 +1,228 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	ErrUndefinedInstruction   = errors.New("undefined instruction")
+	ErrTruncatedImmediate     = errors.New("truncated immediate")
+	ErrInvalidSectionArgument = errors.New("invalid section argument")
+	ErrInvalidJumpDest        = errors.New("invalid jump destination")
+	ErrConflictingStack       = errors.New("conflicting stack height")
+	ErrInvalidBranchCount     = errors.New("invalid number of branches in jump table")
+	ErrInvalidOutputs         = errors.New("invalid number of outputs")
+	ErrInvalidMaxStackHeight  = errors.New("invalid max stack height")
+	ErrInvalidCodeTermination = errors.New("invalid code termination")
+	ErrUnreachableCode        = errors.New("unreachable code")
+	ErrStackHeightUnderflow   = errors.New("stack underflow in code section")
+	ErrDeprecatedInstruction  = errors.New("instruction not allowed in EOF code")
+)
+
+// validateCode validates the code parameter against the EOF v1 validity requirements.
+func validateCode(code []byte, section int, metadata []*FunctionMetadata, jt *JumpTable) error {
+	var (
+		i     = 0
+		count = 0
+		op    OpCode
+		dests []int
+	)
+	// The immediate bitmap tracks which bytes are instruction immediates, so
+	// that relative jumps can be checked against instruction boundaries once
+	// the whole section has been walked.
+	analysis := make(bitvec, len(code)/8+1+4)
+	for i < len(code) {
+		count++
+		op = OpCode(code[i])
+		if jt[op].undefined {
+			return fmt.Errorf("%w: op %s, pos %d", ErrUndefinedInstruction, op, i)
+		}
+		switch op {
+		case JUMP, JUMPI, PC, CALLCODE, SELFDESTRUCT:
+			return fmt.Errorf("%w: op %s, pos %d", ErrDeprecatedInstruction, op, i)
+		}
+		switch {
+		case op >= PUSH1 && op <= PUSH32:
+			size := int(op - PUSH0)
+			if len(code) <= i+size {
+				return fmt.Errorf("%w: op %s, pos %d", ErrTruncatedImmediate, op, i)
+			}
+			for j := 1; j <= size; j++ {
+				analysis.set1(uint64(i + j))
+			}
+			i += size
+		case op == RJUMP || op == RJUMPI:
+			if len(code) <= i+2 {
+				return fmt.Errorf("%w: op %s, pos %d", ErrTruncatedImmediate, op, i)
+			}
+			analysis.set1(uint64(i + 1))
+			analysis.set1(uint64(i + 2))
+			dests = append(dests, i+3+int(int16(binary.BigEndian.Uint16(code[i+1:]))))
+			i += 2
+		case op == RJUMPV:
+			if len(code) <= i+1 {
+				return fmt.Errorf("%w: jump table size missing, op %s, pos %d", ErrTruncatedImmediate, op, i)
+			}
+			count := int(code[i+1])
+			if count == 0 {
+				return fmt.Errorf("%w: must not be 0, pos %d", ErrInvalidBranchCount, i)
+			}
+			if len(code) <= i+1+count*2 {
+				return fmt.Errorf("%w: jump table truncated, op %s, pos %d", ErrTruncatedImmediate, op, i)
+			}
+			for j := 0; j < 1+count*2; j++ {
+				analysis.set1(uint64(i + 1 + j))
+			}
+			for j := 0; j < count; j++ {
+				offset := int(int16(binary.BigEndian.Uint16(code[i+2+2*j:])))
+				dests = append(dests, i+2+2*count+offset)
+			}
+			i += 1 + 2*count
+		case op == CALLF:
+			if i+2 >= len(code) {
+				return fmt.Errorf("%w: op %s, pos %d", ErrTruncatedImmediate, op, i)
+			}
+			arg := binary.BigEndian.Uint16(code[i+1:])
+			if arg >= uint16(len(metadata)) {
+				return fmt.Errorf("%w: arg %d, last %d, pos %d", ErrInvalidSectionArgument, arg, len(metadata), i)
+			}
+			analysis.set1(uint64(i + 1))
+			analysis.set1(uint64(i + 2))
+			i += 2
+		}
+		i += 1
+	}
+	// Code sections may not "fall through" and require proper termination.
+	// Therefore, the last instruction must be considered terminal or RJUMP.
+	if !jt[op].terminal && op != RJUMP {
+		return fmt.Errorf("%w: end with %s, pos %d", ErrInvalidCodeTermination, op, i)
+	}
+	// Relative jumps must land in the section on an instruction boundary.
+	for _, dest := range dests {
+		if dest < 0 || dest >= len(code) {
+			return fmt.Errorf("%w: out-of-bounds offset: dest %d, len %d", ErrInvalidJumpDest, dest, len(code))
+		}
+		if !analysis.codeSegment(uint64(dest)) {
+			return fmt.Errorf("%w: offset into immediate: dest %d", ErrInvalidJumpDest, dest)
+		}
+	}
+	paths, err := validateControlFlow(code, section, metadata, jt)
+	if err != nil {
+		return err
+	}
+	if paths != count {
+		return fmt.Errorf("%w: reached %d of %d instructions", ErrUnreachableCode, paths, count)
+	}
+	return nil
+}
+
+// validateControlFlow iterates through all possible branches the provided code
+// value and determines if it is valid per EOF v1. It returns the number of
+// visited instructions.
+func validateControlFlow(code []byte, section int, metadata []*FunctionMetadata, jt *JumpTable) (int, error) {
+	type item struct {
+		pos    int
+		height int
+	}
+	var (
+		heights        = make(map[int]int)
+		worklist       = []item{{0, int(metadata[section].Input)}}
+		maxStackHeight = int(metadata[section].Input)
+	)
+	for 0 < len(worklist) {
+		var (
+			idx    = len(worklist) - 1
+			pos    = worklist[idx].pos
+			height = worklist[idx].height
+		)
+		worklist = worklist[:idx]
+
+	outer:
+		for pos < len(code) {
+			op := OpCode(code[pos])
+
+			// Check if pos has already been visited; if so, the stack heights should be the same.
+			if want, ok := heights[pos]; ok {
+				if height != want {
+					return 0, fmt.Errorf("%w: have %d, want %d", ErrConflictingStack, height, want)
+				}
+				// Already visited this path and stack height
+				// matches.
+				break
+			}
+			heights[pos] = height
+
+			// Validate height for current op and update as needed.
+			if want, have := jt[op].minStack, height; want > have {
+				return 0, fmt.Errorf("%w: at pos %d", ErrStackHeightUnderflow, pos)
+			}
+			height += int(params.StackLimit) - jt[op].maxStack
+
+			switch {
+			case op == CALLF:
+				arg := binary.BigEndian.Uint16(code[pos+1:])
+				if want, have := int(metadata[arg].Input), height; want > have {
+					return 0, fmt.Errorf("%w: at pos %d", ErrStackHeightUnderflow, pos)
+				}
+				height -= int(metadata[arg].Input)
+				height += int(metadata[arg].Output)
+				pos += 3
+			case op == RETF:
+				if want, have := int(metadata[section].Output), height; have != want {
+					return 0, fmt.Errorf("%w: have %d, want %d, at pos %d", ErrInvalidOutputs, have, want, pos)
+				}
+				break outer
+			case op == RJUMP:
+				arg := int16(binary.BigEndian.Uint16(code[pos+1:]))
+				pos += 3 + int(arg)
+			case op == RJUMPI:
+				arg := int16(binary.BigEndian.Uint16(code[pos+1:]))
+				worklist = append(worklist, item{pos: pos + 3 + int(arg), height: height})
+				pos += 3
+			case op == RJUMPV:
+				count := int(code[pos+1])
+				for i := 0; i < count; i++ {
+					arg := int16(binary.BigEndian.Uint16(code[pos+2+2*i:]))
+					worklist = append(worklist, item{pos: pos + 2 + 2*count + int(arg), height: height})
+				}
+				pos += 2 + 2*count
+			default:
+				if op >= PUSH1 && op <= PUSH32 {
+					pos += 1 + int(op-PUSH0)
+				} else if jt[op].terminal {
+					break outer
+				} else {
+					// Simple op, no operand.
+					pos += 1
+				}
+			}
+			if maxStackHeight < height {
+				maxStackHeight = height
+			}
+		}
+	}
+	if maxStackHeight != int(metadata[section].MaxStackHeight) {
+		return 0, fmt.Errorf("%w in code section %d: have %d, want %d", ErrInvalidMaxStackHeight, section, maxStackHeight, metadata[section].MaxStackHeight)
+	}
+	return len(heights), nil
+}
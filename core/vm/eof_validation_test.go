@@ -0,0 +1,256 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func eofInstructionSet() *JumpTable {
+	jt := LookupInstructionSet(params.Rules{IsCancun: true})
+	EnableEIP(3540, &jt)
+	return &jt
+}
+
+func TestValidateCode(t *testing.T) {
+	jt := eofInstructionSet()
+	for i, test := range []struct {
+		code     []byte
+		section  int
+		metadata []*FunctionMetadata
+		err      error
+	}{
+		{
+			code:     []byte{byte(CALLER), byte(POP), byte(STOP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+		},
+		{
+			code:     []byte{byte(CALLF), 0x00, 0x00, byte(STOP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}},
+		},
+		{
+			code:     []byte{byte(ADDRESS), byte(CALLF), 0x00, 0x00, byte(STOP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+		},
+		{
+			code:     []byte{byte(CALLER), byte(POP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrInvalidCodeTermination,
+		},
+		{
+			code: []byte{
+				byte(RJUMP),
+				byte(0x00),
+				byte(0x01),
+				byte(CALLER),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}},
+			err:      ErrUnreachableCode,
+		},
+		{
+			code: []byte{
+				byte(PUSH1),
+				byte(0x42),
+				byte(ADD),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrStackHeightUnderflow,
+		},
+		{
+			code: []byte{
+				byte(PUSH1),
+				byte(0x42),
+				byte(POP),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 2}},
+			err:      ErrInvalidMaxStackHeight,
+		},
+		{
+			code: []byte{
+				byte(PUSH0),
+				byte(RJUMPI),
+				byte(0x00),
+				byte(0x01),
+				byte(PUSH1),
+				byte(0x42), // jumps to here
+				byte(POP),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrInvalidJumpDest,
+		},
+		{
+			code: []byte{
+				byte(PUSH0),
+				byte(RJUMPV),
+				byte(0x02),
+				byte(0x00),
+				byte(0x01),
+				byte(0x00),
+				byte(0x02),
+				byte(PUSH1),
+				byte(0x42), // jumps to here
+				byte(POP),  // and here
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrInvalidJumpDest,
+		},
+		{
+			code: []byte{
+				byte(PUSH0),
+				byte(RJUMPV),
+				byte(0x00),
+				byte(STOP),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrInvalidBranchCount,
+		},
+		{
+			code: []byte{
+				byte(RJUMP), 0x00, 0x03,
+				byte(JUMPDEST), // this code is unreachable to forward jumps alone
+				byte(JUMPDEST),
+				byte(RETURN),
+				byte(PUSH1), 20,
+				byte(PUSH1), 39,
+				byte(PUSH1), 0x00,
+				byte(CODECOPY),
+				byte(PUSH1), 20,
+				byte(PUSH1), 0x00,
+				byte(RJUMP), 0xff, 0xef,
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 3}},
+		},
+		{
+			code: []byte{
+				byte(PUSH1), 1,
+				byte(RJUMPI), 0x00, 0x03,
+				byte(JUMPDEST),
+				byte(JUMPDEST),
+				byte(STOP),
+				byte(PUSH1), 20,
+				byte(PUSH1), 39,
+				byte(PUSH1), 0x00,
+				byte(CODECOPY),
+				byte(PUSH1), 20,
+				byte(PUSH1), 0x00,
+				byte(RETURN),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 3}},
+		},
+		{
+			code: []byte{
+				byte(PUSH1), 1,
+				byte(RJUMPV), 0x02, 0x00, 0x03, 0xff, 0xf8,
+				byte(JUMPDEST),
+				byte(JUMPDEST),
+				byte(STOP),
+				byte(PUSH1), 20,
+				byte(PUSH1), 39,
+				byte(PUSH1), 0x00,
+				byte(CODECOPY),
+				byte(PUSH1), 20,
+				byte(PUSH1), 0x00,
+				byte(RETURN),
+			},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 3}},
+		},
+		{
+			code:     []byte{byte(STOP), byte(STOP), byte(INVALID)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}},
+			err:      ErrUnreachableCode,
+		},
+		{
+			code:     []byte{byte(RETF)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 1, MaxStackHeight: 0}},
+			err:      ErrInvalidOutputs,
+		},
+		{
+			code:     []byte{byte(RETF)},
+			metadata: []*FunctionMetadata{{Input: 3, Output: 3, MaxStackHeight: 3}},
+		},
+		{
+			code:     []byte{byte(CALLF), 0x00, 0x01, byte(POP), byte(STOP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}, {Input: 0, Output: 1, MaxStackHeight: 0}},
+		},
+		{
+			code:     []byte{byte(CALLF), 0x00, 0x02, byte(STOP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}, {Input: 0, Output: 0, MaxStackHeight: 0}},
+			err:      ErrInvalidSectionArgument,
+		},
+		{
+			code:     []byte{byte(ORIGIN), byte(CALLF), 0x00, 0x01, byte(POP), byte(RETF)},
+			section:  1,
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}, {Input: 0, Output: 1, MaxStackHeight: 2}},
+		},
+		{
+			code:     []byte{byte(PUSH1), 0x42, byte(JUMP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrDeprecatedInstruction,
+		},
+		{
+			code:     []byte{byte(0x0c), byte(STOP)},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}},
+			err:      ErrUndefinedInstruction,
+		},
+		{
+			code:     []byte{byte(PUSH2), 0x42},
+			metadata: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 1}},
+			err:      ErrTruncatedImmediate,
+		},
+	} {
+		err := validateCode(test.code, test.section, test.metadata, jt)
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d (%s): unexpected error (want: %v, got: %v)", i, test.code, test.err, err)
+		}
+	}
+}
+
+func TestValidateContainer(t *testing.T) {
+	jt := eofInstructionSet()
+	c := Container{
+		Types: []*FunctionMetadata{
+			{Input: 0, Output: 0, MaxStackHeight: 2},
+			{Input: 1, Output: 1, MaxStackHeight: 3},
+		},
+		Code: [][]byte{
+			{byte(PUSH1), 0x15, byte(CALLF), 0x00, 0x01, byte(PUSH1), 0x00, byte(MSTORE), byte(PUSH1), 0x20, byte(PUSH1), 0x00, byte(RETURN)},
+			{byte(DUP1), byte(ADD), byte(DUP1), byte(PUSH1), 0x40, byte(GT), byte(RJUMPI), 0xff, 0xf7, byte(RETF)},
+		},
+		Data: []byte{},
+	}
+	var parsed Container
+	if err := parsed.UnmarshalBinary(c.MarshalBinary()); err != nil {
+		t.Fatalf("failed to parse container: %v", err)
+	}
+	if err := parsed.ValidateCode(jt); err != nil {
+		t.Fatalf("failed to validate container: %v", err)
+	}
+	// The legacy instruction set lacks the EOF instructions, so validation
+	// must fail on the first CALLF.
+	legacy := LookupInstructionSet(params.Rules{IsCancun: true})
+	if err := parsed.ValidateCode(&legacy); !errors.Is(err, ErrUndefinedInstruction) {
+		t.Fatalf("unexpected error validating against legacy set: have %v, want %v", err, ErrUndefinedInstruction)
+	}
+}
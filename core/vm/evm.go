@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"fmt"
 	"math/big"
 	"sync/atomic"
 	"time"
@@ -44,6 +45,8 @@ type (
 func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 	var precompiles map[common.Address]PrecompiledContract
 	switch {
+	case evm.chainRules.IsCancun:
+		precompiles = PrecompiledContractsCancun
 	case evm.chainRules.IsBerlin:
 		precompiles = PrecompiledContractsBerlin
 	case evm.chainRules.IsIstanbul:
@@ -53,8 +56,10 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 	default:
 		precompiles = PrecompiledContractsHomestead
 	}
-	p, ok := precompiles[addr]
-	return p, ok
+	if p, ok := precompiles[addr]; ok {
+		return p, true
+	}
+	return customPrecompile(&evm.chainRules, addr)
 }
 
 // BlockContext provides the EVM with auxiliary information. Once provided
@@ -72,9 +77,10 @@ type BlockContext struct {
 	Coinbase    common.Address // Provides information for COINBASE
 	GasLimit    uint64         // Provides information for GASLIMIT
 	BlockNumber *big.Int       // Provides information for NUMBER
-	Time        *big.Int       // Provides information for TIME
+	Time        uint64         // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
 	BaseFee     *big.Int       // Provides information for BASEFEE
+	BlobBaseFee *big.Int       // Provides information for the blob gas price
 	Random      *common.Hash   // Provides information for PREVRANDAO
 }
 
@@ -82,8 +88,9 @@ type BlockContext struct {
 // All fields can change between transactions.
 type TxContext struct {
 	// Message information
-	Origin   common.Address // Provides information for ORIGIN
-	GasPrice *big.Int       // Provides information for GASPRICE
+	Origin     common.Address // Provides information for ORIGIN
+	GasPrice   *big.Int       // Provides information for GASPRICE
+	BlobHashes []common.Hash  // Provides information for BLOBHASH
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -132,7 +139,7 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig
 		StateDB:     statedb,
 		Config:      config,
 		chainConfig: chainConfig,
-		chainRules:  chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Random != nil),
+		chainRules:  chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Random != nil, blockCtx.Time),
 	}
 	evm.interpreter = NewEVMInterpreter(evm, config)
 	return evm
@@ -212,7 +219,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	}
 
 	if isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = evm.runPrecompile(p, addr, caller.Address(), addr, value, input, gas, evm.interpreter.readOnly)
 	} else {
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
@@ -275,7 +282,7 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 
 	// It is allowed to call precompiles, even via delegatecall
 	if p, isPrecompile := evm.precompile(addr); isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = evm.runPrecompile(p, addr, caller.Address(), caller.Address(), value, input, gas, evm.interpreter.readOnly)
 	} else {
 		addrCopy := addr
 		// Initialise a new contract and set the code that is to be used by the EVM.
@@ -316,7 +323,16 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 
 	// It is allowed to call precompiles, even via delegatecall
 	if p, isPrecompile := evm.precompile(addr); isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		// Stateful precompiles see the delegating frame's caller and value, in
+		// the same way as delegated code would.
+		var (
+			origin = caller.Address()
+			value  = new(big.Int)
+		)
+		if parent, ok := caller.(*Contract); ok {
+			origin, value = parent.CallerAddress, parent.value
+		}
+		ret, gas, err = evm.runPrecompile(p, addr, origin, caller.Address(), value, input, gas, evm.interpreter.readOnly)
 	} else {
 		addrCopy := addr
 		// Initialise a new contract and make initialise the delegate values
@@ -365,7 +381,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	}
 
 	if p, isPrecompile := evm.precompile(addr); isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = evm.runPrecompile(p, addr, caller.Address(), addr, new(big.Int), input, gas, true)
 	} else {
 		// At this point, we use a copy of address. If we don't, the go compiler will
 		// leak the 'contract' to the outer scope, and make allocation for 'contract'
@@ -390,6 +406,28 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	return ret, gas, err
 }
 
+// runPrecompile runs the given precompiled contract, handing stateful
+// precompiles the execution context of the call.
+func (evm *EVM) runPrecompile(p PrecompiledContract, addr, caller, self common.Address, value *big.Int, input []byte, gas uint64, readOnly bool) (ret []byte, leftOverGas uint64, err error) {
+	if profiler := evm.Config.Profiler; profiler != nil {
+		defer func(start time.Time, startGas uint64) {
+			elapsed, used := time.Since(start), startGas-leftOverGas
+			if err != nil && err != ErrExecutionReverted {
+				used = startGas // the caller burns all gas on failure
+			}
+			profiler.recordPrecompile(addr, used, elapsed)
+
+			// Exclude the precompile from the statistics of the calling frame.
+			evm.interpreter.nestedTime += elapsed
+			evm.interpreter.nestedGas += used
+		}(time.Now(), gas)
+	}
+	if sp, ok := p.(StatefulPrecompiledContract); ok {
+		return runStatefulPrecompiledContract(evm, sp, caller, self, value, input, gas, readOnly)
+	}
+	return RunPrecompiledContract(p, input, gas)
+}
+
 type codeAndHash struct {
 	code []byte
 	hash common.Hash
@@ -450,15 +488,33 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 
 	start := time.Now()
 
-	ret, err := evm.interpreter.Run(contract, nil, false)
+	var (
+		ret []byte
+		err error
+	)
+	// Validate EOF initcode before running it, an invalid container fails the
+	// creation just like an exceptional halt.
+	if evm.interpreter.eof && HasEOFMagic(codeAndHash.code) {
+		contract.Container, err = evm.validateEOF(codeAndHash.code)
+	}
+	if err == nil {
+		ret, err = evm.interpreter.Run(contract, nil, false)
+	}
 
 	// Check whether the max code size has been exceeded, assign err if the case.
 	if err == nil && evm.chainRules.IsEIP158 && len(ret) > params.MaxCodeSize {
 		err = ErrMaxCodeSizeExceeded
 	}
 
-	// Reject code starting with 0xEF if EIP-3541 is enabled.
-	if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon {
+	if err == nil && contract.Container != nil {
+		// EOF initcode may only deploy valid EOF containers.
+		if !HasEOFMagic(ret) {
+			err = ErrLegacyCode
+		} else {
+			_, err = evm.validateEOF(ret)
+		}
+	} else if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon {
+		// Reject code starting with 0xEF if EIP-3541 is enabled.
 		err = ErrInvalidCode
 	}
 
@@ -495,6 +551,19 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	return ret, address, contract.Gas, err
 }
 
+// validateEOF parses the given EOF container and validates its code sections
+// against the active instruction set.
+func (evm *EVM) validateEOF(code []byte) (*Container, error) {
+	var c Container
+	if err := c.UnmarshalBinary(code); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, err)
+	}
+	if err := c.ValidateCode(evm.interpreter.cfg.JumpTable); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, err)
+	}
+	return &c, nil
+}
+
 // Create creates a new contract using code as deployment code.
 func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
 	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
@@ -17,6 +17,9 @@
 package vm
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -29,6 +32,8 @@ type Config struct {
 	Tracer                  EVMLogger // Opcode logger
 	NoBaseFee               bool      // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
 	EnablePreimageRecording bool      // Enables recording of SHA3/keccak preimages
+	Profiler                *Profiler // Aggregates execution statistics, if set
+	EnableProgram           bool      // Enables running hot legacy code as cached decoded programs
 
 	JumpTable *JumpTable // EVM instruction table, automatically populated if unset
 
@@ -53,13 +58,29 @@ type EVMInterpreter struct {
 
 	readOnly   bool   // Whether to throw on stateful modifications
 	returnData []byte // Last CALL's return data for subsequent reuse
+
+	eof       bool // Whether EOF containers are recognised (EIP-3540)
+	optimized bool // Whether legacy code may be run as a decoded program
+
+	// Time and gas spent in the nested calls of the current frame, used by the
+	// profiler to exclude them from the frame's own statistics.
+	nestedTime time.Duration
+	nestedGas  uint64
 }
 
 // NewEVMInterpreter returns a new instance of the Interpreter.
 func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
+	// Decoded programs are cached per instruction set, which requires one of the
+	// shared default jump tables.
+	optimized := cfg.EnableProgram && cfg.JumpTable == nil && len(cfg.ExtraEips) == 0
+
 	// If jump table was not initialised we set the default one.
 	if cfg.JumpTable == nil {
 		switch {
+		case evm.chainRules.IsCancun:
+			cfg.JumpTable = &cancunInstructionSet
+		case evm.chainRules.IsShanghai:
+			cfg.JumpTable = &shanghaiInstructionSet
 		case evm.chainRules.IsMerge:
 			cfg.JumpTable = &mergeInstructionSet
 		case evm.chainRules.IsLondon:
@@ -94,10 +115,17 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 		}
 		cfg.ExtraEips = extraEips
 	}
-
+	var eof bool
+	for _, eip := range cfg.ExtraEips {
+		if eip == 3540 {
+			eof = true
+		}
+	}
 	return &EVMInterpreter{
-		evm: evm,
-		cfg: cfg,
+		evm:       evm,
+		cfg:       cfg,
+		eof:       eof,
+		optimized: optimized,
 	}
 }
 
@@ -127,6 +155,15 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	if len(contract.Code) == 0 {
 		return nil, nil
 	}
+	// Parse the EOF container of the code if there is one. Deployed containers
+	// are validated at creation time, so there's no need to validate them again.
+	if in.eof && contract.Container == nil && HasEOFMagic(contract.Code) {
+		var c Container
+		if err := c.UnmarshalBinary(contract.Code); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, err)
+		}
+		contract.Container = &c
+	}
 
 	var (
 		op          OpCode        // current opcode
@@ -156,6 +193,27 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	}()
 	contract.Input = input
 
+	var (
+		prof       *frameProfile // per-opcode statistics of this frame, if profiling
+		opStart    time.Time     // start of the current operation
+		opGas      uint64        // gas before the current operation
+		opNestTime time.Duration // nested time before the current operation
+		opNestGas  uint64        // nested gas before the current operation
+	)
+	if in.cfg.Profiler != nil {
+		prof = new(frameProfile)
+
+		var (
+			start, startGas     = time.Now(), contract.Gas
+			outerTime, outerGas = in.nestedTime, in.nestedGas
+		)
+		in.nestedTime, in.nestedGas = 0, 0
+		defer func() {
+			elapsed, used := time.Since(start), startGas-contract.Gas
+			in.cfg.Profiler.recordFrame(contract.CodeHash, prof, exclusive(used, in.nestedGas), elapsed-in.nestedTime)
+			in.nestedTime, in.nestedGas = outerTime+elapsed, outerGas+used
+		}()
+	}
 	if in.cfg.Debug {
 		defer func() {
 			if err != nil {
@@ -167,6 +225,20 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			}
 		}()
 	}
+	// Run the decoded program of the code if neither tracing nor profiling need
+	// to observe the individual operations. Its results are identical to those
+	// of the loop below, which finishes the execution if the program bails out.
+	if in.optimized && !in.cfg.Debug && prof == nil && contract.Container == nil {
+		if prog := contract.program(in.cfg.JumpTable); prog != nil {
+			var done bool
+			if res, pc, done, err = in.runProgram(prog, callContext); done {
+				if err == errStopToken {
+					err = nil // clear stop token error
+				}
+				return res, err
+			}
+		}
+	}
 	// The Interpreter main run loop (contextual). This loop runs until either an
 	// explicit STOP, RETURN or SELFDESTRUCT is executed, an error occurred during
 	// the execution of one of the operations or until the done flag is set by the
@@ -176,6 +248,9 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			// Capture pre-execution values for tracing.
 			logged, pcCopy, gasCopy = false, pc, contract.Gas
 		}
+		if prof != nil {
+			opStart, opGas, opNestTime, opNestGas = time.Now(), contract.Gas, in.nestedTime, in.nestedGas
+		}
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
 		op = contract.GetOp(pc)
@@ -230,6 +305,9 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		}
 		// execute the operation
 		res, err = operation.execute(&pc, in, callContext)
+		if prof != nil {
+			prof.opcodes[op].add(1, exclusive(opGas-contract.Gas, in.nestedGas-opNestGas), time.Since(opStart)-(in.nestedTime-opNestTime))
+		}
 		if err != nil {
 			break
 		}
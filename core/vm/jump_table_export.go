@@ -0,0 +1,52 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// LookupInstructionSet returns the instruction set for the fork configured by
+// the rules. The returned table is a fresh copy that may be modified, e.g. by
+// EnableEIP.
+func LookupInstructionSet(rules params.Rules) JumpTable {
+	switch {
+	case rules.IsCancun:
+		return newCancunInstructionSet()
+	case rules.IsShanghai:
+		return newShanghaiInstructionSet()
+	case rules.IsMerge:
+		return newMergeInstructionSet()
+	case rules.IsLondon:
+		return newLondonInstructionSet()
+	case rules.IsBerlin:
+		return newBerlinInstructionSet()
+	case rules.IsIstanbul:
+		return newIstanbulInstructionSet()
+	case rules.IsConstantinople:
+		return newConstantinopleInstructionSet()
+	case rules.IsByzantium:
+		return newByzantiumInstructionSet()
+	case rules.IsEIP158:
+		return newSpuriousDragonInstructionSet()
+	case rules.IsEIP150:
+		return newTangerineWhistleInstructionSet()
+	case rules.IsHomestead:
+		return newHomesteadInstructionSet()
+	}
+	return newFrontierInstructionSet()
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ProfileStats are the aggregated execution statistics of an opcode, a contract
+// or a precompile.
+type ProfileStats struct {
+	Count uint64        `json:"count"` // Number of executions
+	Gas   uint64        `json:"gas"`   // Gas consumed, excluding nested calls
+	Time  time.Duration `json:"time"`  // Wall-clock time spent, excluding nested calls
+}
+
+// add accumulates the statistics of a single execution.
+func (s *ProfileStats) add(count uint64, gas uint64, elapsed time.Duration) {
+	s.Count += count
+	s.Gas += gas
+	s.Time += elapsed
+}
+
+// Profile is a snapshot of the statistics aggregated by a Profiler.
+type Profile struct {
+	Opcodes     map[string]ProfileStats         `json:"opcodes"`
+	Contracts   map[common.Hash]ProfileStats    `json:"contracts"` // Keyed by code hash
+	Precompiles map[common.Address]ProfileStats `json:"precompiles"`
+}
+
+// profilerMeters are the metrics counters an aggregated statistic is exported
+// through.
+type profilerMeters struct {
+	count, gas, time metrics.Counter
+}
+
+func newProfilerMeters(prefix string) *profilerMeters {
+	return &profilerMeters{
+		count: metrics.GetOrRegisterCounter(prefix+"/count", nil),
+		gas:   metrics.GetOrRegisterCounter(prefix+"/gas", nil),
+		time:  metrics.GetOrRegisterCounter(prefix+"/time", nil),
+	}
+}
+
+func (m *profilerMeters) update(count uint64, gas uint64, elapsed time.Duration) {
+	m.count.Inc(int64(count))
+	m.gas.Inc(int64(gas))
+	m.time.Inc(int64(elapsed))
+}
+
+// Profiler aggregates wall-clock time and gas consumption of EVM executions per
+// opcode, per contract code hash and per precompiled contract. Both time and
+// gas of a call frame exclude the nested calls it makes, so that the cost of a
+// contract is not attributed to its callers as well.
+//
+// The opcode and precompile statistics are also exported as metrics counters
+// under evm/profile/. Contracts are only exposed through Profile, as a metric
+// per code hash would grow the registry without bound.
+//
+// A Profiler is safe for concurrent use by multiple EVMs.
+type Profiler struct {
+	opcodes     [256]ProfileStats
+	contracts   map[common.Hash]*ProfileStats
+	precompiles map[common.Address]*ProfileStats
+
+	opMeters         [256]*profilerMeters
+	precompileMeters map[common.Address]*profilerMeters
+
+	lock sync.Mutex
+}
+
+// NewProfiler creates an empty EVM execution profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		contracts:        make(map[common.Hash]*ProfileStats),
+		precompiles:      make(map[common.Address]*ProfileStats),
+		precompileMeters: make(map[common.Address]*profilerMeters),
+	}
+}
+
+// Profile returns a snapshot of the statistics aggregated so far.
+func (p *Profiler) Profile() *Profile {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	profile := &Profile{
+		Opcodes:     make(map[string]ProfileStats),
+		Contracts:   make(map[common.Hash]ProfileStats, len(p.contracts)),
+		Precompiles: make(map[common.Address]ProfileStats, len(p.precompiles)),
+	}
+	for op, stats := range p.opcodes {
+		if stats.Count > 0 {
+			profile.Opcodes[OpCode(op).String()] = stats
+		}
+	}
+	for hash, stats := range p.contracts {
+		profile.Contracts[hash] = *stats
+	}
+	for addr, stats := range p.precompiles {
+		profile.Precompiles[addr] = *stats
+	}
+	return profile
+}
+
+// Reset discards all statistics aggregated so far. Metrics counters are left
+// untouched.
+func (p *Profiler) Reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.opcodes = [256]ProfileStats{}
+	p.contracts = make(map[common.Hash]*ProfileStats)
+	p.precompiles = make(map[common.Address]*ProfileStats)
+}
+
+// recordFrame merges the statistics of a finished call frame into the profile.
+func (p *Profiler) recordFrame(codeHash common.Hash, frame *frameProfile, gas uint64, elapsed time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for op := range frame.opcodes {
+		stats := &frame.opcodes[op]
+		if stats.Count == 0 {
+			continue
+		}
+		p.opcodes[op].add(stats.Count, stats.Gas, stats.Time)
+
+		if p.opMeters[op] == nil {
+			p.opMeters[op] = newProfilerMeters(fmt.Sprintf("evm/profile/opcode/%v", OpCode(op)))
+		}
+		p.opMeters[op].update(stats.Count, stats.Gas, stats.Time)
+	}
+	stats, ok := p.contracts[codeHash]
+	if !ok {
+		stats = new(ProfileStats)
+		p.contracts[codeHash] = stats
+	}
+	stats.add(1, gas, elapsed)
+}
+
+// recordPrecompile merges the statistics of a precompile execution into the
+// profile.
+func (p *Profiler) recordPrecompile(addr common.Address, gas uint64, elapsed time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	stats, ok := p.precompiles[addr]
+	if !ok {
+		stats = new(ProfileStats)
+		p.precompiles[addr] = stats
+	}
+	stats.add(1, gas, elapsed)
+
+	meters, ok := p.precompileMeters[addr]
+	if !ok {
+		meters = newProfilerMeters(fmt.Sprintf("evm/profile/precompile/%x", addr))
+		p.precompileMeters[addr] = meters
+	}
+	meters.update(1, gas, elapsed)
+}
+
+// frameProfile gathers the opcode statistics of a single call frame, so that
+// the shared profiler only needs to be locked once per frame.
+type frameProfile struct {
+	opcodes [256]ProfileStats
+}
+
+// exclusive returns total minus nested, saturating at zero. Nested gas may
+// exceed the total charged to a frame due to the call stipend.
+func exclusive(total, nested uint64) uint64 {
+	if nested > total {
+		return 0
+	}
+	return total - nested
+}
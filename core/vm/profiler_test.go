@@ -0,0 +1,110 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// callCode returns code calling addr with 32 bytes of input, no value and all
+// available gas.
+func callCode(addr common.Address) []byte {
+	code := []byte{
+		byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 32, byte(PUSH1), 0, byte(PUSH1), 0,
+		byte(PUSH20),
+	}
+	code = append(code, addr.Bytes()...)
+	return append(code, byte(GAS), byte(CALL), byte(POP))
+}
+
+func TestProfiler(t *testing.T) {
+	var (
+		caller = common.HexToAddress("0x1000")
+		callee = common.HexToAddress("0x2000")
+		sha256 = common.BytesToAddress([]byte{2})
+
+		callerCode = append(append(callCode(callee), callCode(sha256)...), byte(STOP))
+		calleeCode = []byte{byte(PUSH1), 1, byte(PUSH1), 2, byte(ADD), byte(POP), byte(STOP)}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(caller, callerCode)
+	statedb.SetCode(callee, calleeCode)
+
+	profiler := NewProfiler()
+	evm := NewEVM(BlockContext{
+		BlockNumber: big.NewInt(0),
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+	}, TxContext{}, statedb, params.TestChainConfig, Config{Profiler: profiler})
+
+	startGas := uint64(100000)
+	_, leftOver, err := evm.Call(AccountRef(common.Address{}), caller, nil, startGas, new(big.Int))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	profile := profiler.Profile()
+
+	// Both contracts are executed exactly once.
+	callerStats, ok := profile.Contracts[crypto.Keccak256Hash(callerCode)]
+	if !ok || callerStats.Count != 1 {
+		t.Fatalf("caller stats missing or wrong: %+v", callerStats)
+	}
+	calleeStats, ok := profile.Contracts[crypto.Keccak256Hash(calleeCode)]
+	if !ok || calleeStats.Count != 1 {
+		t.Fatalf("callee stats missing or wrong: %+v", calleeStats)
+	}
+	// The callee only runs cheap arithmetic: 2 PUSH1, ADD, POP and STOP.
+	if want := 3*GasFastestStep + GasQuickStep; calleeStats.Gas != want {
+		t.Errorf("callee gas mismatch: have %d, want %d", calleeStats.Gas, want)
+	}
+	precompileStats, ok := profile.Precompiles[sha256]
+	if !ok || precompileStats.Count != 1 {
+		t.Fatalf("precompile stats missing or wrong: %+v", precompileStats)
+	}
+	if want := params.Sha256BaseGas + params.Sha256PerWordGas; precompileStats.Gas != want {
+		t.Errorf("precompile gas mismatch: have %d, want %d", precompileStats.Gas, want)
+	}
+	// Costs are attributed exclusively, so the frames add up to the total.
+	if have, want := callerStats.Gas+calleeStats.Gas+precompileStats.Gas, startGas-leftOver; have != want {
+		t.Errorf("total gas mismatch: have %d, want %d", have, want)
+	}
+	var opGas uint64
+	for _, stats := range profile.Opcodes {
+		opGas += stats.Gas
+	}
+	if have, want := opGas, callerStats.Gas+calleeStats.Gas; have != want {
+		t.Errorf("opcode gas mismatch: have %d, want %d", have, want)
+	}
+	if stats := profile.Opcodes["CALL"]; stats.Count != 2 {
+		t.Errorf("CALL count mismatch: have %d, want 2", stats.Count)
+	}
+	if stats := profile.Opcodes["ADD"]; stats.Count != 1 || stats.Gas != GasFastestStep {
+		t.Errorf("ADD stats mismatch: have %+v", stats)
+	}
+	// Resetting discards all statistics.
+	profiler.Reset()
+	if profile := profiler.Profile(); len(profile.Opcodes)+len(profile.Contracts)+len(profile.Precompiles) != 0 {
+		t.Errorf("profile not reset: %+v", profile)
+	}
+}
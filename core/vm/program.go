@@ -0,0 +1,324 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// instrKind denotes how a decoded instruction is executed.
+type instrKind uint8
+
+const (
+	instrOp        instrKind = iota // Operation with static gas, executed through the jump table
+	instrJump                       // JUMP or JUMPI to a destination computed at runtime
+	instrDynamic                    // Operation charged and checked on its own, like the reference loop does
+	instrPush                       // PUSHn with the value decoded in advance
+	instrPushPush                   // Two consecutive PUSHes
+	instrPushJump                   // PUSHn followed by JUMP to a valid destination
+	instrPushJumpi                  // PUSHn followed by JUMPI to a valid destination
+)
+
+// staticBlock is a sequence of instructions without dynamic gas costs, which
+// is entered at its first instruction only and left at its last one. The gas
+// and stack requirements of all its operations are checked when entering it.
+type staticBlock struct {
+	gas      uint64 // Sum of the constant gas of the operations
+	minStack int    // Stack height needed on entry to not underflow
+	maxStack int    // Stack height allowed on entry to not overflow
+}
+
+// instruction is an element of a decoded program.
+type instruction struct {
+	operation *operation
+	block     *staticBlock // Block entered at this instruction, if any
+	pc        uint64       // Position of the (first) opcode in the code
+	arg       uint32       // Index of the PUSH value, or of the jump target instruction
+	kind      instrKind
+}
+
+// program is contract code decoded for a given instruction set, with the
+// immediates of PUSH operations pre-decoded, common sequences fused and the
+// static gas costs of straight-line code aggregated.
+type program struct {
+	instrs    []instruction
+	values    []uint256.Int     // Values pushed by PUSHn
+	jumpdests map[uint64]uint32 // Instruction index of every JUMPDEST by pc
+	size      uint64            // Approximate memory used by the program
+}
+
+// jumpdestEntrySize is the approximate memory used by an entry of the jumpdests
+// map of a program, including the overhead of the map buckets.
+const jumpdestEntrySize = 32
+
+// newProgram decodes the given legacy code for the given instruction set. The
+// bitmap is the JUMPDEST analysis of the code.
+func newProgram(code []byte, bitmap bitvec, jt *JumpTable) *program {
+	prog := &program{
+		instrs:    make([]instruction, 0, len(code)/2+1),
+		jumpdests: make(map[uint64]uint32),
+	}
+	var (
+		block  *staticBlock // Block being built, nil if the next instruction starts a new one
+		height int          // Stack height relative to the entry of the block
+		blocks int          // Number of static blocks allocated
+	)
+	// add appends an operation to the program, extending or terminating the
+	// current static block.
+	add := func(op OpCode, pc uint64, kind instrKind, arg uint32) {
+		operation := jt[op]
+
+		// GAS exposes the remaining gas, so the costs of the block must not be
+		// charged in advance.
+		if operation.dynamicGas != nil || op == GAS {
+			prog.instrs = append(prog.instrs, instruction{operation: operation, pc: pc, kind: instrDynamic})
+			block = nil
+			return
+		}
+		ins := instruction{operation: operation, pc: pc, arg: arg, kind: kind}
+		if block == nil || op == JUMPDEST {
+			block = &staticBlock{maxStack: int(^uint(0) >> 1)}
+			height = 0
+			blocks++
+			ins.block = block
+		}
+		block.gas += operation.constantGas
+		if min := operation.minStack - height; min > block.minStack {
+			block.minStack = min
+		}
+		if max := operation.maxStack - height; max < block.maxStack {
+			block.maxStack = max
+		}
+		height += operationStackDelta(operation)
+
+		if op == JUMPDEST {
+			prog.jumpdests[pc] = uint32(len(prog.instrs))
+		}
+		prog.instrs = append(prog.instrs, ins)
+
+		switch {
+		case op == JUMP, op == JUMPI, op == STOP, operation.terminal, operation.undefined:
+			block = nil
+		}
+	}
+	for pc := uint64(0); pc < uint64(len(code)); {
+		op := OpCode(code[pc])
+		if size, ok := pushSize(op, jt); ok {
+			prog.values = append(prog.values, pushValue(code, pc, size))
+			add(op, pc, instrPush, uint32(len(prog.values)-1))
+			pc += 1 + size
+			continue
+		}
+		kind := instrOp
+		if op == JUMP || op == JUMPI {
+			kind = instrJump
+		}
+		add(op, pc, kind, 0)
+		pc++
+	}
+	// Running off the end of the code is an implicit STOP.
+	add(STOP, uint64(len(code)), instrOp, 0)
+
+	prog.fuse(code, bitmap)
+
+	prog.size = uint64(cap(prog.instrs))*uint64(unsafe.Sizeof(instruction{})) +
+		uint64(cap(prog.values))*uint64(unsafe.Sizeof(uint256.Int{})) +
+		uint64(blocks)*uint64(unsafe.Sizeof(staticBlock{})) +
+		uint64(len(prog.jumpdests))*jumpdestEntrySize
+	return prog
+}
+
+// fuse merges the PUSH instructions of the program with the instruction
+// following them within the same static block, where possible. Fused
+// instructions keep their place in the stream, so that the instruction indices
+// of the jump destinations remain valid; the second instruction of a fused pair
+// is simply skipped during execution.
+func (prog *program) fuse(code []byte, bitmap bitvec) {
+	for i := 0; i+1 < len(prog.instrs); i++ {
+		ins, next := &prog.instrs[i], &prog.instrs[i+1]
+		if ins.kind != instrPush || next.block != nil {
+			continue
+		}
+		switch next.kind {
+		case instrPush:
+			// Push values are stored in code order, so the second one follows.
+			ins.kind = instrPushPush
+			i++
+		case instrJump:
+			dest, overflow := prog.values[ins.arg].Uint64WithOverflow()
+			if overflow || dest >= uint64(len(code)) || OpCode(code[dest]) != JUMPDEST || !bitmap.codeSegment(dest) {
+				continue // Invalid jumps are left to fail at runtime
+			}
+			ins.arg = prog.jumpdests[dest]
+			if OpCode(code[next.pc]) == JUMP {
+				ins.kind = instrPushJump
+			} else {
+				ins.kind = instrPushJumpi
+			}
+			i++
+		}
+	}
+}
+
+// pushSize returns the number of immediate bytes of op, if it is a PUSH in the
+// given instruction set.
+func pushSize(op OpCode, jt *JumpTable) (uint64, bool) {
+	switch {
+	case op >= PUSH1 && op <= PUSH32:
+		return uint64(op - PUSH1 + 1), true
+	case op == PUSH0 && !jt[PUSH0].undefined:
+		return 0, true
+	}
+	return 0, false
+}
+
+// pushValue decodes the immediate of the PUSH operation at pc, right-padding
+// it with zeroes if the code ends prematurely, like makePush does.
+func pushValue(code []byte, pc uint64, size uint64) uint256.Int {
+	var value uint256.Int
+	if size == 0 {
+		return value
+	}
+	start := pc + 1
+	if start > uint64(len(code)) {
+		start = uint64(len(code))
+	}
+	end := start + size
+	if end > uint64(len(code)) {
+		end = uint64(len(code))
+	}
+	value.SetBytes(common.RightPadBytes(code[start:end], int(size)))
+	return value
+}
+
+// operationStackDelta returns the change of the stack height caused by the
+// given operation.
+func operationStackDelta(op *operation) int {
+	// maxStack is defined as StackLimit + pops - pushes.
+	return int(params.StackLimit) - op.maxStack
+}
+
+// runProgram executes the decoded program of the contract until it halts, or
+// until it can no longer guarantee the same outcome as the reference loop in
+// Run. In the latter case it returns with done unset and the pc to resume the
+// reference loop at, having made no modifications since entering the static
+// block at that pc.
+func (in *EVMInterpreter) runProgram(prog *program, scope *ScopeContext) (ret []byte, pc uint64, done bool, err error) {
+	var (
+		instrs   = prog.instrs
+		values   = prog.values
+		stack    = scope.Stack
+		contract = scope.Contract
+		i        uint32
+	)
+	for {
+		ins := &instrs[i]
+		if block := ins.block; block != nil {
+			// Let the reference loop report the exact error if the block can't
+			// be executed in full.
+			if sLen := stack.len(); sLen < block.minStack || sLen > block.maxStack || contract.Gas < block.gas {
+				return nil, ins.pc, false, nil
+			}
+			contract.Gas -= block.gas
+		}
+		switch ins.kind {
+		case instrOp:
+			pc = ins.pc
+			if ret, err = ins.operation.execute(&pc, in, scope); err != nil {
+				return ret, pc, true, err
+			}
+			i++
+
+		case instrPush:
+			stack.push(&values[ins.arg])
+			i++
+
+		case instrPushPush:
+			stack.push(&values[ins.arg])
+			stack.push(&values[ins.arg+1])
+			i += 2
+
+		case instrPushJump:
+			if atomic.LoadInt32(&in.evm.abort) != 0 {
+				return nil, ins.pc, true, errStopToken
+			}
+			i = ins.arg
+
+		case instrPushJumpi:
+			if atomic.LoadInt32(&in.evm.abort) != 0 {
+				return nil, ins.pc, true, errStopToken
+			}
+			if cond := stack.pop(); !cond.IsZero() {
+				i = ins.arg
+			} else {
+				i += 2
+			}
+
+		case instrJump:
+			pc = ins.pc
+			if ret, err = ins.operation.execute(&pc, in, scope); err != nil {
+				return ret, pc, true, err
+			}
+			if pc == ins.pc {
+				i++ // JUMPI not taken
+			} else {
+				i = prog.jumpdests[pc+1]
+			}
+
+		case instrDynamic:
+			operation := ins.operation
+			if sLen := stack.len(); sLen < operation.minStack {
+				return nil, ins.pc, true, &ErrStackUnderflow{stackLen: sLen, required: operation.minStack}
+			} else if sLen > operation.maxStack {
+				return nil, ins.pc, true, &ErrStackOverflow{stackLen: sLen, limit: operation.maxStack}
+			}
+			if !contract.UseGas(operation.constantGas) {
+				return nil, ins.pc, true, ErrOutOfGas
+			}
+			var memorySize uint64
+			if operation.memorySize != nil {
+				memSize, overflow := operation.memorySize(stack)
+				if overflow {
+					return nil, ins.pc, true, ErrGasUintOverflow
+				}
+				if memorySize, overflow = math.SafeMul(toWordSize(memSize), 32); overflow {
+					return nil, ins.pc, true, ErrGasUintOverflow
+				}
+			}
+			if operation.dynamicGas != nil {
+				dynamicCost, gasErr := operation.dynamicGas(in.evm, contract, stack, scope.Memory, memorySize)
+				if gasErr != nil || !contract.UseGas(dynamicCost) {
+					return nil, ins.pc, true, ErrOutOfGas
+				}
+			}
+			if memorySize > 0 {
+				scope.Memory.Resize(memorySize)
+			}
+			pc = ins.pc
+			if ret, err = operation.execute(&pc, in, scope); err != nil {
+				return ret, pc, true, err
+			}
+			i++
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// executionResult is the observable outcome of executing a piece of code.
+type executionResult struct {
+	ret  []byte
+	gas  uint64
+	err  string
+	root common.Hash
+}
+
+// executeCode runs code with the given gas, either as a decoded program or
+// with the reference loop of the interpreter.
+func executeCode(code []byte, gas uint64, optimized bool) executionResult {
+	var (
+		contract   = common.HexToAddress("0xc0de")
+		callee     = common.HexToAddress("0xca11")
+		calleeCode = []byte{byte(PUSH1), 1, byte(PUSH1), 0, byte(SSTORE), byte(GAS), byte(PUSH1), 0, byte(MSTORE), byte(PUSH1), 32, byte(PUSH1), 0, byte(RETURN)}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(contract, code)
+	statedb.SetCode(callee, calleeCode)
+	statedb.AddAddressToAccessList(contract)
+
+	// Programs are only decoded for code executed before, mark it as such.
+	if optimized {
+		analyses.hot(crypto.Keccak256Hash(code))
+		analyses.hot(crypto.Keccak256Hash(calleeCode))
+	}
+
+	evm := NewEVM(BlockContext{
+		BlockNumber: big.NewInt(1),
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+	}, TxContext{}, statedb, params.TestChainConfig, Config{EnableProgram: optimized})
+
+	ret, leftOver, err := evm.Call(AccountRef(common.Address{}), contract, nil, gas, new(big.Int))
+	res := executionResult{ret: ret, gas: leftOver, root: statedb.IntermediateRoot(true)}
+	if err != nil {
+		res.err = err.Error()
+	}
+	return res
+}
+
+// checkEquivalence executes code with both interpreter loops and reports any
+// difference in the outcome.
+func checkEquivalence(t *testing.T, code []byte, gas uint64) {
+	t.Helper()
+
+	want := executeCode(code, gas, false)
+	have := executeCode(code, gas, true)
+	if !bytes.Equal(have.ret, want.ret) || have.gas != want.gas || have.err != want.err || have.root != want.root {
+		t.Errorf("code %x, gas %d: outcome mismatch\nhave: ret %x gas %d err %q root %x\nwant: ret %x gas %d err %q root %x",
+			code, gas, have.ret, have.gas, have.err, have.root, want.ret, want.gas, want.err, want.root)
+	}
+}
+
+func TestProgramEquivalence(t *testing.T) {
+	tests := []struct {
+		name string
+		code []byte
+	}{
+		{"empty", nil},
+		{"arithmetic", []byte{byte(PUSH1), 1, byte(PUSH1), 2, byte(ADD), byte(PUSH1), 0, byte(MSTORE), byte(PUSH1), 32, byte(PUSH1), 0, byte(RETURN)}},
+		{"pc and gas", []byte{byte(PUSH1), 1, byte(PC), byte(GAS), byte(ADD), byte(ADD), byte(PUSH1), 0, byte(SSTORE)}},
+		{"push0", []byte{byte(PUSH0), byte(PUSH0), byte(SSTORE), byte(PUSH0), byte(PUSH0), byte(REVERT)}},
+		{"truncated push", []byte{byte(PUSH1), 1, byte(PUSH1), 0, byte(SSTORE), byte(PUSH4), 0xff, 0xff}},
+		{"underflow in block", []byte{byte(PUSH1), 1, byte(POP), byte(POP), byte(PUSH1), 1}},
+		{"overflow", append(bytes.Repeat([]byte{byte(PUSH1), 1}, 1025), byte(STOP))},
+		{"invalid opcode", []byte{byte(PUSH1), 1, byte(PUSH1), 0, byte(SSTORE), 0xef}},
+		{"invalid jump", []byte{byte(PUSH1), 1, byte(PUSH1), 0, byte(SSTORE), byte(PUSH1), 3, byte(JUMP)}},
+		{"jump into push data", []byte{byte(PUSH1), 3, byte(JUMP), byte(PUSH1), byte(JUMPDEST), byte(STOP)}},
+		{"dynamic jump", []byte{byte(PUSH1), 4, byte(DUP1), byte(JUMP), byte(JUMPDEST), byte(PUSH1), 1, byte(SSTORE)}},
+		{"jumpi", []byte{
+			byte(PUSH1), 0, byte(PUSH1), 9, byte(JUMPI), // not taken
+			byte(PUSH1), 1, byte(PUSH1), 9, byte(JUMPI), // taken
+			byte(INVALID), byte(JUMPDEST), byte(PUSH1), 2, byte(PUSH1), 0, byte(SSTORE),
+		}},
+		{"loop", []byte{
+			byte(PUSH1), 0, // counter
+			byte(JUMPDEST),                                                      // pc 2
+			byte(PUSH1), 1, byte(ADD), byte(DUP1), byte(PUSH1), 0, byte(SSTORE), // counter++ and store
+			byte(DUP1), byte(PUSH1), 100, byte(GT), byte(PUSH1), 2, byte(JUMPI), // loop while counter < 100
+			byte(STOP),
+		}},
+		{"call", []byte{
+			byte(PUSH1), 32, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0,
+			byte(PUSH2), 0xca, 0x11, byte(GAS), byte(CALL),
+			byte(PUSH1), 0, byte(MLOAD), byte(ADD), byte(PUSH1), 0, byte(SSTORE),
+		}},
+	}
+	for _, tt := range tests {
+		for _, gas := range []uint64{0, 5, 10, 21, 22, 30, 100, 1000, 25000, 100000, 10000000} {
+			t.Run(fmt.Sprintf("%s/%d", tt.name, gas), func(t *testing.T) {
+				checkEquivalence(t, tt.code, gas)
+			})
+		}
+	}
+}
+
+func TestProgramEquivalenceRandom(t *testing.T) {
+	// Bias the generated code towards stack manipulation and control flow, so
+	// that it runs for more than a few instructions.
+	ops := []OpCode{
+		PUSH0, PUSH1, PUSH1, PUSH1, PUSH2, PUSH32, DUP1, DUP2, SWAP1, POP,
+		ADD, SUB, MUL, LT, GT, ISZERO, AND, JUMP, JUMPI, JUMPDEST, JUMPDEST,
+		PC, GAS, MSIZE, MLOAD, MSTORE, SLOAD, SSTORE, TLOAD, TSTORE, STOP,
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		var code []byte
+		for n := rng.Intn(64); n > 0; n-- {
+			op := ops[rng.Intn(len(ops))]
+			code = append(code, byte(op))
+			if op >= PUSH1 && op <= PUSH32 {
+				for j := 0; j <= int(op-PUSH1); j++ {
+					// Small values make for valid jump destinations
+					code = append(code, byte(rng.Intn(len(code)+8)))
+				}
+			}
+		}
+		checkEquivalence(t, code, uint64(rng.Intn(30000)))
+	}
+}
+
+func TestAnalysisCache(t *testing.T) {
+	var (
+		code = []byte{byte(PUSH1), byte(JUMPDEST), byte(PUSH1), 0, byte(PUSH1), 7, byte(JUMP), byte(JUMPDEST), byte(STOP)}
+		hash = crypto.Keccak256Hash(code)
+	)
+	analyses.remove(hash)
+	t.Cleanup(func() { analyses.remove(hash) })
+
+	// Code executed for the first time is not decoded, only the second time.
+	if cachedAnalysis(hash, code, &cancunInstructionSet) != nil {
+		t.Fatal("program decoded for cold code")
+	}
+	if cachedAnalysis(hash, code, &cancunInstructionSet) == nil {
+		t.Fatal("program not decoded for hot code")
+	}
+	analyses.remove(hash)
+
+	bitmap := cachedCodeBitmap(hash, code)
+	if !bytes.Equal(bitmap, codeBitmap(code)) {
+		t.Fatalf("bitmap mismatch: have %x, want %x", bitmap, codeBitmap(code))
+	}
+	// Decoding the program reuses the cached bitmap.
+	analysis := cachedAnalysis(hash, code, &cancunInstructionSet)
+	if &analysis.bitmap[0] != &bitmap[0] {
+		t.Error("cached bitmap not reused")
+	}
+	if cachedAnalysis(hash, code, &cancunInstructionSet) != analysis {
+		t.Error("cached program not reused")
+	}
+	// Another instruction set requires a new program.
+	other := cachedAnalysis(hash, code, &londonInstructionSet)
+	if other == analysis || other.program == analysis.program {
+		t.Fatal("program reused across instruction sets")
+	}
+	if cachedAnalysis(hash, code, &londonInstructionSet) != other {
+		t.Error("replaced program not cached")
+	}
+	// The first two PUSHes are fused, as are the third one and the JUMP to the
+	// JUMPDEST at pc 7. The JUMPDEST at pc 1 is push data.
+	prog := other.program
+	if len(prog.jumpdests) != 1 {
+		t.Fatalf("jumpdest count mismatch: have %d, want 1", len(prog.jumpdests))
+	}
+	if kind := prog.instrs[0].kind; kind != instrPushPush {
+		t.Errorf("pushes not fused: kind %d", kind)
+	}
+	jump := prog.instrs[2]
+	if jump.kind != instrPushJump || prog.instrs[jump.arg].pc != 7 {
+		t.Errorf("jump not fused: kind %d, target pc %d", jump.kind, prog.instrs[jump.arg].pc)
+	}
+}
+
+func TestAnalysisCacheLimit(t *testing.T) {
+	var (
+		cache    = newAnalysisCache(1024)
+		analysis = func(size int) *codeAnalysis { return &codeAnalysis{bitmap: make(bitvec, size)} }
+	)
+	for i := 0; i < 4; i++ {
+		cache.add(common.Hash{byte(i)}, analysis(300))
+	}
+	// The least recently used analysis must have been evicted to fit the limit.
+	if cache.size != 900 {
+		t.Fatalf("cache size mismatch: have %d, want %d", cache.size, 900)
+	}
+	if _, ok := cache.get(common.Hash{0}); ok {
+		t.Error("least recently used analysis retained")
+	}
+	// Replacing an analysis accounts for the size of the old one.
+	cache.add(common.Hash{1}, analysis(100))
+	if cache.size != 700 {
+		t.Fatalf("cache size mismatch after replacement: have %d, want %d", cache.size, 700)
+	}
+	// Analyses larger than the whole cache are not retained.
+	cache.add(common.Hash{4}, analysis(2048))
+	if _, ok := cache.get(common.Hash{4}); ok || cache.size != 700 {
+		t.Errorf("oversized analysis retained, cache size %d", cache.size)
+	}
+}
+
+func BenchmarkProgramLoop(b *testing.B) {
+	// A tight loop of cheap arithmetic, counting down from 2^16.
+	code := []byte{
+		byte(PUSH3), 0x01, 0x00, 0x00,
+		byte(JUMPDEST), // pc 4
+		byte(PUSH1), 1, byte(SWAP1), byte(SUB), byte(DUP1), byte(PUSH1), 4, byte(JUMPI),
+		byte(STOP),
+	}
+	for _, optimized := range []bool{false, true} {
+		b.Run(fmt.Sprintf("optimized=%v", optimized), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if res := executeCode(code, 10000000, optimized); res.err != "" {
+					b.Fatal(res.err)
+				}
+			}
+		})
+	}
+}
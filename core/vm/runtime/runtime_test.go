@@ -17,6 +17,7 @@
 package runtime
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"os"
@@ -105,6 +106,142 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+// TestShanghaiTime tests that the PUSH0 instruction is only available once the
+// block timestamp passes the Shanghai fork time.
+func TestShanghaiTime(t *testing.T) {
+	code := []byte{
+		byte(vm.PUSH0),
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	}
+	config := *params.AllEthashProtocolChanges
+	config.ShanghaiTime = new(uint64)
+	*config.ShanghaiTime = 1000
+
+	for _, tt := range []struct {
+		time   int64
+		active bool
+	}{{999, false}, {1000, true}, {1001, true}} {
+		_, _, err := Execute(code, nil, &Config{ChainConfig: &config, Time: big.NewInt(tt.time)})
+		if tt.active && err != nil {
+			t.Errorf("time %d: unexpected error: %v", tt.time, err)
+		}
+		if !tt.active && err == nil {
+			t.Errorf("time %d: PUSH0 executed before Shanghai", tt.time)
+		}
+	}
+}
+
+// eofTestContainer returns an EOF container that doubles 0x15 in a separate
+// code section until it exceeds 0x40, returning the result (0x54).
+func eofTestContainer() *vm.Container {
+	return &vm.Container{
+		Types: []*vm.FunctionMetadata{
+			{Input: 0, Output: 0, MaxStackHeight: 2},
+			{Input: 1, Output: 1, MaxStackHeight: 3},
+		},
+		Code: [][]byte{
+			{
+				byte(vm.PUSH1), 0x15,
+				byte(vm.CALLF), 0x00, 0x01,
+				byte(vm.PUSH1), 0,
+				byte(vm.MSTORE),
+				byte(vm.PUSH1), 32,
+				byte(vm.PUSH1), 0,
+				byte(vm.RETURN),
+			},
+			{
+				byte(vm.DUP1),
+				byte(vm.ADD),
+				byte(vm.DUP1),
+				byte(vm.PUSH1), 0x40,
+				byte(vm.GT),
+				byte(vm.RJUMPI), 0xff, 0xf7,
+				byte(vm.RETF),
+			},
+		},
+		Data: []byte{},
+	}
+}
+
+// TestExecuteEOF tests that EOF containers are executed when EIP-3540 is
+// enabled, and rejected as invalid code otherwise.
+func TestExecuteEOF(t *testing.T) {
+	code := eofTestContainer().MarshalBinary()
+
+	ret, _, err := Execute(code, nil, &Config{EVMConfig: vm.Config{ExtraEips: []int{3540}}})
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if num := new(big.Int).SetBytes(ret); num.Cmp(big.NewInt(0x54)) != 0 {
+		t.Errorf("Expected 0x54, got %v", num)
+	}
+	if _, _, err := Execute(code, nil, nil); err == nil {
+		t.Error("expected EOF code to fail without EIP-3540")
+	}
+}
+
+// TestCreateEOF tests that EOF initcode is validated at creation time and may
+// only deploy valid EOF containers.
+func TestCreateEOF(t *testing.T) {
+	initcode := func(deployed []byte) []byte {
+		size := byte(len(deployed))
+		c := &vm.Container{
+			Types: []*vm.FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 3}},
+			Code: [][]byte{{
+				byte(vm.PUSH1), size,
+				byte(vm.PUSH1), 0, // data offset, patched below
+				byte(vm.PUSH1), 0,
+				byte(vm.CODECOPY),
+				byte(vm.PUSH1), size,
+				byte(vm.PUSH1), 0,
+				byte(vm.RETURN),
+			}},
+			Data: deployed,
+		}
+		c.Code[0][3] = byte(len(c.MarshalBinary()) - len(deployed))
+		return c.MarshalBinary()
+	}
+	runtime := eofTestContainer().MarshalBinary()
+	invalid := eofTestContainer()
+	invalid.Types[1].MaxStackHeight = 2
+
+	for i, tt := range []struct {
+		code []byte
+		eips []int
+		ok   bool
+	}{
+		{code: initcode(runtime), eips: []int{3540}, ok: true},
+		{code: initcode(runtime), ok: false},                                  // EOF disabled
+		{code: initcode([]byte{byte(vm.STOP)}), eips: []int{3540}, ok: false}, // legacy code deployed
+		{code: initcode(invalid.MarshalBinary()), eips: []int{3540}, ok: false},
+		{code: append(initcode(runtime), 0x00), eips: []int{3540}, ok: false}, // malformed initcode
+	} {
+		cfg := &Config{EVMConfig: vm.Config{ExtraEips: tt.eips}}
+		code, address, _, err := Create(tt.code, cfg)
+		if tt.ok {
+			if err != nil {
+				t.Fatalf("test %d: unexpected error: %v", i, err)
+			}
+			if !bytes.Equal(code, runtime) {
+				t.Fatalf("test %d: deployed code mismatch: have %x, want %x", i, code, runtime)
+			}
+			ret, _, err := Call(address, nil, cfg)
+			if err != nil {
+				t.Fatalf("test %d: call failed: %v", i, err)
+			}
+			if num := new(big.Int).SetBytes(ret); num.Cmp(big.NewInt(0x54)) != 0 {
+				t.Errorf("test %d: expected 0x54, got %v", i, num)
+			}
+		} else if err == nil {
+			t.Errorf("test %d: expected creation to fail", i)
+		}
+	}
+}
+
 func TestCall(t *testing.T) {
 	state, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	address := common.HexToAddress("0x0a")
@@ -0,0 +1,176 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"errors"
+)
+
+// Flags of the compressed point encoding, set in the most significant bits of
+// the first byte, as defined by the zcash serialization format.
+const (
+	compressionFlag = 1 << 7 // The point is compressed, only x is encoded
+	infinityFlag    = 1 << 6 // The point is the point at infinity
+	largestFlag     = 1 << 5 // The y coordinate is the lexicographically largest of the two roots
+	flagsMask       = compressionFlag | infinityFlag | largestFlag
+)
+
+// isLargest reports whether a field element is lexicographically larger than
+// its negation.
+func isLargest(e *fe) bool {
+	return toBig(e).Cmp(pMinus1Over2) > 0
+}
+
+// isLargest2 reports whether an Fp2 element is lexicographically larger than
+// its negation, comparing the imaginary parts first.
+func isLargest2(e *fe2) bool {
+	if !e[1].isZero() {
+		return isLargest(&e[1])
+	}
+	return isLargest(&e[0])
+}
+
+// checkInfinity validates the encoding of a compressed point at infinity.
+func checkInfinity(in []byte) error {
+	if in[0] != compressionFlag|infinityFlag {
+		return errors.New("invalid point at infinity flags")
+	}
+	for _, b := range in[1:] {
+		if b != 0 {
+			return errors.New("invalid point at infinity encoding")
+		}
+	}
+	return nil
+}
+
+// ToCompressed serializes a point into 48 bytes in compressed form, following
+// the zcash serialization format.
+func (g *G1) ToCompressed(p *PointG1) []byte {
+	out := make([]byte, 48)
+	if g.IsZero(p) {
+		out[0] = compressionFlag | infinityFlag
+		return out
+	}
+	g.Affine(p)
+	copy(out, toBytes(&p[0]))
+	out[0] |= compressionFlag
+	if isLargest(&p[1]) {
+		out[0] |= largestFlag
+	}
+	return out
+}
+
+// FromCompressed constructs a point from its 48 bytes compressed form, following
+// the zcash serialization format. The point is checked to be on the curve and
+// in the correct subgroup.
+func (g *G1) FromCompressed(in []byte) (*PointG1, error) {
+	if len(in) != 48 {
+		return nil, errors.New("input string should be equal 48 bytes")
+	}
+	if in[0]&compressionFlag == 0 {
+		return nil, errors.New("point is not compressed")
+	}
+	if in[0]&infinityFlag != 0 {
+		if err := checkInfinity(in); err != nil {
+			return nil, err
+		}
+		return g.Zero(), nil
+	}
+	buf := make([]byte, 48)
+	copy(buf, in)
+	buf[0] &^= flagsMask
+
+	x, err := fromBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	// y^2 = x^3 + b
+	y, y2 := new(fe), new(fe)
+	square(y2, x)
+	mul(y2, y2, x)
+	add(y2, y2, b)
+	if !sqrt(y, y2) {
+		return nil, errors.New("point is not on curve")
+	}
+	if isLargest(y) != (in[0]&largestFlag != 0) {
+		neg(y, y)
+	}
+	p := &PointG1{*x, *y, *new(fe).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in correct subgroup")
+	}
+	return p, nil
+}
+
+// ToCompressed serializes a point into 96 bytes in compressed form, following
+// the zcash serialization format.
+func (g *G2) ToCompressed(p *PointG2) []byte {
+	out := make([]byte, 96)
+	if g.IsZero(p) {
+		out[0] = compressionFlag | infinityFlag
+		return out
+	}
+	g.Affine(p)
+	copy(out, g.f.toBytes(&p[0]))
+	out[0] |= compressionFlag
+	if isLargest2(&p[1]) {
+		out[0] |= largestFlag
+	}
+	return out
+}
+
+// FromCompressed constructs a point from its 96 bytes compressed form, following
+// the zcash serialization format. The point is checked to be on the curve and
+// in the correct subgroup.
+func (g *G2) FromCompressed(in []byte) (*PointG2, error) {
+	if len(in) != 96 {
+		return nil, errors.New("input string should be equal 96 bytes")
+	}
+	if in[0]&compressionFlag == 0 {
+		return nil, errors.New("point is not compressed")
+	}
+	if in[0]&infinityFlag != 0 {
+		if err := checkInfinity(in); err != nil {
+			return nil, err
+		}
+		return g.Zero(), nil
+	}
+	buf := make([]byte, 96)
+	copy(buf, in)
+	buf[0] &^= flagsMask
+
+	x, err := g.f.fromBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	// y^2 = x^3 + b2
+	y, y2 := new(fe2), new(fe2)
+	g.f.square(y2, x)
+	g.f.mul(y2, y2, x)
+	g.f.add(y2, y2, b2)
+	if !g.f.sqrt(y, y2) {
+		return nil, errors.New("point is not on curve")
+	}
+	if isLargest2(y) != (in[0]&largestFlag != 0) {
+		g.f.neg(y, y)
+	}
+	p := &PointG2{*x, *y, *new(fe2).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in correct subgroup")
+	}
+	return p, nil
+}
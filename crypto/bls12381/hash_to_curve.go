@@ -0,0 +1,108 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// expandMsgXMD expands a message into a uniformly random byte string of the
+// given length, using SHA-256 as defined in RFC 9380, section 5.3.1.
+func expandMsgXMD(msg, dst []byte, length int) ([]byte, error) {
+	ell := (length + sha256.Size - 1) / sha256.Size
+	if ell > 255 || length > 65535 {
+		return nil, errors.New("requested length too large")
+	}
+	if len(dst) > 255 {
+		return nil, errors.New("domain separation tag too long")
+	}
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	// b_0 = H(Z_pad || msg || l_i_b_str || 0 || DST_prime)
+	h := sha256.New()
+	h.Write(make([]byte, sha256.BlockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(length >> 8), byte(length), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	// b_i = H(strxor(b_0, b_(i-1)) || i || DST_prime)
+	var (
+		out  = make([]byte, 0, ell*sha256.Size)
+		prev = make([]byte, sha256.Size)
+	)
+	for i := 1; i <= ell; i++ {
+		for j := range prev {
+			prev[j] ^= b0[j]
+		}
+		h.Reset()
+		h.Write(prev)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		prev = h.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length], nil
+}
+
+// hashToFieldFp2 hashes a message into count elements of Fp2, as defined in
+// RFC 9380, section 5.2.
+func hashToFieldFp2(msg, dst []byte, count int) ([]*fe2, error) {
+	const l = 64 // ceil((ceil(log2(p)) + k) / 8), with k = 128 bits of security
+
+	uniform, err := expandMsgXMD(msg, dst, count*2*l)
+	if err != nil {
+		return nil, err
+	}
+	p := modulus.big()
+	elems := make([]*fe2, count)
+	for i := range elems {
+		elems[i] = new(fe2)
+		for j := 0; j < 2; j++ {
+			offset := l * (j + i*2)
+			e, err := fromBig(new(big.Int).Mod(new(big.Int).SetBytes(uniform[offset:offset+l]), p))
+			if err != nil {
+				return nil, err
+			}
+			elems[i][j].set(e)
+		}
+	}
+	return elems, nil
+}
+
+// HashToCurve hashes a message into a G2 point using the hash_to_curve random
+// oracle construction of RFC 9380 with the BLS12381G2_XMD:SHA-256_SSWU_RO_
+// suite, separated by the given domain tag.
+func (g *G2) HashToCurve(msg, dst []byte) (*PointG2, error) {
+	u, err := hashToFieldFp2(msg, dst, 2)
+	if err != nil {
+		return nil, err
+	}
+	one := new(fe2).one()
+	q := make([]*PointG2, 2)
+	for i := range q {
+		x, y := swuMapG2(g.f, u[i])
+		isogenyMapG2(g.f, x, y)
+		q[i] = &PointG2{*x, *y, *one}
+	}
+	r := g.New()
+	g.Add(r, q[0], q[1])
+	g.ClearCofactor(r)
+	return g.Affine(r), nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// SignatureDST is the domain separation tag of the BLS signature scheme used by
+// the Ethereum consensus layer, with public keys in G1 and signatures in G2.
+var SignatureDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+const (
+	// SecretKeyLength is the length of a serialized secret key.
+	SecretKeyLength = 32
+
+	// PublicKeyLength is the length of a compressed public key.
+	PublicKeyLength = 48
+
+	// SignatureLength is the length of a compressed signature.
+	SignatureLength = 96
+)
+
+// SecretKey is a BLS secret key, a non-zero scalar of the group order.
+type SecretKey struct {
+	k *big.Int
+}
+
+// GenerateKey creates a new random secret key, reading randomness from r or
+// from crypto/rand if nil.
+func GenerateKey(r io.Reader) (*SecretKey, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	for {
+		k, err := rand.Int(r, q)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() != 0 {
+			return &SecretKey{k: k}, nil
+		}
+	}
+}
+
+// SecretKeyFromBytes decodes a big-endian encoded secret key.
+func SecretKeyFromBytes(in []byte) (*SecretKey, error) {
+	if len(in) != SecretKeyLength {
+		return nil, errors.New("invalid secret key length")
+	}
+	k := new(big.Int).SetBytes(in)
+	if k.Sign() == 0 || k.Cmp(q) >= 0 {
+		return nil, errors.New("invalid secret key")
+	}
+	return &SecretKey{k: k}, nil
+}
+
+// Bytes returns the big-endian encoding of the secret key.
+func (sk *SecretKey) Bytes() []byte {
+	out := make([]byte, SecretKeyLength)
+	return sk.k.FillBytes(out)
+}
+
+// PublicKey returns the compressed public key of the secret key.
+func (sk *SecretKey) PublicKey() []byte {
+	g := NewG1()
+	return g.ToCompressed(g.MulScalar(g.New(), g.One(), sk.k))
+}
+
+// Sign signs a message, returning the compressed signature.
+func (sk *SecretKey) Sign(msg []byte) ([]byte, error) {
+	g := NewG2()
+	h, err := g.HashToCurve(msg, SignatureDST)
+	if err != nil {
+		return nil, err
+	}
+	return g.ToCompressed(g.MulScalar(g.New(), h, sk.k)), nil
+}
+
+// Verify checks that a compressed signature over a message was created by the
+// owner of a compressed public key.
+func Verify(pubkey, msg, sig []byte) bool {
+	g1, g2 := NewG1(), NewG2()
+
+	pk, err := g1.FromCompressed(pubkey)
+	if err != nil || g1.IsZero(pk) {
+		return false
+	}
+	s, err := g2.FromCompressed(sig)
+	if err != nil {
+		return false
+	}
+	h, err := g2.HashToCurve(msg, SignatureDST)
+	if err != nil {
+		return false
+	}
+	// e(pk, H(m)) == e(g1, sig)
+	return NewPairingEngine().AddPair(pk, h).AddPairInv(g1.One(), s).Check()
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestExpandMsgXMD(t *testing.T) {
+	// Test vectors from RFC 9380, appendix K.1
+	dst := []byte("QUUX-V01-CS02-with-expander-SHA256-128")
+	for i, v := range []struct {
+		msg  string
+		want string
+	}{
+		{"", "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235"},
+		{"abc", "d8ccab23b5985ccea865c6c97b6e5b8350e794e603b4b97902f53a8a0d605615"},
+	} {
+		out, err := expandMsgXMD([]byte(v.msg), dst, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, common.FromHex(v.want)) {
+			t.Errorf("vector %d: have %x, want %s", i, out, v.want)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	g1, g2 := NewG1(), NewG2()
+
+	// The compressed generators are well known
+	if have, want := g1.ToCompressed(g1.One()), common.FromHex("0x97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"); !bytes.Equal(have, want) {
+		t.Fatalf("g1 generator mismatch: have %x, want %x", have, want)
+	}
+	if have, want := g2.ToCompressed(g2.One()), common.FromHex("0x93e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8"); !bytes.Equal(have, want) {
+		t.Fatalf("g2 generator mismatch: have %x, want %x", have, want)
+	}
+	for i := 0; i < 10; i++ {
+		p1 := g1.MulScalar(g1.New(), g1.One(), randScalar(q))
+		d1, err := g1.FromCompressed(g1.ToCompressed(p1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g1.Equal(p1, d1) {
+			t.Fatal("g1 compression roundtrip failed")
+		}
+		p2 := g2.MulScalar(g2.New(), g2.One(), randScalar(q))
+		d2, err := g2.FromCompressed(g2.ToCompressed(p2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g2.Equal(p2, d2) {
+			t.Fatal("g2 compression roundtrip failed")
+		}
+	}
+	for _, p := range [][]byte{g1.ToCompressed(g1.Zero()), g2.ToCompressed(g2.Zero())} {
+		if p[0] != compressionFlag|infinityFlag {
+			t.Fatalf("infinity flags mismatch: %x", p[0])
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	// Test vector from the consensus spec tests, sign_case_84d45c9c7cca6b92
+	sk, err := SecretKeyFromBytes(common.FromHex("0x263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := common.FromHex("0x5656565656565656565656565656565656565656565656565656565656565656")
+	want := common.FromHex("0x882730e5d03f6b42c3abc26d3372625034e1d871b65a8a6b900a56dae22da98abbe1b68f85e49fe7652a55ec3d0591c20767677e33e5cbb1207315c41a9ac03be39c2e7668edc043d6cb1d9fd93033caa8a1c5b0e84bedaeb6c64972503a43eb")
+	sig, err := sk.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Fatalf("signature mismatch: have %x, want %x", sig, want)
+	}
+	pk := sk.PublicKey()
+	if !Verify(pk, msg, sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if Verify(pk, []byte("other message"), sig) {
+		t.Fatal("signature over other message accepted")
+	}
+	other, _ := GenerateKey(nil)
+	if Verify(other.PublicKey(), msg, sig) {
+		t.Fatal("signature by other key accepted")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kzg4844
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+// randBlob creates a blob of random field elements. The top byte of every
+// element is cleared to keep it below the BLS modulus.
+func randBlob() Blob {
+	var blob Blob
+	rand.Read(blob[:])
+	for i := 0; i < len(blob); i += 32 {
+		blob[i] = 0
+	}
+	return blob
+}
+
+func TestCKZGWithPoint(t *testing.T) {
+	blob := randBlob()
+
+	commitment, err := BlobToCommitment(blob)
+	if err != nil {
+		t.Fatalf("failed to create KZG commitment from blob: %v", err)
+	}
+	point := Point{0x01}
+	proof, claim, err := ComputeProof(blob, point)
+	if err != nil {
+		t.Fatalf("failed to create KZG proof at point: %v", err)
+	}
+	if err := VerifyProof(commitment, point, claim, proof); err != nil {
+		t.Fatalf("failed to verify KZG proof at point: %v", err)
+	}
+	claim[31] ^= 0x01
+	if err := VerifyProof(commitment, point, claim, proof); err == nil {
+		t.Fatal("verified KZG proof with invalid claim")
+	}
+}
+
+func TestCKZGWithBlob(t *testing.T) {
+	blob := randBlob()
+
+	commitment, err := BlobToCommitment(blob)
+	if err != nil {
+		t.Fatalf("failed to create KZG commitment from blob: %v", err)
+	}
+	proof, err := ComputeBlobProof(blob, commitment)
+	if err != nil {
+		t.Fatalf("failed to create KZG proof for blob: %v", err)
+	}
+	if err := VerifyBlobProof(blob, commitment, proof); err != nil {
+		t.Fatalf("failed to verify KZG proof for blob: %v", err)
+	}
+	blob[63] ^= 0x01
+	if err := VerifyBlobProof(blob, commitment, proof); err == nil {
+		t.Fatal("verified KZG proof for modified blob")
+	}
+}
+
+func TestCalcBlobHashV1(t *testing.T) {
+	var commitment Commitment
+	vh := CalcBlobHashV1(NewHasher(), &commitment)
+	if !IsValidVersionedHash(vh[:]) {
+		t.Fatalf("invalid versioned hash: %x", vh)
+	}
+}
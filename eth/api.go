@@ -26,16 +26,21 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/backup"
+	"github.com/ethereum/go-ethereum/core/integrity"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
@@ -134,6 +139,41 @@ func (api *MinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
 
+// SendBundleArgs represents the arguments to submit a bundle of transactions.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp      *hexutil.Uint64 `json:"minTimestamp"`
+	MaxTimestamp      *hexutil.Uint64 `json:"maxTimestamp"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundle submits a bundle of signed transactions, to be included atomically
+// and in order at the top of the target block, and returns the bundle hash.
+//
+// Every pending bundle is simulated while building blocks, so submission is only
+// exposed in the miner namespace, which operators have to enable explicitly.
+func (api *MinerAPI) SendBundle(args SendBundleArgs) (common.Hash, error) {
+	bundle := &miner.Bundle{
+		BlockNumber:       uint64(args.BlockNumber),
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+	for i, input := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(input); err != nil {
+			return common.Hash{}, fmt.Errorf("tx %d: %w", i, err)
+		}
+		bundle.Txs = append(bundle.Txs, tx)
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = uint64(*args.MinTimestamp)
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*args.MaxTimestamp)
+	}
+	return api.e.Miner().AddBundle(bundle)
+}
+
 // AdminAPI is the collection of Ethereum full node related APIs for node
 // administration.
 type AdminAPI struct {
@@ -243,6 +283,29 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// Backup takes a consistent checkpoint of the chain database while the node is
+// running and stores it as a new backup in the given directory. Unless full is
+// set, only the data changed since the latest backup in the directory is stored.
+func (api *AdminAPI) Backup(dir string, full *bool) (*backup.Manifest, error) {
+	if !atomic.CompareAndSwapInt32(&api.eth.backupRunning, 0, 1) {
+		return nil, errors.New("backup already in progress")
+	}
+	defer atomic.StoreInt32(&api.eth.backupRunning, 0)
+
+	return backup.Backup(api.eth.ChainDb(), dir, full != nil && *full)
+}
+
+// RecompressFreezer rewrites a table of the chain freezer with the given codec
+// while the node keeps running. If dictSize is set, a zstd dictionary of that
+// size is sampled from the table.
+func (api *AdminAPI) RecompressFreezer(table string, codec string, dictSize *int) error {
+	var size int
+	if dictSize != nil {
+		size = *dictSize
+	}
+	return rawdb.RecompressAncientTable(api.eth.ChainDb(), table, codec, nil, size)
+}
+
 // DebugAPI is the collection of Ethereum full node APIs for debugging the
 // protocol.
 type DebugAPI struct {
@@ -254,6 +317,21 @@ func NewDebugAPI(eth *Ethereum) *DebugAPI {
 	return &DebugAPI{eth: eth}
 }
 
+// EvmProfile returns the per-opcode, per-contract and per-precompile execution
+// statistics of block processing, aggregated since the node started or since
+// the last reset. If reset is set, the statistics are discarded afterwards.
+func (api *DebugAPI) EvmProfile(reset *bool) (*vm.Profile, error) {
+	profiler := api.eth.blockchain.GetVMConfig().Profiler
+	if profiler == nil {
+		return nil, errors.New("EVM profiling is disabled, enable it with --vmprofile")
+	}
+	profile := profiler.Profile()
+	if reset != nil && *reset {
+		profiler.Reset()
+	}
+	return profile, nil
+}
+
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *DebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
 	opts := &state.DumpConfig{
@@ -590,3 +668,59 @@ func (api *DebugAPI) GetAccessibleState(from, to rpc.BlockNumber) (uint64, error
 	}
 	return 0, errors.New("no state found")
 }
+
+// defaultVerifyThrottle is the pause inserted after every batch of items checked
+// by a background database integrity check, unless configured otherwise.
+const defaultVerifyThrottle = 10 * time.Millisecond
+
+// VerifyDatabaseConfig are the options of a background database integrity check.
+type VerifyDatabaseConfig struct {
+	Roots         *int    `json:"roots"`         // Number of recent state roots to verify
+	SkipFreezer   bool    `json:"skipFreezer"`   // Whether to skip the ancient store checks
+	SkipCanonical bool    `json:"skipCanonical"` // Whether to skip the canonical chain checks
+	Throttle      *string `json:"throttle"`      // Pause after every batch of checked items
+	MaxIssues     *int    `json:"maxIssues"`     // Maximum number of issues in the report
+}
+
+// VerifyDatabase starts a throttled database integrity check in the background.
+// The progress and the outcome can be retrieved via DatabaseVerificationReport.
+func (api *DebugAPI) VerifyDatabase(config *VerifyDatabaseConfig) error {
+	cfg := integrity.DefaultConfig
+	cfg.Throttle = defaultVerifyThrottle
+	if config != nil {
+		if config.Roots != nil {
+			cfg.Roots = *config.Roots
+		}
+		if config.MaxIssues != nil {
+			cfg.MaxIssues = *config.MaxIssues
+		}
+		if config.Throttle != nil {
+			throttle, err := time.ParseDuration(*config.Throttle)
+			if err != nil {
+				return err
+			}
+			cfg.Throttle = throttle
+		}
+		cfg.SkipFreezer = config.SkipFreezer
+		cfg.SkipCanonical = config.SkipCanonical
+	}
+	return api.eth.startVerification(cfg)
+}
+
+// DatabaseVerificationReport returns the report of the most recent database
+// integrity check, which might still be running.
+func (api *DebugAPI) DatabaseVerificationReport() (*integrity.Report, error) {
+	api.eth.verifyLock.Lock()
+	defer api.eth.verifyLock.Unlock()
+
+	if api.eth.verifier == nil {
+		return nil, errors.New("no database verification started")
+	}
+	return api.eth.verifier.Report(), nil
+}
+
+// StopDatabaseVerification aborts the running database integrity check. It
+// returns whether there was a check to abort.
+func (api *DebugAPI) StopDatabaseVerification() bool {
+	return api.eth.stopVerification()
+}
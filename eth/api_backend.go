@@ -248,9 +248,23 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if b.eth.readonly {
+		return errReadOnly
+	}
 	return b.eth.txPool.AddLocal(signedTx)
 }
 
+func (b *EthAPIBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction, maxBlock uint64) error {
+	if b.eth.readonly {
+		return errReadOnly
+	}
+	return b.eth.txPool.AddPrivate(signedTx, maxBlock)
+}
+
+func (b *EthAPIBackend) PrivateTxDeadline(txHash common.Hash) (uint64, bool) {
+	return b.eth.txPool.PrivateDeadline(txHash)
+}
+
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending := b.eth.txPool.Pending(false)
 	var txs types.Transactions
@@ -260,6 +274,15 @@ func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	return txs, nil
 }
 
+func (b *EthAPIBackend) GetPublicPoolTransactions() (types.Transactions, error) {
+	pending := b.eth.txPool.PublicPending(false)
+	var txs types.Transactions
+	for _, batch := range pending {
+		txs = append(txs, batch...)
+	}
+	return txs, nil
+}
+
 func (b *EthAPIBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
 	return b.eth.txPool.Get(hash)
 }
@@ -293,6 +316,14 @@ func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.S
 	return b.eth.TxPool().SubscribeNewTxsEvent(ch)
 }
 
+func (b *EthAPIBackend) TxHistory(txHash common.Hash) []txpool.TxEvent {
+	return b.eth.TxPool().TxHistory(txHash)
+}
+
+func (b *EthAPIBackend) SubscribeTxEvents(ch chan<- txpool.TxEvent) event.Subscription {
+	return b.eth.TxPool().SubscribeTxEvents(ch)
+}
+
 func (b *EthAPIBackend) SyncProgress() ethereum.SyncProgress {
 	return b.eth.Downloader().Progress()
 }
@@ -305,6 +336,10 @@ func (b *EthAPIBackend) FeeHistory(ctx context.Context, blockCount int, lastBloc
 	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
 }
 
+func (b *EthAPIBackend) FeeEstimate(ctx context.Context, blocks int, strategy string) (*gasprice.FeeEstimate, error) {
+	return b.gpo.FeeEstimate(ctx, blocks, strategy)
+}
+
 func (b *EthAPIBackend) ChainDb() ethdb.Database {
 	return b.eth.ChainDb()
 }
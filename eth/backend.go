@@ -18,12 +18,14 @@
 package eth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -33,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/integrity"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/txpool"
@@ -58,6 +61,10 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// errReadOnly is returned if an operation modifying the chain or the transaction
+// pool is attempted on a read-only instance.
+var errReadOnly = errors.New("read-only instance")
+
 // Config contains the configuration options of the ETH protocol.
 // Deprecated: use ethconfig.Config instead.
 type Config = ethconfig.Config
@@ -99,6 +106,17 @@ type Ethereum struct {
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
 
 	shutdownTracker *shutdowncheck.ShutdownTracker // Tracks if and when the node has shutdown ungracefully
+
+	verifyLock   sync.Mutex         // Protects the database integrity check fields
+	verifier     *integrity.Checker // Most recently started database integrity check
+	verifyCancel context.CancelFunc // Aborts the running database integrity check
+	verifyDone   chan struct{}      // Closed when the running integrity check terminates
+
+	backupRunning int32 // Flag whether a database backup is in progress (atomic)
+
+	readonly      bool          // Flag whether the node follows the database of a primary instance
+	refresh       time.Duration // Interval of catching up with the primary instance
+	closeFollower chan struct{}
 }
 
 // New creates a new Ethereum object (including the
@@ -127,12 +145,23 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	log.Info("Allocated trie memory caches", "clean", common.StorageSize(config.TrieCleanCache)*1024*1024, "dirty", common.StorageSize(config.TrieDirtyCache)*1024*1024)
 
 	// Assemble the Ethereum object
-	chainDb, err := stack.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", false)
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
+	if config.DatabaseFreezerRemote != "" {
+		chainDb, err = stack.OpenDatabaseWithRemoteFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezerRemote, "eth/db/chaindata/", false)
+	} else {
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", false)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb, stack.ResolvePath(config.TrieCleanCacheJournal)); err != nil {
-		log.Error("Failed to recover state", "error", err)
+	readonly := stack.Config().ReadOnly
+	if !readonly {
+		if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb, stack.ResolvePath(config.TrieCleanCacheJournal)); err != nil {
+			log.Error("Failed to recover state", "error", err)
+		}
 	}
 	// Transfer mining-related config to the ethash config.
 	ethashConfig := config.Ethash
@@ -158,6 +187,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		bloomIndexer:      core.NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms),
 		p2pServer:         stack.Server(),
 		shutdownTracker:   shutdowncheck.NewShutdownTracker(chainDb),
+		readonly:          readonly,
+		refresh:           stack.Config().ReadOnlyRefresh,
+		closeFollower:     make(chan struct{}),
 	}
 
 	bcVersion := rawdb.ReadDatabaseVersion(chainDb)
@@ -171,6 +203,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		if bcVersion != nil && *bcVersion > core.BlockChainVersion {
 			return nil, fmt.Errorf("database version is v%d, Geth %s only supports v%d", *bcVersion, params.VersionWithMeta, core.BlockChainVersion)
 		} else if bcVersion == nil || *bcVersion < core.BlockChainVersion {
+			if readonly {
+				return nil, fmt.Errorf("database version is v%s, read-only instances require v%d", dbVer, core.BlockChainVersion)
+			}
 			if bcVersion != nil { // only print warning on upgrade, not on init
 				log.Warn("Upgrade blockchain database version", "from", dbVer, "to", core.BlockChainVersion)
 			}
@@ -180,6 +215,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	var (
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
+			EnableProgram:           config.EnableEVMPrograms,
 		}
 		cacheConfig = &core.CacheConfig{
 			TrieCleanLimit:      config.TrieCleanCache,
@@ -191,8 +227,12 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			ParallelExecution:   config.ParallelExecution,
 		}
 	)
+	if config.EnableEVMProfiling {
+		vmConfig.Profiler = vm.NewProfiler()
+	}
 	// Override the chain config with provided settings.
 	var overrides core.ChainOverrides
 	if config.OverrideTerminalTotalDifficulty != nil {
@@ -201,16 +241,33 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.OverrideTerminalTotalDifficultyPassed != nil {
 		overrides.OverrideTerminalTotalDifficultyPassed = config.OverrideTerminalTotalDifficultyPassed
 	}
-	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, config.Genesis, &overrides, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
-	if err != nil {
-		return nil, err
+	if config.OverrideShanghai != nil {
+		overrides.OverrideShanghai = config.OverrideShanghai
+	}
+	if readonly {
+		// Read-only instances follow the chain of the primary, which owns the
+		// chain configuration, the bloom bits and the transaction journal.
+		eth.blockchain, err = core.NewReadOnlyBlockChain(chainDb, cacheConfig, eth.engine, vmConfig)
+		if err != nil {
+			return nil, err
+		}
+		config.TxPool.Journal = ""
+		config.TxPool.FullJournal = ""
+	} else {
+		eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, config.Genesis, &overrides, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
+		if err != nil {
+			return nil, err
+		}
+		eth.bloomIndexer.Start(eth.blockchain)
 	}
-	eth.bloomIndexer.Start(eth.blockchain)
-
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
-	eth.txPool = txpool.NewTxPool(config.TxPool, eth.blockchain.Config(), eth.blockchain)
+	if config.TxPool.FullJournal != "" {
+		config.TxPool.FullJournal = stack.ResolvePath(config.TxPool.FullJournal)
+	}
+	legacyPool := txpool.NewLegacyPool(config.TxPool, eth.blockchain.Config(), eth.blockchain)
+	eth.txPool = txpool.New(eth.blockchain, []txpool.SubPool{legacyPool})
 
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
@@ -262,12 +319,15 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 
 	// Register the backend on the node
 	stack.RegisterAPIs(eth.APIs())
-	stack.RegisterProtocols(eth.Protocols())
+	if !readonly {
+		stack.RegisterProtocols(eth.Protocols())
+	}
 	stack.RegisterLifecycle(eth)
 
 	// Successful startup; push a marker and check previous unclean shutdowns.
-	eth.shutdownTracker.MarkStartup()
-
+	if !readonly {
+		eth.shutdownTracker.MarkStartup()
+	}
 	return eth, nil
 }
 
@@ -414,6 +474,9 @@ func (s *Ethereum) SetEtherbase(etherbase common.Address) {
 // is already running, this method adjust the number of threads allowed to use
 // and updates the minimum price required by the transaction pool.
 func (s *Ethereum) StartMining(threads int) error {
+	if s.readonly {
+		return errReadOnly
+	}
 	// Update the thread count within the consensus engine
 	type threaded interface {
 		SetThreads(threads int)
@@ -512,11 +575,16 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 // Start implements node.Lifecycle, starting all internal goroutines needed by the
 // Ethereum protocol implementation.
 func (s *Ethereum) Start() error {
-	eth.StartENRUpdater(s.blockchain, s.p2pServer.LocalNode())
-
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
 
+	// Read-only instances are not part of the network, only follow the primary
+	if s.readonly {
+		go s.followPrimary()
+		return nil
+	}
+	eth.StartENRUpdater(s.blockchain, s.p2pServer.LocalNode())
+
 	// Regularly update shutdown marker
 	s.shutdownTracker.Start()
 
@@ -539,21 +607,96 @@ func (s *Ethereum) Stop() error {
 	// Stop all the peer-related stuff first.
 	s.ethDialCandidates.Close()
 	s.snapDialCandidates.Close()
-	s.handler.Stop()
+	if s.readonly {
+		close(s.closeFollower)
+	} else {
+		s.handler.Stop()
+	}
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
+	s.stopVerification()
 	s.txPool.Stop()
 	s.miner.Close()
 	s.blockchain.Stop()
 	s.engine.Close()
 
 	// Clean shutdown marker as the last thing before closing db
-	s.shutdownTracker.Stop()
+	if !s.readonly {
+		s.shutdownTracker.Stop()
+	}
 
 	s.chainDb.Close()
 	s.eventMux.Stop()
 
 	return nil
 }
+
+// followPrimary periodically reloads the chain head written by the primary
+// instance into the database.
+func (s *Ethereum) followPrimary() {
+	refresh := s.refresh
+	if refresh <= 0 {
+		refresh = node.DefaultReadOnlyRefresh
+	}
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.blockchain.ReloadHead(); err != nil {
+				log.Warn("Failed to follow primary chain head", "err", err)
+			}
+		case <-s.closeFollower:
+			return
+		}
+	}
+}
+
+// startVerification starts a database integrity check in the background, unless
+// one is already running.
+func (s *Ethereum) startVerification(config integrity.Config) error {
+	s.verifyLock.Lock()
+	defer s.verifyLock.Unlock()
+
+	if s.verifyDone != nil {
+		select {
+		case <-s.verifyDone:
+		default:
+			return errors.New("database verification already running")
+		}
+	}
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		done        = make(chan struct{})
+		checker     = integrity.New(s.chainDb, s.blockchain.StateCache().TrieDB(), s.blockchain.Snapshots(), config)
+	)
+	s.verifier, s.verifyCancel, s.verifyDone = checker, cancel, done
+
+	go func() {
+		defer close(done)
+		checker.Run(ctx)
+	}()
+	return nil
+}
+
+// stopVerification aborts the running database integrity check, if any, and
+// waits for it to terminate. It returns whether there was a check to abort.
+func (s *Ethereum) stopVerification() bool {
+	s.verifyLock.Lock()
+	defer s.verifyLock.Unlock()
+
+	if s.verifyDone == nil {
+		return false
+	}
+	select {
+	case <-s.verifyDone:
+		return false
+	default:
+	}
+	s.verifyCancel()
+	<-s.verifyDone
+	return true
+}
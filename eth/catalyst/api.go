@@ -34,7 +34,9 @@ import (
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -126,6 +128,15 @@ type ConsensusAPI struct {
 // NewConsensusAPI creates a new consensus api for the given backend.
 // The underlying blockchain needs to have a valid terminal total difficulty set.
 func NewConsensusAPI(eth *eth.Ethereum) *ConsensusAPI {
+	api := newConsensusAPIWithoutHeartbeat(eth)
+	go api.heartbeat()
+	return api
+}
+
+// newConsensusAPIWithoutHeartbeat creates a new consensus api for the given
+// backend, without warning about an absent beacon client. It's used when the
+// node drives the chain itself.
+func newConsensusAPIWithoutHeartbeat(eth *eth.Ethereum) *ConsensusAPI {
 	if eth.BlockChain().Config().TerminalTotalDifficulty == nil {
 		log.Warn("Engine API started but chain not configured for merge yet")
 	}
@@ -137,8 +148,6 @@ func NewConsensusAPI(eth *eth.Ethereum) *ConsensusAPI {
 		invalidTipsets:    make(map[common.Hash]*types.Header),
 	}
 	eth.Downloader().SetBadBlockCallback(api.setInvalidAncestor)
-	go api.heartbeat()
-
 	return api
 }
 
@@ -156,7 +165,39 @@ func NewConsensusAPI(eth *eth.Ethereum) *ConsensusAPI {
 //
 // If there are payloadAttributes: we try to assemble a block with the payloadAttributes
 // and return its payloadID.
-func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributesV1) (beacon.ForkChoiceResponse, error) {
+func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributes) (beacon.ForkChoiceResponse, error) {
+	if payloadAttributes != nil && payloadAttributes.Withdrawals != nil {
+		return beacon.STATUS_INVALID, beacon.InvalidParams.With(errors.New("withdrawals not supported in V1"))
+	}
+	return api.forkchoiceUpdated(update, payloadAttributes)
+}
+
+// ForkchoiceUpdatedV2 is equivalent to V1 with the addition of withdrawals in
+// the payload attributes, which must be present after and only after Shanghai.
+func (api *ConsensusAPI) ForkchoiceUpdatedV2(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributes) (beacon.ForkChoiceResponse, error) {
+	if payloadAttributes != nil {
+		if err := api.verifyPayloadAttributes(payloadAttributes); err != nil {
+			return beacon.STATUS_INVALID, beacon.InvalidParams.With(err)
+		}
+	}
+	return api.forkchoiceUpdated(update, payloadAttributes)
+}
+
+// verifyPayloadAttributes checks that the withdrawals in the given payload
+// attributes match the fork active at the requested timestamp.
+func (api *ConsensusAPI) verifyPayloadAttributes(attr *beacon.PayloadAttributes) error {
+	config := api.eth.BlockChain().Config()
+	if config.IsShanghai(api.eth.BlockChain().CurrentBlock().Number(), attr.Timestamp) {
+		if attr.Withdrawals == nil {
+			return errors.New("missing withdrawals list")
+		}
+	} else if attr.Withdrawals != nil {
+		return errors.New("withdrawals before shanghai")
+	}
+	return nil
+}
+
+func (api *ConsensusAPI) forkchoiceUpdated(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributes) (beacon.ForkChoiceResponse, error) {
 	api.forkchoiceLock.Lock()
 	defer api.forkchoiceLock.Unlock()
 
@@ -278,24 +319,26 @@ func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, pa
 		api.eth.BlockChain().SetSafe(safeBlock)
 	}
 	// If payload generation was requested, create a new block to be potentially
-	// sealed by the beacon client. The payload will be requested later, and we
-	// might replace it arbitrarily many times in between.
+	// sealed by the beacon client. The payload will be requested later, and it
+	// is improved continuously in between as new transactions arrive.
 	if payloadAttributes != nil {
-		// Create an empty block first which can be used as a fallback
-		empty, err := api.eth.Miner().GetSealingBlockSync(update.HeadBlockHash, payloadAttributes.Timestamp, payloadAttributes.SuggestedFeeRecipient, payloadAttributes.Random, true)
-		if err != nil {
-			log.Error("Failed to create empty sealing payload", "err", err)
-			return valid(nil), beacon.InvalidPayloadAttributes.With(err)
+		id := computePayloadId(update.HeadBlockHash, payloadAttributes)
+		if api.localBlocks.has(id) {
+			// Payload already being built, keep improving the tracked one
+			return valid(&id), nil
 		}
-		// Send a request to generate a full block in the background.
-		// The result can be obtained via the returned channel.
-		resCh, err := api.eth.Miner().GetSealingBlockAsync(update.HeadBlockHash, payloadAttributes.Timestamp, payloadAttributes.SuggestedFeeRecipient, payloadAttributes.Random, false)
+		payload, err := api.eth.Miner().BuildPayload(&miner.BuildPayloadArgs{
+			Parent:       update.HeadBlockHash,
+			Timestamp:    payloadAttributes.Timestamp,
+			FeeRecipient: payloadAttributes.SuggestedFeeRecipient,
+			Random:       payloadAttributes.Random,
+			Withdrawals:  payloadAttributes.Withdrawals,
+		})
 		if err != nil {
-			log.Error("Failed to create async sealing payload", "err", err)
+			log.Error("Failed to build payload", "err", err)
 			return valid(nil), beacon.InvalidPayloadAttributes.With(err)
 		}
-		id := computePayloadId(update.HeadBlockHash, payloadAttributes)
-		api.localBlocks.put(id, &payload{empty: empty, result: resCh})
+		api.localBlocks.put(id, payload)
 		return valid(&id), nil
 	}
 	return valid(nil), nil
@@ -332,9 +375,26 @@ func (api *ConsensusAPI) ExchangeTransitionConfigurationV1(config beacon.Transit
 }
 
 // GetPayloadV1 returns a cached payload by id.
-func (api *ConsensusAPI) GetPayloadV1(payloadID beacon.PayloadID) (*beacon.ExecutableDataV1, error) {
+func (api *ConsensusAPI) GetPayloadV1(payloadID beacon.PayloadID) (*beacon.ExecutableData, error) {
+	data, err := api.getPayload(payloadID, false)
+	if err != nil {
+		return nil, err
+	}
+	return data.ExecutionPayload, nil
+}
+
+// GetPayloadV2 returns a cached payload by id, along with the value it delivers
+// to the fee recipient.
+func (api *ConsensusAPI) GetPayloadV2(payloadID beacon.PayloadID) (*beacon.ExecutionPayloadEnvelope, error) {
+	return api.getPayload(payloadID, false)
+}
+
+// getPayload returns a cached payload by id. If full is set, it waits for the
+// block including transactions to be built instead of falling back to the
+// empty one.
+func (api *ConsensusAPI) getPayload(payloadID beacon.PayloadID, full bool) (*beacon.ExecutionPayloadEnvelope, error) {
 	log.Trace("Engine API request received", "method", "GetPayload", "id", payloadID)
-	data := api.localBlocks.get(payloadID)
+	data := api.localBlocks.get(payloadID, full)
 	if data == nil {
 		return nil, beacon.UnknownPayload
 	}
@@ -342,7 +402,42 @@ func (api *ConsensusAPI) GetPayloadV1(payloadID beacon.PayloadID) (*beacon.Execu
 }
 
 // NewPayloadV1 creates an Eth1 block, inserts it in the chain, and returns the status of the chain.
-func (api *ConsensusAPI) NewPayloadV1(params beacon.ExecutableDataV1) (beacon.PayloadStatusV1, error) {
+func (api *ConsensusAPI) NewPayloadV1(params beacon.ExecutableData) (beacon.PayloadStatusV1, error) {
+	if params.Withdrawals != nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("withdrawals not supported in V1"))
+	}
+	if params.BlobGasUsed != nil || params.ExcessBlobGas != nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("blob gas fields not supported in V1"))
+	}
+	return api.newPayload(params)
+}
+
+// NewPayloadV2 is equivalent to V1 with the addition of withdrawals in the
+// payload, which must be present after and only after Shanghai, and of the
+// blob gas fields, which must be present after and only after Cancun.
+func (api *ConsensusAPI) NewPayloadV2(params beacon.ExecutableData) (beacon.PayloadStatusV1, error) {
+	var (
+		config = api.eth.BlockChain().Config()
+		number = new(big.Int).SetUint64(params.Number)
+	)
+	if config.IsShanghai(number, params.Timestamp) {
+		if params.Withdrawals == nil {
+			return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("nil withdrawals post-shanghai"))
+		}
+	} else if params.Withdrawals != nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("non-nil withdrawals pre-shanghai"))
+	}
+	if config.IsCancun(number, params.Timestamp) {
+		if params.BlobGasUsed == nil || params.ExcessBlobGas == nil {
+			return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("nil blob gas fields post-cancun"))
+		}
+	} else if params.BlobGasUsed != nil || params.ExcessBlobGas != nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("non-nil blob gas fields pre-cancun"))
+	}
+	return api.newPayload(params)
+}
+
+func (api *ConsensusAPI) newPayload(params beacon.ExecutableData) (beacon.PayloadStatusV1, error) {
 	// The locking here is, strictly, not required. Without these locks, this can happen:
 	//
 	// 1. NewPayload( execdata-N ) is invoked from the CL. It goes all the way down to
@@ -445,13 +540,14 @@ func (api *ConsensusAPI) NewPayloadV1(params beacon.ExecutableDataV1) (beacon.Pa
 }
 
 // computePayloadId computes a pseudo-random payloadid, based on the parameters.
-func computePayloadId(headBlockHash common.Hash, params *beacon.PayloadAttributesV1) beacon.PayloadID {
+func computePayloadId(headBlockHash common.Hash, params *beacon.PayloadAttributes) beacon.PayloadID {
 	// Hash
 	hasher := sha256.New()
 	hasher.Write(headBlockHash[:])
 	binary.Write(hasher, binary.BigEndian, params.Timestamp)
 	hasher.Write(params.Random[:])
 	hasher.Write(params.SuggestedFeeRecipient[:])
+	rlp.Encode(hasher, params.Withdrawals)
 	var out beacon.PayloadID
 	copy(out[:], hasher.Sum(nil)[:8])
 	return out
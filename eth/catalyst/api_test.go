@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/beacon"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -89,7 +90,7 @@ func TestEth2AssembleBlock(t *testing.T) {
 		t.Fatalf("error signing transaction, err=%v", err)
 	}
 	ethservice.TxPool().AddLocal(tx)
-	blockParams := beacon.PayloadAttributesV1{
+	blockParams := beacon.PayloadAttributes{
 		Timestamp: blocks[9].Time() + 5,
 	}
 	// This test is a bit time-sensitive, the miner needs to pick up on the
@@ -119,7 +120,7 @@ func TestEth2AssembleBlockWithAnotherBlocksTxs(t *testing.T) {
 
 	// Put the 10th block's tx in the pool and produce a new block
 	api.eth.TxPool().AddRemotesSync(blocks[9].Transactions())
-	blockParams := beacon.PayloadAttributesV1{
+	blockParams := beacon.PayloadAttributes{
 		Timestamp: blocks[8].Time() + 5,
 	}
 	// This test is a bit time-sensitive, the miner needs to pick up on the
@@ -169,7 +170,7 @@ func TestEth2PrepareAndGetPayload(t *testing.T) {
 
 	// Put the 10th block's tx in the pool and produce a new block
 	ethservice.TxPool().AddLocals(blocks[9].Transactions())
-	blockParams := beacon.PayloadAttributesV1{
+	blockParams := beacon.PayloadAttributes{
 		Timestamp: blocks[8].Time() + 5,
 	}
 	fcState := beacon.ForkchoiceStateV1{
@@ -241,7 +242,7 @@ func TestInvalidPayloadTimestamp(t *testing.T) {
 
 	for i, test := range tests {
 		t.Run(fmt.Sprintf("Timestamp test: %v", i), func(t *testing.T) {
-			params := beacon.PayloadAttributesV1{
+			params := beacon.PayloadAttributes{
 				Timestamp:             test.time,
 				Random:                crypto.Keccak256Hash([]byte{byte(123)}),
 				SuggestedFeeRecipient: parent.Coinbase(),
@@ -285,7 +286,7 @@ func TestEth2NewBlock(t *testing.T) {
 		tx, _ := types.SignTx(types.NewContractCreation(nonce, new(big.Int), 1000000, big.NewInt(2*params.InitialBaseFee), logCode), types.LatestSigner(ethservice.BlockChain().Config()), testKey)
 		ethservice.TxPool().AddLocal(tx)
 
-		execData, err := assembleBlock(api, parent.Hash(), &beacon.PayloadAttributesV1{
+		execData, err := assembleBlock(api, parent.Hash(), &beacon.PayloadAttributes{
 			Timestamp: parent.Time() + 5,
 		})
 		if err != nil {
@@ -327,7 +328,7 @@ func TestEth2NewBlock(t *testing.T) {
 	)
 	parent = preMergeBlocks[len(preMergeBlocks)-1]
 	for i := 0; i < 10; i++ {
-		execData, err := assembleBlock(api, parent.Hash(), &beacon.PayloadAttributesV1{
+		execData, err := assembleBlock(api, parent.Hash(), &beacon.PayloadAttributes{
 			Timestamp: parent.Time() + 6,
 		})
 		if err != nil {
@@ -360,6 +361,131 @@ func TestEth2NewBlock(t *testing.T) {
 	}
 }
 
+// TestEth2ShanghaiTime checks that payloads built and imported through the
+// engine API activate a timestamp based fork by their own timestamp.
+func TestEth2ShanghaiTime(t *testing.T) {
+	genesis, preMergeBlocks := generatePreMergeChain(10)
+	parent := preMergeBlocks[len(preMergeBlocks)-1]
+	shanghaiTime := parent.Time() + 10
+	genesis.Config.ShanghaiTime = &shanghaiTime
+
+	n, ethservice := startEthService(t, genesis, preMergeBlocks)
+	defer n.Close()
+
+	// The init code of the transactions only succeeds if PUSH0 is available
+	api := NewConsensusAPI(ethservice)
+	for i, active := range []bool{false, true} {
+		statedb, _ := ethservice.BlockChain().StateAt(parent.Root())
+		nonce := statedb.GetNonce(testAddr)
+		tx, _ := types.SignTx(types.NewContractCreation(nonce, new(big.Int), 100000, big.NewInt(2*params.InitialBaseFee), []byte{byte(vm.PUSH0), byte(vm.PUSH0), byte(vm.RETURN)}), types.LatestSigner(ethservice.BlockChain().Config()), testKey)
+		ethservice.TxPool().AddLocal(tx)
+
+		attrs := &beacon.PayloadAttributes{
+			Timestamp: parent.Time() + 5,
+		}
+		if active {
+			attrs.Withdrawals = make([]*types.Withdrawal, 0)
+		}
+		execData, err := assembleBlock(api, parent.Hash(), attrs)
+		if err != nil {
+			t.Fatalf("block %d: failed to create the executable data %v", i, err)
+		}
+		if len(execData.Transactions) != 1 {
+			t.Fatalf("block %d: invalid number of transactions %d", i, len(execData.Transactions))
+		}
+		if resp, err := api.NewPayloadV2(*execData); err != nil || resp.Status != beacon.VALID {
+			t.Fatalf("block %d: failed to insert block: %v %v", i, resp.Status, err)
+		}
+		block := ethservice.BlockChain().GetBlockByHash(execData.BlockHash)
+		if have := ethservice.BlockChain().Config().IsShanghai(block.Number(), block.Time()); have != active {
+			t.Fatalf("block %d: Shanghai active %v, want %v", i, have, active)
+		}
+		receipts := ethservice.BlockChain().GetReceiptsByHash(block.Hash())
+		if have := receipts[0].Status == types.ReceiptStatusSuccessful; have != active {
+			t.Fatalf("block %d: PUSH0 execution success %v, want %v", i, have, active)
+		}
+		fcState := beacon.ForkchoiceStateV1{HeadBlockHash: block.Hash()}
+		if _, err := api.ForkchoiceUpdatedV2(fcState, nil); err != nil {
+			t.Fatalf("block %d: failed to set head: %v", i, err)
+		}
+		parent = block
+	}
+}
+
+// TestEth2Withdrawals checks that withdrawals requested through the V2 engine
+// API are included in the built payload and credited on import, and that the
+// V1 methods and pre-Shanghai payloads reject them.
+func TestEth2Withdrawals(t *testing.T) {
+	genesis, preMergeBlocks := generatePreMergeChain(10)
+	parent := preMergeBlocks[len(preMergeBlocks)-1]
+	shanghaiTime := parent.Time() + 10
+	genesis.Config.ShanghaiTime = &shanghaiTime
+
+	n, ethservice := startEthService(t, genesis, preMergeBlocks)
+	defer n.Close()
+
+	var (
+		api         = NewConsensusAPI(ethservice)
+		recipient   = common.Address{0xaa}
+		withdrawals = []*types.Withdrawal{
+			{Index: 0, Validator: 1, Address: recipient, Amount: 32},
+			{Index: 1, Validator: 2, Address: recipient, Amount: 10},
+		}
+		fcState = beacon.ForkchoiceStateV1{HeadBlockHash: parent.Hash()}
+	)
+	// Withdrawals are rejected before Shanghai and by the V1 methods
+	pre := &beacon.PayloadAttributes{Timestamp: parent.Time() + 5, Withdrawals: withdrawals}
+	if _, err := api.ForkchoiceUpdatedV2(fcState, pre); err == nil {
+		t.Fatal("expected error for withdrawals before shanghai")
+	}
+	post := &beacon.PayloadAttributes{Timestamp: shanghaiTime, Withdrawals: withdrawals}
+	if _, err := api.ForkchoiceUpdatedV1(fcState, post); err == nil {
+		t.Fatal("expected error for withdrawals in V1")
+	}
+	if _, err := api.ForkchoiceUpdatedV2(fcState, &beacon.PayloadAttributes{Timestamp: shanghaiTime}); err == nil {
+		t.Fatal("expected error for missing withdrawals after shanghai")
+	}
+	// Build a payload with withdrawals after Shanghai
+	resp, err := api.ForkchoiceUpdatedV2(fcState, post)
+	if err != nil {
+		t.Fatalf("error preparing payload, err=%v", err)
+	}
+	envelope, err := api.GetPayloadV2(*resp.PayloadID)
+	if err != nil {
+		t.Fatalf("error getting payload, err=%v", err)
+	}
+	if envelope.BlockValue == nil {
+		t.Fatal("missing block value")
+	}
+	execData := envelope.ExecutionPayload
+	if len(execData.Withdrawals) != len(withdrawals) {
+		t.Fatalf("withdrawal count mismatch: have %d, want %d", len(execData.Withdrawals), len(withdrawals))
+	}
+	if _, err := api.NewPayloadV1(*execData); err == nil {
+		t.Fatal("expected error for withdrawals in V1")
+	}
+	if resp, err := api.NewPayloadV2(*execData); err != nil || resp.Status != beacon.VALID {
+		t.Fatalf("failed to insert block: %v %v", resp.Status, err)
+	}
+	fcState = beacon.ForkchoiceStateV1{HeadBlockHash: execData.BlockHash}
+	if _, err := api.ForkchoiceUpdatedV2(fcState, nil); err != nil {
+		t.Fatalf("failed to set head: %v", err)
+	}
+	// Check the withdrawals were credited to the recipient
+	block := ethservice.BlockChain().CurrentBlock()
+	if block.Hash() != execData.BlockHash {
+		t.Fatalf("chain head mismatch: have %x, want %x", block.Hash(), execData.BlockHash)
+	}
+	if have := len(block.Withdrawals()); have != len(withdrawals) {
+		t.Fatalf("stored withdrawal count mismatch: have %d, want %d", have, len(withdrawals))
+	}
+	statedb, _ := ethservice.BlockChain().StateAt(block.Root())
+	want := new(big.Int).Mul(big.NewInt(42), big.NewInt(params.GWei))
+	if have := statedb.GetBalance(recipient); have.Cmp(want) != 0 {
+		t.Fatalf("recipient balance mismatch: have %v, want %v", have, want)
+	}
+}
+
 func TestEth2DeepReorg(t *testing.T) {
 	// TODO (MariusVanDerWijden) TestEth2DeepReorg is currently broken, because it tries to reorg
 	// before the totalTerminalDifficulty threshold
@@ -568,7 +694,7 @@ func TestNewPayloadOnInvalidChain(t *testing.T) {
 		tx, _ := types.SignTx(types.NewContractCreation(nonce, new(big.Int), 1000000, big.NewInt(2*params.InitialBaseFee), logCode), types.LatestSigner(ethservice.BlockChain().Config()), testKey)
 		ethservice.TxPool().AddLocal(tx)
 
-		params := beacon.PayloadAttributesV1{
+		params := beacon.PayloadAttributes{
 			Timestamp:             parent.Time() + 1,
 			Random:                crypto.Keccak256Hash([]byte{byte(i)}),
 			SuggestedFeeRecipient: parent.Coinbase(),
@@ -617,8 +743,8 @@ func TestNewPayloadOnInvalidChain(t *testing.T) {
 	}
 }
 
-func assembleBlock(api *ConsensusAPI, parentHash common.Hash, params *beacon.PayloadAttributesV1) (*beacon.ExecutableDataV1, error) {
-	block, err := api.eth.Miner().GetSealingBlockSync(parentHash, params.Timestamp, params.SuggestedFeeRecipient, params.Random, false)
+func assembleBlock(api *ConsensusAPI, parentHash common.Hash, params *beacon.PayloadAttributes) (*beacon.ExecutableData, error) {
+	block, _, err := api.eth.Miner().GetSealingBlockSync(parentHash, params.Timestamp, params.SuggestedFeeRecipient, params.Random, params.Withdrawals, false)
 	if err != nil {
 		return nil, err
 	}
@@ -685,8 +811,8 @@ func TestEmptyBlocks(t *testing.T) {
 	}
 }
 
-func getNewPayload(t *testing.T, api *ConsensusAPI, parent *types.Block) *beacon.ExecutableDataV1 {
-	params := beacon.PayloadAttributesV1{
+func getNewPayload(t *testing.T, api *ConsensusAPI, parent *types.Block) *beacon.ExecutableData {
+	params := beacon.PayloadAttributes{
 		Timestamp:             parent.Time() + 1,
 		Random:                crypto.Keccak256Hash([]byte{byte(1)}),
 		SuggestedFeeRecipient: parent.Coinbase(),
@@ -701,7 +827,7 @@ func getNewPayload(t *testing.T, api *ConsensusAPI, parent *types.Block) *beacon
 
 // setBlockhash sets the blockhash of a modified ExecutableData.
 // Can be used to make modified payloads look valid.
-func setBlockhash(data *beacon.ExecutableDataV1) *beacon.ExecutableDataV1 {
+func setBlockhash(data *beacon.ExecutableData) *beacon.ExecutableData {
 	txs, _ := decodeTransactions(data.Transactions)
 	number := big.NewInt(0)
 	number.SetUint64(data.Number)
@@ -760,7 +886,7 @@ func TestTrickRemoteBlockCache(t *testing.T) {
 	setupBlocks(t, ethserviceA, 10, commonAncestor, func(parent *types.Block) {})
 	commonAncestor = ethserviceA.BlockChain().CurrentBlock()
 
-	var invalidChain []*beacon.ExecutableDataV1
+	var invalidChain []*beacon.ExecutableData
 	// create a valid payload (P1)
 	//payload1 := getNewPayload(t, apiA, commonAncestor)
 	//invalidChain = append(invalidChain, payload1)
@@ -854,12 +980,12 @@ func TestNewPayloadOnInvalidTerminalBlock(t *testing.T) {
 	}
 
 	// Test parent already post TTD in NewPayload
-	params := beacon.PayloadAttributesV1{
+	params := beacon.PayloadAttributes{
 		Timestamp:             parent.Time() + 1,
 		Random:                crypto.Keccak256Hash([]byte{byte(1)}),
 		SuggestedFeeRecipient: parent.Coinbase(),
 	}
-	empty, err := api.eth.Miner().GetSealingBlockSync(parent.Hash(), params.Timestamp, params.SuggestedFeeRecipient, params.Random, true)
+	empty, _, err := api.eth.Miner().GetSealingBlockSync(parent.Hash(), params.Timestamp, params.SuggestedFeeRecipient, params.Random, nil, true)
 	if err != nil {
 		t.Fatalf("error preparing payload, err=%v", err)
 	}
@@ -897,7 +1023,7 @@ func TestSimultaneousNewBlock(t *testing.T) {
 				Gas:       1000000,
 				To:        &common.Address{99},
 			}))
-		execData, err := assembleBlock(api, parent.Hash(), &beacon.PayloadAttributesV1{
+		execData, err := assembleBlock(api, parent.Hash(), &beacon.PayloadAttributes{
 			Timestamp: parent.Time() + 5,
 		})
 		if err != nil {
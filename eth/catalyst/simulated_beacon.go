@@ -0,0 +1,263 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// devEpochLength is the number of blocks of an epoch on the simulated beacon
+	// chain. The first block of the latest epoch is reported as finalized.
+	devEpochLength = 32
+
+	// maxWithdrawalsPerBlock is the maximum number of queued withdrawals
+	// included in a single block.
+	maxWithdrawalsPerBlock = 16
+
+	// txChanSize is the size of the channel listening to new transactions in
+	// the pool, to produce blocks on demand.
+	txChanSize = 256
+)
+
+// SimulatedBeacon drives a proof-of-stake chain without a consensus client, by
+// calling the Engine API of the node itself. Blocks are produced periodically,
+// or if the period is zero, whenever transactions arrive in the pool. Blocks
+// can also be produced on demand, e.g. by tests.
+type SimulatedBeacon struct {
+	eth       *eth.Ethereum
+	engineAPI *ConsensusAPI
+	period    uint64 // Block period in seconds, zero to produce blocks on transaction arrival
+
+	feeRecipient common.Address      // Fee recipient of the produced blocks
+	withdrawals  []*types.Withdrawal // Withdrawals queued for inclusion
+	timestamp    uint64              // Timestamp of the next block, zero for the current time
+	forkchoice   beacon.ForkchoiceStateV1
+	lock         sync.Mutex // Protects the fields above and serializes block production
+
+	shutdownCh chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewSimulatedBeacon creates a simulated beacon driving the chain of the given
+// node, which must be a proof-of-stake chain from genesis on.
+func NewSimulatedBeacon(period uint64, eth *eth.Ethereum) (*SimulatedBeacon, error) {
+	config := eth.BlockChain().Config()
+	if config.TerminalTotalDifficulty == nil || config.TerminalTotalDifficulty.Sign() != 0 {
+		return nil, errors.New("simulated beacon requires a chain merged from genesis")
+	}
+	feeRecipient, _ := eth.Etherbase()
+
+	c := &SimulatedBeacon{
+		eth:          eth,
+		engineAPI:    newConsensusAPIWithoutHeartbeat(eth),
+		period:       period,
+		feeRecipient: feeRecipient,
+		shutdownCh:   make(chan struct{}),
+	}
+	head := eth.BlockChain().CurrentBlock()
+	c.setForkchoice(head.Hash(), head.NumberU64())
+	return c, nil
+}
+
+// Start implements node.Lifecycle, launching the block production.
+func (c *SimulatedBeacon) Start() error {
+	c.wg.Add(1)
+	if c.period == 0 {
+		// Subscribe right away to not miss any transactions added after startup
+		txs := make(chan core.NewTxsEvent, txChanSize)
+		sub := c.eth.TxPool().SubscribeNewTxsEvent(txs)
+		go c.loopOnDemand(txs, sub)
+	} else {
+		go c.loop()
+	}
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the block production.
+func (c *SimulatedBeacon) Stop() error {
+	close(c.shutdownCh)
+	c.wg.Wait()
+	return nil
+}
+
+// loop produces a block every period.
+func (c *SimulatedBeacon) loop() {
+	defer c.wg.Done()
+
+	timer := time.NewTicker(time.Duration(c.period) * time.Second)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if _, err := c.Commit(); err != nil {
+				log.Warn("Failed to produce dev block", "err", err)
+			}
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// loopOnDemand produces a block whenever new transactions become executable.
+func (c *SimulatedBeacon) loopOnDemand(txs chan core.NewTxsEvent, sub event.Subscription) {
+	defer c.wg.Done()
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-txs:
+			// Include all the transactions arrived meanwhile in one block
+			for drained := false; !drained; {
+				select {
+				case <-txs:
+				default:
+					drained = true
+				}
+			}
+			if _, err := c.Commit(); err != nil {
+				log.Warn("Failed to produce dev block", "err", err)
+			}
+		case <-sub.Err():
+			return
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// Commit produces a block on top of the current head, including the pending
+// transactions and queued withdrawals, and returns its hash.
+func (c *SimulatedBeacon) Commit() (common.Hash, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Build on the current head, in case the chain was rewound since the last block
+	head := c.eth.BlockChain().CurrentBlock()
+	if c.forkchoice.HeadBlockHash != head.Hash() {
+		c.setForkchoice(head.Hash(), head.NumberU64())
+	}
+	timestamp := c.timestamp
+	if timestamp == 0 {
+		timestamp = uint64(time.Now().Unix())
+		if timestamp <= head.Time() {
+			timestamp = head.Time() + 1
+		}
+	}
+	withdrawals := c.withdrawals
+	if len(withdrawals) > maxWithdrawalsPerBlock {
+		withdrawals = withdrawals[:maxWithdrawalsPerBlock]
+	}
+	var random common.Hash
+	rand.Read(random[:])
+
+	// Request and retrieve a payload, waiting for the transactions to be included
+	res, err := c.engineAPI.ForkchoiceUpdatedV2(c.forkchoice, &beacon.PayloadAttributes{
+		Timestamp:             timestamp,
+		Random:                random,
+		SuggestedFeeRecipient: c.feeRecipient,
+		Withdrawals:           append([]*types.Withdrawal{}, withdrawals...),
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if res.PayloadStatus.Status != beacon.VALID || res.PayloadID == nil {
+		return common.Hash{}, fmt.Errorf("payload request rejected: %s", res.PayloadStatus.Status)
+	}
+	envelope, err := c.engineAPI.getPayload(*res.PayloadID, true)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	payload := envelope.ExecutionPayload
+
+	// Import the payload and make it the new head
+	status, err := c.engineAPI.NewPayloadV2(*payload)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if status.Status != beacon.VALID {
+		return common.Hash{}, fmt.Errorf("payload import rejected: %s", status.Status)
+	}
+	c.setForkchoice(payload.BlockHash, payload.Number)
+	if _, err := c.engineAPI.ForkchoiceUpdatedV2(c.forkchoice, nil); err != nil {
+		return common.Hash{}, err
+	}
+	c.withdrawals = c.withdrawals[len(withdrawals):]
+	c.timestamp = 0
+
+	log.Info("Produced dev block", "number", payload.Number, "hash", payload.BlockHash, "txs", len(payload.Transactions), "withdrawals", len(withdrawals))
+	return payload.BlockHash, nil
+}
+
+// setForkchoice sets the forkchoice state for the given head. The first block
+// of the head's epoch is used as the safe and finalized block. The caller must
+// hold the lock.
+func (c *SimulatedBeacon) setForkchoice(head common.Hash, number uint64) {
+	final := head
+	if epoch := number - number%devEpochLength; epoch != number {
+		if header := c.eth.BlockChain().GetHeaderByNumber(epoch); header != nil {
+			final = header.Hash()
+		}
+	}
+	c.forkchoice = beacon.ForkchoiceStateV1{
+		HeadBlockHash:      head,
+		SafeBlockHash:      final,
+		FinalizedBlockHash: final,
+	}
+}
+
+// SetTimestamp sets the timestamp of the next block, which must be after the
+// timestamp of the current head.
+func (c *SimulatedBeacon) SetTimestamp(timestamp uint64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if head := c.eth.BlockChain().CurrentBlock(); timestamp <= head.Time() {
+		return fmt.Errorf("timestamp %d not after head timestamp %d", timestamp, head.Time())
+	}
+	c.timestamp = timestamp
+	return nil
+}
+
+// AddWithdrawal queues a withdrawal for inclusion in the next blocks.
+func (c *SimulatedBeacon) AddWithdrawal(withdrawal *types.Withdrawal) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.withdrawals = append(c.withdrawals, withdrawal)
+}
+
+// SetFeeRecipient sets the fee recipient of the next blocks.
+func (c *SimulatedBeacon) SetFeeRecipient(feeRecipient common.Address) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.feeRecipient = feeRecipient
+}
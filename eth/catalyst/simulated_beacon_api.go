@@ -0,0 +1,61 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DevAPI controls the block production of a simulated beacon.
+type DevAPI struct {
+	sim *SimulatedBeacon
+}
+
+// Mine produces a block immediately and returns its hash.
+func (api *DevAPI) Mine() (common.Hash, error) {
+	return api.sim.Commit()
+}
+
+// SetTimestamp sets the timestamp of the next block.
+func (api *DevAPI) SetTimestamp(timestamp hexutil.Uint64) error {
+	return api.sim.SetTimestamp(uint64(timestamp))
+}
+
+// AddWithdrawal queues a withdrawal for inclusion in the next blocks.
+func (api *DevAPI) AddWithdrawal(withdrawal types.Withdrawal) {
+	api.sim.AddWithdrawal(&withdrawal)
+}
+
+// SetFeeRecipient sets the fee recipient of the next blocks.
+func (api *DevAPI) SetFeeRecipient(feeRecipient common.Address) {
+	api.sim.SetFeeRecipient(feeRecipient)
+}
+
+// RegisterSimulatedBeaconAPIs adds the dev API controlling the simulated beacon
+// to the node.
+func RegisterSimulatedBeaconAPIs(stack *node.Node, sim *SimulatedBeacon) {
+	stack.RegisterAPIs([]rpc.API{
+		{
+			Namespace: "dev",
+			Service:   &DevAPI{sim: sim},
+		},
+	})
+}
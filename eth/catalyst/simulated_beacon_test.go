@@ -0,0 +1,103 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the simulated beacon produces blocks as transactions arrive, and
+// on demand with the requested timestamp and withdrawals.
+func TestSimulatedBeacon(t *testing.T) {
+	genesis := core.DeveloperGenesisBlock(11_500_000, testAddr)
+	n, ethservice := startEthService(t, genesis, nil)
+	defer n.Close()
+
+	sim, err := NewSimulatedBeacon(0, ethservice)
+	if err != nil {
+		t.Fatalf("can't create simulated beacon: %v", err)
+	}
+	if err := sim.Start(); err != nil {
+		t.Fatalf("can't start simulated beacon: %v", err)
+	}
+	defer sim.Stop()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := ethservice.BlockChain().SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	// Submitted transactions are included right away.
+	signer := types.LatestSigner(genesis.Config)
+	tx := types.MustSignNewTx(testKey, signer, &types.DynamicFeeTx{
+		ChainID:   genesis.Config.ChainID,
+		To:        &common.Address{0x01},
+		Value:     big.NewInt(1),
+		Gas:       params.TxGas,
+		GasFeeCap: big.NewInt(2 * params.InitialBaseFee),
+		GasTipCap: big.NewInt(1),
+	})
+	if err := ethservice.TxPool().AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	select {
+	case ev := <-heads:
+		if txs := ev.Block.Transactions(); len(txs) != 1 || txs[0].Hash() != tx.Hash() {
+			t.Fatalf("transaction not included: %v", txs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no block produced for the transaction")
+	}
+	// Blocks produced on demand carry the requested timestamp and withdrawals.
+	head := ethservice.BlockChain().CurrentBlock()
+	if err := sim.SetTimestamp(head.Time()); err == nil {
+		t.Fatal("timestamp of the head accepted")
+	}
+	if err := sim.SetTimestamp(head.Time() + 100); err != nil {
+		t.Fatalf("failed to set timestamp: %v", err)
+	}
+	withdrawal := &types.Withdrawal{Index: 0, Validator: 1, Address: common.Address{0x02}, Amount: 10}
+	sim.AddWithdrawal(withdrawal)
+
+	hash, err := sim.Commit()
+	if err != nil {
+		t.Fatalf("failed to produce block: %v", err)
+	}
+	block := ethservice.BlockChain().CurrentBlock()
+	if block.Hash() != hash {
+		t.Fatalf("head mismatch: have %x, want %x", block.Hash(), hash)
+	}
+	if block.Time() != head.Time()+100 {
+		t.Errorf("timestamp mismatch: have %d, want %d", block.Time(), head.Time()+100)
+	}
+	if ws := block.Withdrawals(); len(ws) != 1 || ws[0].Address != withdrawal.Address {
+		t.Errorf("withdrawal not included: %v", ws)
+	}
+	state, _ := ethservice.BlockChain().State()
+	if have, want := state.GetBalance(withdrawal.Address), big.NewInt(10*params.GWei); have.Cmp(want) != 0 {
+		t.Errorf("withdrawal balance mismatch: have %v, want %v", have, want)
+	}
+	if final := ethservice.BlockChain().CurrentFinalizedBlock(); final == nil || final.NumberU64() != 0 {
+		t.Errorf("finalized block mismatch: have %v, want genesis", final)
+	}
+}
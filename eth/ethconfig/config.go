@@ -49,6 +49,7 @@ var FullNodeGPO = gasprice.Config{
 	MaxBlockHistory:  1024,
 	MaxPrice:         gasprice.DefaultMaxPrice,
 	IgnorePrice:      gasprice.DefaultIgnorePrice,
+	Estimator:        gasprice.PoolEstimator{}.Name(),
 }
 
 // LightClientGPO contains default gasprice oracle settings for light client.
@@ -59,6 +60,7 @@ var LightClientGPO = gasprice.Config{
 	MaxBlockHistory:  5,
 	MaxPrice:         gasprice.DefaultMaxPrice,
 	IgnorePrice:      gasprice.DefaultIgnorePrice,
+	Estimator:        gasprice.PercentileEstimator{}.Name(),
 }
 
 // Defaults contains default settings for use on the Ethereum main net.
@@ -134,6 +136,8 @@ type Config struct {
 	NoPruning  bool // Whether to disable pruning and flush everything to disk
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
+	ParallelExecution bool // Whether to execute the transactions of imported blocks speculatively in parallel
+
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
@@ -156,10 +160,11 @@ type Config struct {
 	UltraLightOnlyAnnounce bool     `toml:",omitempty"` // Whether to only announce headers, or also serve them
 
 	// Database options
-	SkipBcVersionCheck bool `toml:"-"`
-	DatabaseHandles    int  `toml:"-"`
-	DatabaseCache      int
-	DatabaseFreezer    string
+	SkipBcVersionCheck    bool `toml:"-"`
+	DatabaseHandles       int  `toml:"-"`
+	DatabaseCache         int
+	DatabaseFreezer       string
+	DatabaseFreezerRemote string `toml:",omitempty"` // RPC endpoint of a remote freezer server, overrides DatabaseFreezer
 
 	TrieCleanCache          int
 	TrieCleanCacheJournal   string        `toml:",omitempty"` // Disk journal directory for trie cache to survive node restarts
@@ -187,6 +192,12 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables aggregating execution statistics of block processing in the VM
+	EnableEVMProfiling bool
+
+	// Enables running frequently executed contract code as decoded programs
+	EnableEVMPrograms bool
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
@@ -211,6 +222,9 @@ type Config struct {
 
 	// OverrideTerminalTotalDifficultyPassed (TODO: remove after the fork)
 	OverrideTerminalTotalDifficultyPassed *bool `toml:",omitempty"`
+
+	// OverrideShanghai (TODO: remove after the fork)
+	OverrideShanghai *uint64 `toml:",omitempty"`
 }
 
 // CreateConsensusEngine creates a consensus engine for the given chain configuration.
@@ -0,0 +1,224 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Confidence is the likelihood of inclusion a fee estimate aims for.
+type Confidence int
+
+const (
+	Slow Confidence = iota
+	Standard
+	Fast
+
+	numConfidences = int(Fast) + 1
+)
+
+// confidenceParams are the parameters of the confidence levels.
+var confidenceParams = [numConfidences]struct {
+	name       string
+	percentile int    // Percentile of the recent tips to pay
+	blocks     uint64 // Number of blocks the transaction should be included within
+}{
+	Slow:     {"slow", 10, 10},
+	Standard: {"standard", 50, 3},
+	Fast:     {"fast", 90, 1},
+}
+
+// String implements fmt.Stringer.
+func (c Confidence) String() string {
+	if c < Slow || c > Fast {
+		return "unknown"
+	}
+	return confidenceParams[c].name
+}
+
+// Percentile returns the percentile of the recently paid tips a transaction
+// needs to match at the confidence level.
+func (c Confidence) Percentile() int {
+	return confidenceParams[c].percentile
+}
+
+// TargetBlocks returns the number of blocks a transaction should be included
+// within at the confidence level.
+func (c Confidence) TargetBlocks() uint64 {
+	return confidenceParams[c].blocks
+}
+
+// BlockSample is the fee market data of a recent block.
+type BlockSample struct {
+	Number   uint64
+	BaseFee  *big.Int
+	GasUsed  uint64
+	GasLimit uint64
+
+	// Tips are the effective tips of the included transactions in ascending
+	// order, excluding the ones sent by the fee recipient and the ones under
+	// the ignore price of the oracle.
+	Tips []*big.Int
+}
+
+// EstimatorInput is the market data fee estimators base their estimates on.
+type EstimatorInput struct {
+	Head       *types.Header
+	History    []*BlockSample     // Recent blocks, oldest first
+	Pending    types.Transactions // Executable pool transactions, nil if the pool is not available
+	BaseFees   []*big.Int         // Projected base fees of the upcoming blocks, starting with the next one
+	GasLimit   uint64             // Gas limit of the upcoming blocks
+	DefaultTip *big.Int           // Tip to fall back to if the history contains no samples
+}
+
+// TipEstimate is the tip an estimator suggests for a confidence level.
+type TipEstimate struct {
+	TipCap         *big.Int
+	ExpectedBlocks uint64 // Number of blocks until the expected inclusion
+}
+
+// Estimator is a strategy to suggest transaction tips. Custom strategies can
+// be plugged into the oracle through the configuration.
+type Estimator interface {
+	// Name returns the name the strategy can be selected by.
+	Name() string
+
+	// EstimateTip suggests the tip to pay for inclusion at the given confidence
+	// level. The expected number of blocks should not exceed the number of
+	// projected base fees.
+	EstimateTip(input *EstimatorInput, confidence Confidence) (*TipEstimate, error)
+}
+
+// PercentileEstimator suggests tips based on the tips recently paid only. The
+// tip is the confidence percentile of the sampled tips and the expected blocks
+// are derived from the share of recent blocks the tip would have made it into.
+type PercentileEstimator struct{}
+
+// Name implements Estimator.
+func (PercentileEstimator) Name() string { return "percentile" }
+
+// EstimateTip implements Estimator.
+func (PercentileEstimator) EstimateTip(input *EstimatorInput, confidence Confidence) (*TipEstimate, error) {
+	var tips []*big.Int
+	for _, block := range input.History {
+		tips = append(tips, block.Tips...)
+	}
+	tip := input.DefaultTip
+	if len(tips) > 0 {
+		sort.Sort(bigIntArray(tips))
+		tip = tips[(len(tips)-1)*confidence.Percentile()/100]
+	}
+	return &TipEstimate{
+		TipCap:         new(big.Int).Set(tip),
+		ExpectedBlocks: historicalBlocks(input.History, tip, uint64(len(input.BaseFees))),
+	}, nil
+}
+
+// historicalBlocks estimates the number of blocks until a transaction paying
+// the given tip is included, based on the share of recent blocks it would have
+// made it into. The result is capped to the given horizon.
+func historicalBlocks(history []*BlockSample, tip *big.Int, horizon uint64) uint64 {
+	var hits int
+	for _, block := range history {
+		// A block with spare room would have taken any tip, otherwise the tip
+		// needed to beat the cheapest included transaction.
+		if block.GasUsed+params.TxGas <= block.GasLimit || len(block.Tips) == 0 || tip.Cmp(block.Tips[0]) >= 0 {
+			hits++
+		}
+	}
+	if len(history) == 0 {
+		return 1
+	}
+	if hits == 0 {
+		return horizon
+	}
+	blocks := uint64((len(history) + hits - 1) / hits)
+	if blocks > horizon {
+		blocks = horizon
+	}
+	return blocks
+}
+
+// PoolEstimator refines the percentile estimates with the composition of the
+// transaction pool. The tip is raised to outbid the pending transactions that
+// would fill the targeted blocks, and the expected blocks account for the gas
+// of the pending transactions paying more.
+type PoolEstimator struct{}
+
+// Name implements Estimator.
+func (PoolEstimator) Name() string { return "pool" }
+
+// EstimateTip implements Estimator.
+func (PoolEstimator) EstimateTip(input *EstimatorInput, confidence Confidence) (*TipEstimate, error) {
+	estimate, err := PercentileEstimator{}.EstimateTip(input, confidence)
+	if err != nil || input.Pending == nil || input.GasLimit == 0 {
+		return estimate, err
+	}
+	// Collect the pending transactions able to pay the next base fee, the ones
+	// paying the most first as the miners would.
+	pending := make([]pendingTx, 0, len(input.Pending))
+	for _, tx := range input.Pending {
+		if tip, err := tx.EffectiveGasTip(input.BaseFees[0]); err == nil {
+			pending = append(pending, pendingTx{tip: tip, gas: tx.Gas()})
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].tip.Cmp(pending[j].tip) > 0
+	})
+	// Outbid the transactions which would fill the targeted blocks, leaving
+	// room for the estimated one.
+	var (
+		capacity = confidence.TargetBlocks() * input.GasLimit
+		used     uint64
+	)
+	for _, tx := range pending {
+		if used+tx.gas+params.TxGas > capacity {
+			if tip := new(big.Int).Add(tx.tip, common.Big1); tip.Cmp(estimate.TipCap) > 0 {
+				estimate.TipCap = tip
+			}
+			break
+		}
+		used += tx.gas
+	}
+	// The transaction has to wait for the pending ones paying more
+	var ahead uint64
+	for _, tx := range pending {
+		if tx.tip.Cmp(estimate.TipCap) <= 0 {
+			break
+		}
+		ahead += tx.gas
+	}
+	blocks := ahead/input.GasLimit + 1
+	if horizon := uint64(len(input.BaseFees)); blocks > horizon {
+		blocks = horizon
+	}
+	if blocks > estimate.ExpectedBlocks {
+		estimate.ExpectedBlocks = blocks
+	}
+	return estimate, nil
+}
+
+// pendingTx is the tip and gas of a pending transaction.
+type pendingTx struct {
+	tip *big.Int
+	gas uint64
+}
@@ -0,0 +1,278 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// DefaultEstimateBlocks is the default number of upcoming blocks the base
+	// fee is projected over.
+	DefaultEstimateBlocks = 10
+
+	// maxEstimateBlocks is the maximum number of upcoming blocks the base fee
+	// can be projected over.
+	maxEstimateBlocks = 64
+)
+
+var (
+	errUnknownEstimator = errors.New("unknown fee estimation strategy")
+	errPreLondon        = errors.New("fee estimation not available before the London fork")
+)
+
+// poolBackend is implemented by the oracle backends with access to the pending
+// transactions, allowing the estimators to account for the pool composition.
+// Private transactions are not part of the view, as the estimates would leak
+// them otherwise.
+type poolBackend interface {
+	GetPublicPoolTransactions() (types.Transactions, error)
+}
+
+// LevelEstimate is the fee estimate of a confidence level.
+type LevelEstimate struct {
+	Confidence     Confidence
+	TipCap         *big.Int
+	FeeCap         *big.Int
+	ExpectedBlocks uint64
+}
+
+// FeeEstimate is the fee suggestion of a strategy for all confidence levels.
+type FeeEstimate struct {
+	Strategy     string
+	BaseFee      *big.Int         // Base fee of the next block
+	BaseFeeTrend []*big.Int       // Projected base fees of the upcoming blocks
+	Levels       []*LevelEstimate // Estimates from the slowest to the fastest confidence level
+}
+
+// sampleCacheKey is the key of the block samples in the history cache.
+type sampleCacheKey common.Hash
+
+// FeeEstimate suggests tip and fee caps for each confidence level using the
+// given estimation strategy, or the configured one if empty. The base fee is
+// projected over the given number of upcoming blocks, which also caps the
+// expected inclusion delay.
+func (oracle *Oracle) FeeEstimate(ctx context.Context, blocks int, strategy string) (*FeeEstimate, error) {
+	if strategy == "" {
+		strategy = oracle.estimator
+	}
+	estimator, ok := oracle.estimators[strategy]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", errUnknownEstimator, strategy)
+	}
+	if blocks < 1 {
+		blocks = 1
+	} else if blocks > maxEstimateBlocks {
+		blocks = maxEstimateBlocks
+	}
+	head, err := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	config := oracle.backend.ChainConfig()
+	if !config.IsLondon(new(big.Int).Add(head.Number, common.Big1)) {
+		return nil, errPreLondon
+	}
+	history, err := oracle.sampleHistory(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+	var pending types.Transactions
+	if pool, ok := oracle.backend.(poolBackend); ok {
+		if pending, err = pool.GetPublicPoolTransactions(); err != nil {
+			return nil, err
+		}
+		if pending == nil {
+			pending = types.Transactions{}
+		}
+	}
+	oracle.cacheLock.RLock()
+	defaultTip := oracle.lastPrice
+	oracle.cacheLock.RUnlock()
+	if defaultTip == nil {
+		defaultTip = new(big.Int)
+	}
+
+	input := &EstimatorInput{
+		Head:       head,
+		History:    history,
+		Pending:    pending,
+		BaseFees:   projectBaseFees(config, head, history, pending, blocks),
+		GasLimit:   head.GasLimit,
+		DefaultTip: defaultTip,
+	}
+	estimate := &FeeEstimate{
+		Strategy:     strategy,
+		BaseFee:      input.BaseFees[0],
+		BaseFeeTrend: input.BaseFees,
+	}
+	for c := Slow; c <= Fast; c++ {
+		tip, err := estimator.EstimateTip(input, c)
+		if err != nil {
+			return nil, err
+		}
+		tipCap := tip.TipCap
+		if tipCap.Cmp(oracle.maxPrice) > 0 {
+			tipCap = new(big.Int).Set(oracle.maxPrice)
+		}
+		expected := tip.ExpectedBlocks
+		if expected < 1 {
+			expected = 1
+		} else if expected > uint64(blocks) {
+			expected = uint64(blocks)
+		}
+		// The fee cap has to cover the base fee until the expected inclusion
+		baseFee := new(big.Int)
+		for _, fee := range input.BaseFees[:expected] {
+			if fee.Cmp(baseFee) > 0 {
+				baseFee = fee
+			}
+		}
+		estimate.Levels = append(estimate.Levels, &LevelEstimate{
+			Confidence:     c,
+			TipCap:         tipCap,
+			FeeCap:         new(big.Int).Add(baseFee, tipCap),
+			ExpectedBlocks: expected,
+		})
+	}
+	return estimate, nil
+}
+
+// sampleHistory collects the fee market data of the recent blocks, oldest first.
+func (oracle *Oracle) sampleHistory(ctx context.Context, head *types.Header) ([]*BlockSample, error) {
+	var (
+		samples []*BlockSample
+		number  = head.Number.Uint64()
+	)
+	for len(samples) < oracle.checkBlocks && number > 0 {
+		sample, err := oracle.sampleBlock(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+		number--
+	}
+	for i, j := 0, len(samples)-1; i < j; i, j = i+1, j-1 {
+		samples[i], samples[j] = samples[j], samples[i]
+	}
+	return samples, nil
+}
+
+// sampleBlock retrieves the fee market data of a block, from the cache if
+// it was sampled before.
+func (oracle *Oracle) sampleBlock(ctx context.Context, number uint64) (*BlockSample, error) {
+	header, err := oracle.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if header == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", number)
+		}
+		return nil, err
+	}
+	key := sampleCacheKey(header.Hash())
+	if sample, ok := oracle.historyCache.Get(key); ok {
+		return sample.(*BlockSample), nil
+	}
+	block, err := oracle.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if block == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", number)
+		}
+		return nil, err
+	}
+	sample := &BlockSample{
+		Number:   number,
+		BaseFee:  block.BaseFee(),
+		GasUsed:  block.GasUsed(),
+		GasLimit: block.GasLimit(),
+	}
+	signer := types.MakeSigner(oracle.backend.ChainConfig(), block.Number(), block.Time())
+	for _, tx := range block.Transactions() {
+		tip, _ := tx.EffectiveGasTip(block.BaseFee())
+		if tip.Cmp(oracle.ignorePrice) < 0 {
+			continue
+		}
+		if sender, err := types.Sender(signer, tx); err == nil && sender != block.Coinbase() {
+			sample.Tips = append(sample.Tips, tip)
+		}
+	}
+	sort.Sort(bigIntArray(sample.Tips))
+	oracle.historyCache.Add(key, sample)
+	return sample, nil
+}
+
+// projectBaseFees projects the base fees of the given number of blocks after
+// the head. The gas used by the upcoming blocks is predicted from the pending
+// transactions able to pay the projected base fees, but not less than the
+// average usage of the recent blocks.
+func projectBaseFees(config *params.ChainConfig, head *types.Header, history []*BlockSample, pending types.Transactions, blocks int) []*big.Int {
+	// Average the share of the gas limit used by the recent blocks
+	var ratio float64
+	for _, block := range history {
+		if block.GasLimit > 0 {
+			ratio += float64(block.GasUsed) / float64(block.GasLimit)
+		}
+	}
+	if len(history) > 0 {
+		ratio /= float64(len(history))
+	}
+	// Order the pending transactions as the miners would
+	txs := make(types.Transactions, len(pending))
+	copy(txs, pending)
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].GasTipCapCmp(txs[j]) > 0
+	})
+	included := make([]bool, len(txs))
+
+	var (
+		fees   = make([]*big.Int, blocks)
+		parent = head
+	)
+	for i := range fees {
+		fees[i] = misc.CalcBaseFee(config, parent)
+
+		// Fill the block with the pending transactions willing to pay
+		var backlog uint64
+		for j, tx := range txs {
+			if included[j] || tx.GasFeeCapIntCmp(fees[i]) < 0 || backlog+tx.Gas() > parent.GasLimit {
+				continue
+			}
+			included[j] = true
+			backlog += tx.Gas()
+		}
+		gasUsed := uint64(ratio * float64(parent.GasLimit))
+		if backlog > gasUsed {
+			gasUsed = backlog
+		}
+		parent = &types.Header{
+			Number:   new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit: parent.GasLimit,
+			GasUsed:  gasUsed,
+			BaseFee:  fees[i],
+		}
+	}
+	return fees
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// poolTestBackend is a test backend with a transaction pool.
+type poolTestBackend struct {
+	*testBackend
+	pool types.Transactions
+}
+
+func (b *poolTestBackend) GetPublicPoolTransactions() (types.Transactions, error) {
+	return b.pool, nil
+}
+
+// constEstimator is a custom estimator suggesting the same tip at every level.
+type constEstimator struct{}
+
+func (constEstimator) Name() string { return "const" }
+
+func (constEstimator) EstimateTip(input *EstimatorInput, confidence Confidence) (*TipEstimate, error) {
+	return &TipEstimate{TipCap: big.NewInt(params.GWei), ExpectedBlocks: confidence.TargetBlocks()}, nil
+}
+
+func TestFeeEstimatePercentile(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(0), false)
+	defer backend.teardown()
+
+	oracle := NewOracle(backend, Config{
+		Blocks:    3,
+		Default:   big.NewInt(params.GWei),
+		Estimator: "percentile",
+	})
+	estimate, err := oracle.FeeEstimate(context.Background(), 5, "")
+	if err != nil {
+		t.Fatalf("Failed to estimate fees: %v", err)
+	}
+	if estimate.Strategy != "percentile" {
+		t.Errorf("Strategy mismatch, want %q, got %q", "percentile", estimate.Strategy)
+	}
+	if len(estimate.BaseFeeTrend) != 5 {
+		t.Fatalf("Base fee trend length mismatch, want %d, got %d", 5, len(estimate.BaseFeeTrend))
+	}
+	// The recent blocks are almost empty, the base fee should keep dropping
+	for i := 1; i < len(estimate.BaseFeeTrend); i++ {
+		if estimate.BaseFeeTrend[i].Cmp(estimate.BaseFeeTrend[i-1]) >= 0 {
+			t.Errorf("Base fee not decreasing at block %d: %v", i, estimate.BaseFeeTrend)
+		}
+	}
+	// The sampled tips are 30G, 31G and 32G
+	want := []int64{30, 31, 31}
+	for i, level := range estimate.Levels {
+		if level.TipCap.Cmp(big.NewInt(want[i]*params.GWei)) != 0 {
+			t.Errorf("%v tip mismatch, want %dG, got %v", level.Confidence, want[i], level.TipCap)
+		}
+		if level.ExpectedBlocks != 1 {
+			t.Errorf("%v expected blocks mismatch, want 1, got %d", level.Confidence, level.ExpectedBlocks)
+		}
+		if feeCap := new(big.Int).Add(estimate.BaseFee, level.TipCap); level.FeeCap.Cmp(feeCap) != 0 {
+			t.Errorf("%v fee cap mismatch, want %v, got %v", level.Confidence, feeCap, level.FeeCap)
+		}
+	}
+}
+
+func TestFeeEstimatePool(t *testing.T) {
+	backend := &poolTestBackend{testBackend: newTestBackend(t, big.NewInt(0), false)}
+	defer backend.teardown()
+
+	// Fill the pool with two blocks worth of well paying transactions
+	head, _ := backend.HeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	gas := head.GasLimit / 2
+	for i := 0; i < 4; i++ {
+		backend.pool = append(backend.pool, types.NewTx(&types.DynamicFeeTx{
+			Nonce:     uint64(i),
+			To:        &common.Address{},
+			Gas:       gas,
+			GasFeeCap: big.NewInt(1000 * params.GWei),
+			GasTipCap: big.NewInt(100 * params.GWei),
+		}))
+	}
+	oracle := NewOracle(backend, Config{
+		Blocks:    3,
+		Default:   big.NewInt(params.GWei),
+		MaxPrice:  big.NewInt(50 * params.GWei),
+		Estimator: "pool",
+	})
+	estimate, err := oracle.FeeEstimate(context.Background(), DefaultEstimateBlocks, "")
+	if err != nil {
+		t.Fatalf("Failed to estimate fees: %v", err)
+	}
+	// The pool backlog should push the base fee up
+	if estimate.BaseFeeTrend[1].Cmp(estimate.BaseFeeTrend[0]) <= 0 {
+		t.Errorf("Base fee not increasing with the pool backlog: %v", estimate.BaseFeeTrend)
+	}
+	// The slow and standard levels wait for the backlog, the fast one outbids
+	// it but is capped to the max price.
+	backlog := 4*gas/head.GasLimit + 1
+	var (
+		wantTips   = []int64{30, 31, 50}
+		wantBlocks = []uint64{backlog, backlog, 1}
+	)
+	for i, level := range estimate.Levels {
+		if level.TipCap.Cmp(big.NewInt(wantTips[i]*params.GWei)) != 0 {
+			t.Errorf("%v tip mismatch, want %dG, got %v", level.Confidence, wantTips[i], level.TipCap)
+		}
+		if level.ExpectedBlocks != wantBlocks[i] {
+			t.Errorf("%v expected blocks mismatch, want %d, got %d", level.Confidence, wantBlocks[i], level.ExpectedBlocks)
+		}
+		baseFee := new(big.Int)
+		for _, fee := range estimate.BaseFeeTrend[:level.ExpectedBlocks] {
+			if fee.Cmp(baseFee) > 0 {
+				baseFee = fee
+			}
+		}
+		if feeCap := new(big.Int).Add(baseFee, level.TipCap); level.FeeCap.Cmp(feeCap) != 0 {
+			t.Errorf("%v fee cap mismatch, want %v, got %v", level.Confidence, feeCap, level.FeeCap)
+		}
+	}
+}
+
+func TestFeeEstimateStrategies(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(0), false)
+	defer backend.teardown()
+
+	oracle := NewOracle(backend, Config{
+		Blocks:     3,
+		Default:    big.NewInt(params.GWei),
+		Estimators: []Estimator{constEstimator{}},
+	})
+	// Custom strategies should be selectable by name
+	estimate, err := oracle.FeeEstimate(context.Background(), 3, "const")
+	if err != nil {
+		t.Fatalf("Failed to estimate fees: %v", err)
+	}
+	for _, level := range estimate.Levels {
+		if level.TipCap.Cmp(big.NewInt(params.GWei)) != 0 {
+			t.Errorf("%v tip mismatch, want 1G, got %v", level.Confidence, level.TipCap)
+		}
+		// The expected blocks are capped to the projection
+		if want := level.Confidence.TargetBlocks(); want > 3 && level.ExpectedBlocks != 3 {
+			t.Errorf("%v expected blocks not capped, got %d", level.Confidence, level.ExpectedBlocks)
+		}
+	}
+	// Unknown strategies should be rejected
+	if _, err := oracle.FeeEstimate(context.Background(), 3, "unknown"); !errors.Is(err, errUnknownEstimator) {
+		t.Errorf("Unknown strategy error mismatch, want %v, got %v", errUnknownEstimator, err)
+	}
+	// Pre-London chains have no base fee to estimate
+	legacy := newTestBackend(t, nil, false)
+	defer legacy.teardown()
+
+	if _, err := NewOracle(legacy, Config{Blocks: 3}).FeeEstimate(context.Background(), 3, ""); err != errPreLondon {
+		t.Errorf("Pre-London error mismatch, want %v, got %v", errPreLondon, err)
+	}
+}
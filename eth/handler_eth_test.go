@@ -447,6 +447,94 @@ func testTransactionPropagation(t *testing.T, protocol uint) {
 	}
 }
 
+// Tests that private transactions are neither announced, broadcast nor served to
+// remote peers, whether they were pooled before or after the peers connected.
+func TestPrivateTransactionPropagation66(t *testing.T) {
+	testPrivateTransactionPropagation(t, eth.ETH66)
+}
+
+func testPrivateTransactionPropagation(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	source := newTestHandler()
+	source.handler.snapSync = 0 // Avoid requiring snap, otherwise some will be dropped below
+	defer source.close()
+
+	// Create a batch of public and private transactions
+	txs := make([]*types.Transaction, 8)
+	for nonce := range txs {
+		tx := types.NewTransaction(uint64(nonce), common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil)
+		tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+
+		txs[nonce] = tx
+	}
+	// Pool half of them before the peers connect, to be announced on sync
+	source.txpool.AddRemotes(txs[:2])
+	source.txpool.addPrivate(txs[2:4])
+
+	if tx := (*ethHandler)(source.handler).TxPool().Get(txs[2].Hash()); tx != nil {
+		t.Fatalf("private transaction served to peers")
+	}
+	sinks := make([]*testHandler, 4)
+	txChs := make([]chan core.NewTxsEvent, len(sinks))
+	for i := range sinks {
+		sinks[i] = newTestHandler()
+		defer sinks[i].close()
+
+		sinks[i].handler.acceptTxs = 1 // mark synced to accept transactions
+
+		txChs[i] = make(chan core.NewTxsEvent, 1024)
+		sub := sinks[i].txpool.SubscribeNewTxsEvent(txChs[i])
+		defer sub.Unsubscribe()
+	}
+	for i, sink := range sinks {
+		sink := sink // Closure for gorotuine below
+
+		sourcePipe, sinkPipe := p2p.MsgPipe()
+		defer sourcePipe.Close()
+		defer sinkPipe.Close()
+
+		sourcePeer := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{byte(i + 1)}, "", nil, sourcePipe), sourcePipe, (*ethHandler)(source.handler).TxPool())
+		sinkPeer := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{0}, "", nil, sinkPipe), sinkPipe, sink.txpool)
+		defer sourcePeer.Close()
+		defer sinkPeer.Close()
+
+		go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(source.handler), peer)
+		})
+		go sink.handler.runEthPeer(sinkPeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(sink.handler), peer)
+		})
+	}
+	// Pool the other half after the peers connected, to be broadcast
+	time.Sleep(100 * time.Millisecond)
+	source.txpool.AddRemotes(txs[4:6])
+	source.txpool.addPrivate(txs[6:])
+
+	// Ensure all sinks receive the public transactions
+	for i := range sinks {
+		for arrived, timeout := 0, false; arrived < 4 && !timeout; {
+			select {
+			case event := <-txChs[i]:
+				arrived += len(event.Txs)
+			case <-time.After(time.Second):
+				t.Errorf("sink %d: transaction propagation timed out: have %d, want %d", i, arrived, 4)
+				timeout = true
+			}
+		}
+	}
+	// Give any leaked transaction some time to arrive and ensure there's none
+	time.Sleep(100 * time.Millisecond)
+	for i, sink := range sinks {
+		for j, tx := range txs {
+			private := (j >= 2 && j < 4) || j >= 6
+			if sink.txpool.Has(tx.Hash()) == private {
+				t.Errorf("sink %d: tx %d presence mismatch: private %v", i, j, private)
+			}
+		}
+	}
+}
+
 // Tests that post eth protocol handshake, clients perform a mutual checkpoint
 // challenge to validate each other's chains. Hash mismatches, or missing ones
 // during a fast sync should lead to the peer getting dropped.
@@ -83,7 +83,7 @@ func newTestBackendWithGenerator(blocks int, generator func(int, *core.BlockGen)
 	return &testBackend{
 		db:     db,
 		chain:  chain,
-		txpool: txpool.NewTxPool(txconfig, params.TestChainConfig, chain),
+		txpool: txpool.New(chain, []txpool.SubPool{txpool.NewLegacyPool(txconfig, params.TestChainConfig, chain)}),
 	}
 }
 
@@ -241,7 +241,7 @@ func (t *jsTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Addr
 	t.ctx["value"] = valueBig
 	t.ctx["block"] = t.vm.ToValue(env.Context.BlockNumber.Uint64())
 	// Update list of precompiles based on current block
-	rules := env.ChainConfig().Rules(env.Context.BlockNumber, env.Context.Random != nil)
+	rules := env.ChainConfig().Rules(env.Context.BlockNumber, env.Context.Random != nil, env.Context.Time)
 	t.activePrecompiles = vm.ActivePrecompiles(rules)
 	t.ctx["intrinsicGas"] = t.vm.ToValue(t.gasLimit - gas)
 }
@@ -722,6 +722,28 @@ func (do *dbObj) GetState(addrSlice goja.Value, hashSlice goja.Value) goja.Value
 	return res
 }
 
+func (do *dbObj) GetTransientState(addrSlice goja.Value, hashSlice goja.Value) goja.Value {
+	a, err := do.fromBuf(do.vm, addrSlice, false)
+	if err != nil {
+		do.vm.Interrupt(err)
+		return nil
+	}
+	addr := common.BytesToAddress(a)
+	h, err := do.fromBuf(do.vm, hashSlice, false)
+	if err != nil {
+		do.vm.Interrupt(err)
+		return nil
+	}
+	hash := common.BytesToHash(h)
+	state := do.db.GetTransientState(addr, hash).Bytes()
+	res, err := do.toBuf(do.vm, state)
+	if err != nil {
+		do.vm.Interrupt(err)
+		return nil
+	}
+	return res
+}
+
 func (do *dbObj) Exists(addrSlice goja.Value) bool {
 	a, err := do.fromBuf(do.vm, addrSlice, false)
 	if err != nil {
@@ -738,6 +760,7 @@ func (do *dbObj) setupObject() *goja.Object {
 	o.Set("getNonce", do.vm.ToValue(do.GetNonce))
 	o.Set("getCode", do.vm.ToValue(do.GetCode))
 	o.Set("getState", do.vm.ToValue(do.GetState))
+	o.Set("getTransientState", do.vm.ToValue(do.GetTransientState))
 	o.Set("exists", do.vm.ToValue(do.Exists))
 	return o
 }
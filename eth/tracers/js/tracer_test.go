@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers"
@@ -259,6 +260,39 @@ func TestIsPrecompile(t *testing.T) {
 	}
 }
 
+func TestTransientState(t *testing.T) {
+	chaincfg := *params.TestChainConfig
+	chaincfg.ShanghaiTime = new(uint64)
+	chaincfg.CancunTime = new(uint64)
+
+	tracer, err := newJsTracer("{res: null, step: function(log, db) { if (log.op.toString() === 'STOP') { this.res = toHex(db.getTransientState(log.contract.getAddress(), toWord('0x01'))); } }, fault: function() {}, result: function() { return this.res; }}", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		env        = vm.NewEVM(vm.BlockContext{BlockNumber: big.NewInt(1)}, vm.TxContext{GasPrice: big.NewInt(1)}, statedb, &chaincfg, vm.Config{Debug: true, Tracer: tracer})
+		contract   = vm.NewContract(account{}, account{}, big.NewInt(0), 10000)
+	)
+	contract.Code = []byte{byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x01, byte(vm.TSTORE), byte(vm.STOP)}
+
+	tracer.CaptureTxStart(31000)
+	tracer.CaptureStart(env, contract.Caller(), contract.Address(), false, []byte{}, 10000, big.NewInt(0))
+	ret, err := env.Interpreter().Run(contract, []byte{}, false)
+	tracer.CaptureEnd(ret, 10000-contract.Gas, 1, err)
+	tracer.CaptureTxEnd(10000 - contract.Gas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"0x000000000000000000000000000000000000000000000000000000000000002a"`; string(res) != want {
+		t.Errorf("transient state mismatch: have %s, want %s", res, want)
+	}
+}
+
 func TestEnterExit(t *testing.T) {
 	// test that either both or none of enter() and exit() are defined
 	if _, err := newJsTracer("{step: function() {}, fault: function() {}, result: function() { return null; }, enter: function() {}}", new(tracers.Context), nil); err == nil {
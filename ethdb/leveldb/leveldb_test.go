@@ -17,6 +17,7 @@
 package leveldb
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -38,3 +39,42 @@ func TestLevelDB(t *testing.T) {
 		})
 	})
 }
+
+func TestSecondary(t *testing.T) {
+	dir := t.TempDir()
+	primary, err := New(dir, 16, 16, "", false)
+	if err != nil {
+		t.Fatalf("failed to open primary: %v", err)
+	}
+	defer primary.Close()
+
+	primary.Put([]byte("a"), []byte{0x01})
+
+	// Open a secondary while the primary holds the lock, ensure the unflushed
+	// writes of the primary are visible.
+	secondary, err := NewSecondary(dir, 16, 16)
+	if err != nil {
+		t.Fatalf("failed to open secondary: %v", err)
+	}
+	if val, err := secondary.Get([]byte("a")); err != nil || !bytes.Equal(val, []byte{0x01}) {
+		t.Fatalf("value mismatch: have %x (%v), want 01", val, err)
+	}
+	if err := secondary.Put([]byte("b"), []byte{0x02}); err == nil {
+		t.Fatalf("write to secondary succeeded")
+	}
+	// Further writes are only visible after reopening the secondary
+	primary.Put([]byte("c"), []byte{0x03})
+	if ok, _ := secondary.Has([]byte("c")); ok {
+		t.Fatalf("secondary observed write after opening")
+	}
+	secondary.Close()
+
+	if secondary, err = NewSecondary(dir, 16, 16); err != nil {
+		t.Fatalf("failed to reopen secondary: %v", err)
+	}
+	defer secondary.Close()
+
+	if ok, _ := secondary.Has([]byte("c")); !ok {
+		t.Fatalf("secondary missing write after reopening")
+	}
+}
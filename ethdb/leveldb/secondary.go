@@ -0,0 +1,190 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package leveldb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// errSecondaryReadOnly is returned if a write is attempted on the storage of a
+// secondary database instance.
+var errSecondaryReadOnly = errors.New("leveldb: secondary instance is read-only")
+
+// NewSecondary opens the database at the given path as a read-only secondary
+// instance, which can be used while a primary instance is holding the database
+// lock and writing into it. The secondary sees the content of the database as
+// of the time of opening, it needs to be reopened to observe newer writes.
+//
+// Since the primary may compact away table files still referenced by the
+// secondary, reads from a long lived secondary may fail with missing files.
+// Secondaries are meant to be reopened frequently, hence no metrics are collected
+// for them.
+func NewSecondary(file string, cache int, handles int) (*Database, error) {
+	if cache < minCache {
+		cache = minCache
+	}
+	if handles < minHandles {
+		handles = minHandles
+	}
+	options := configureOptions(func(options *opt.Options) {
+		options.OpenFilesCacheCapacity = handles
+		options.BlockCacheCapacity = cache / 2 * opt.MiB
+		options.ReadOnly = true
+	})
+	db, err := leveldb.Open(&secondaryStorage{path: file}, options)
+	if err != nil {
+		return nil, err
+	}
+	return &Database{
+		fn:  file,
+		db:  db,
+		log: log.New("database", file, "secondary", true),
+	}, nil
+}
+
+// secondaryStorage is a read-only leveldb file storage which doesn't acquire
+// the database lock, allowing it to be opened while a primary instance is
+// running. Files are only ever read, everything else is rejected.
+type secondaryStorage struct {
+	path string
+}
+
+// secondaryLock is a no-op lock of the secondary storage.
+type secondaryLock struct{}
+
+func (secondaryLock) Unlock() {}
+
+// Lock returns a no-op lock, the primary instance holds the real one.
+func (s *secondaryStorage) Lock() (storage.Locker, error) {
+	return secondaryLock{}, nil
+}
+
+// Log forwards the leveldb internal logs to the trace level logger.
+func (s *secondaryStorage) Log(str string) {
+	log.Trace("Secondary leveldb", "path", s.path, "msg", str)
+}
+
+// SetMeta, Create, Remove and Rename are rejected, the secondary never writes.
+func (s *secondaryStorage) SetMeta(fd storage.FileDesc) error { return errSecondaryReadOnly }
+func (s *secondaryStorage) Create(fd storage.FileDesc) (storage.Writer, error) {
+	return nil, errSecondaryReadOnly
+}
+func (s *secondaryStorage) Remove(fd storage.FileDesc) error           { return errSecondaryReadOnly }
+func (s *secondaryStorage) Rename(oldfd, newfd storage.FileDesc) error { return errSecondaryReadOnly }
+
+// Close is a no-op, the opened files are closed by their users.
+func (s *secondaryStorage) Close() error {
+	return nil
+}
+
+// GetMeta returns the manifest file referenced by the CURRENT file, which the
+// primary instance replaces atomically.
+func (s *secondaryStorage) GetMeta() (storage.FileDesc, error) {
+	blob, err := os.ReadFile(filepath.Join(s.path, "CURRENT"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = os.ErrNotExist
+		}
+		return storage.FileDesc{}, err
+	}
+	name := strings.TrimSuffix(string(blob), "\n")
+	fd, ok := parseFileName(name)
+	if !ok || fd.Type != storage.TypeManifest || len(name) == len(blob) {
+		return storage.FileDesc{}, &storage.ErrCorrupted{Fd: fd, Err: fmt.Errorf("invalid CURRENT content %q", blob)}
+	}
+	if _, err := os.Stat(filepath.Join(s.path, name)); err != nil {
+		return storage.FileDesc{}, os.ErrNotExist
+	}
+	return fd, nil
+}
+
+// List returns the file descriptors of the given types found in the database.
+func (s *secondaryStorage) List(ft storage.FileType) ([]storage.FileDesc, error) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var fds []storage.FileDesc
+	for _, entry := range entries {
+		if fd, ok := parseFileName(entry.Name()); ok && fd.Type&ft != 0 {
+			fds = append(fds, fd)
+		}
+	}
+	return fds, nil
+}
+
+// Open opens the file with the given descriptor for reading.
+func (s *secondaryStorage) Open(fd storage.FileDesc) (storage.Reader, error) {
+	if !storage.FileDescOk(fd) {
+		return nil, storage.ErrInvalidFile
+	}
+	file, err := os.Open(filepath.Join(s.path, fileName(fd)))
+	if err != nil && fd.Type == storage.TypeTable && os.IsNotExist(err) {
+		file, err = os.Open(filepath.Join(s.path, fmt.Sprintf("%06d.sst", fd.Num)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// fileName returns the name of the file with the given descriptor, following
+// the naming scheme of the leveldb file storage.
+func fileName(fd storage.FileDesc) string {
+	switch fd.Type {
+	case storage.TypeManifest:
+		return fmt.Sprintf("MANIFEST-%06d", fd.Num)
+	case storage.TypeJournal:
+		return fmt.Sprintf("%06d.log", fd.Num)
+	case storage.TypeTable:
+		return fmt.Sprintf("%06d.ldb", fd.Num)
+	default:
+		return fmt.Sprintf("%06d.tmp", fd.Num)
+	}
+}
+
+// parseFileName parses the name of a file of the leveldb file storage.
+func parseFileName(name string) (fd storage.FileDesc, ok bool) {
+	var tail string
+	if _, err := fmt.Sscanf(name, "%d.%s", &fd.Num, &tail); err == nil {
+		switch tail {
+		case "log":
+			fd.Type = storage.TypeJournal
+		case "ldb", "sst":
+			fd.Type = storage.TypeTable
+		case "tmp":
+			fd.Type = storage.TypeTemp
+		default:
+			return fd, false
+		}
+		return fd, true
+	}
+	if n, _ := fmt.Sscanf(name, "MANIFEST-%d%s", &fd.Num, &tail); n == 1 {
+		fd.Type = storage.TypeManifest
+		return fd, true
+	}
+	return fd, false
+}
@@ -0,0 +1,213 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package remotefreezer implements an ancient store backed by a standalone
+// freezer server, accessed over RPC via the `freezer_` namespace.
+//
+// Reads are forwarded one by one to the server. Writes issued through
+// ModifyAncients are buffered locally, uploaded to the server in bounded chunks
+// and committed atomically in a single server-side freezer batch, so the same
+// all-or-nothing guarantees of the local freezer apply.
+package remotefreezer
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errNotSupported is returned for operations that cannot be executed remotely.
+var errNotSupported = errors.New("not supported by remote freezer")
+
+// maxChunkSize is the maximum amount of raw item data uploaded to the server
+// in a single call while staging a write batch. It's kept well below the HTTP
+// request size limit of the RPC server, taking hex-encoding into account.
+const maxChunkSize = 1024 * 1024
+
+// Item is a single raw ancient item being appended to a table.
+type Item struct {
+	Kind   string         `json:"kind"`
+	Number hexutil.Uint64 `json:"number"`
+	Data   hexutil.Bytes  `json:"data"`
+}
+
+// Client is an ancient store backed by a remote freezer server.
+type Client struct {
+	remote *rpc.Client
+}
+
+// New creates an ancient store on top of an established RPC connection to a
+// freezer server.
+func New(client *rpc.Client) *Client {
+	return &Client{remote: client}
+}
+
+// Dial connects to the freezer server at the given endpoint.
+func Dial(endpoint string) (*Client, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return New(client), nil
+}
+
+// HasAncient returns an indicator whether the specified data exists in the
+// remote ancient store.
+func (c *Client) HasAncient(kind string, number uint64) (bool, error) {
+	var resp bool
+	err := c.remote.Call(&resp, "freezer_hasAncient", kind, number)
+	return resp, err
+}
+
+// Ancient retrieves an ancient binary blob from the remote ancient store.
+func (c *Client) Ancient(kind string, number uint64) ([]byte, error) {
+	var resp hexutil.Bytes
+	if err := c.remote.Call(&resp, "freezer_ancient", kind, number); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index
+// 'start'. The limits are enforced by the server.
+func (c *Client) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	var resp []hexutil.Bytes
+	if err := c.remote.Call(&resp, "freezer_ancientRange", kind, start, count, maxBytes); err != nil {
+		return nil, err
+	}
+	items := make([][]byte, len(resp))
+	for i, item := range resp {
+		items[i] = item
+	}
+	return items, nil
+}
+
+// Ancients returns the number of items in the remote ancient store.
+func (c *Client) Ancients() (uint64, error) {
+	var resp uint64
+	err := c.remote.Call(&resp, "freezer_ancients")
+	return resp, err
+}
+
+// Tail returns the number of the first stored item in the remote ancient store.
+func (c *Client) Tail() (uint64, error) {
+	var resp uint64
+	err := c.remote.Call(&resp, "freezer_tail")
+	return resp, err
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (c *Client) AncientSize(kind string) (uint64, error) {
+	var resp uint64
+	err := c.remote.Call(&resp, "freezer_ancientSize", kind)
+	return resp, err
+}
+
+// ReadAncients runs the given read operation against the remote store. Note,
+// the server cannot be locked across multiple calls, so unlike the local
+// freezer there is no guarantee that no writes happen in between the reads.
+func (c *Client) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	return fn(c)
+}
+
+// ModifyAncients runs the given write operation. The appended items are staged
+// on the server and only applied if the operation and the upload both succeed.
+func (c *Client) ModifyAncients(fn func(ethdb.AncientWriteOp) error) (int64, error) {
+	batch := new(writeBatch)
+	if err := fn(batch); err != nil {
+		return 0, err
+	}
+	if len(batch.items) == 0 {
+		return 0, nil
+	}
+	var id hexutil.Uint64
+	if err := c.remote.Call(&id, "freezer_newBatch"); err != nil {
+		return 0, err
+	}
+	for start := 0; start < len(batch.items); {
+		end, size := start, 0
+		for end < len(batch.items) && (end == start || size+len(batch.items[end].Data) <= maxChunkSize) {
+			size += len(batch.items[end].Data)
+			end++
+		}
+		if err := c.remote.Call(nil, "freezer_writeBatch", id, batch.items[start:end]); err != nil {
+			c.remote.Call(nil, "freezer_discardBatch", id)
+			return 0, err
+		}
+		start = end
+	}
+	var size hexutil.Uint64
+	if err := c.remote.Call(&size, "freezer_commitBatch", id); err != nil {
+		return 0, err
+	}
+	return int64(size), nil
+}
+
+// TruncateHead discards all but the first n ancient data from the remote store.
+func (c *Client) TruncateHead(n uint64) error {
+	return c.remote.Call(nil, "freezer_truncateHead", n)
+}
+
+// TruncateTail discards the first n ancient data from the remote store.
+func (c *Client) TruncateTail(n uint64) error {
+	return c.remote.Call(nil, "freezer_truncateTail", n)
+}
+
+// Sync flushes all in-memory ancient store data of the server to disk.
+func (c *Client) Sync() error {
+	return c.remote.Call(nil, "freezer_sync")
+}
+
+// MigrateTable is not supported remotely, since the conversion function can't
+// be shipped to the server. Migrations need to be run on the server itself.
+func (c *Client) MigrateTable(kind string, convert func([]byte) ([]byte, error)) error {
+	return errNotSupported
+}
+
+// Close terminates the connection to the server. The remote store itself is
+// left running, as it might be shared with other clients.
+func (c *Client) Close() error {
+	c.remote.Close()
+	return nil
+}
+
+// writeBatch accumulates the items appended during a ModifyAncients call.
+type writeBatch struct {
+	items []Item
+}
+
+// Append adds an RLP-encoded item.
+func (b *writeBatch) Append(kind string, number uint64, item interface{}) error {
+	blob, err := rlp.EncodeToBytes(item)
+	if err != nil {
+		return err
+	}
+	return b.AppendRaw(kind, number, blob)
+}
+
+// AppendRaw adds an item without RLP-encoding it.
+func (b *writeBatch) AppendRaw(kind string, number uint64, item []byte) error {
+	b.items = append(b.items, Item{
+		Kind:   kind,
+		Number: hexutil.Uint64(number),
+		Data:   common.CopyBytes(item),
+	})
+	return nil
+}
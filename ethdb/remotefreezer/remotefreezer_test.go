@@ -0,0 +1,219 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotefreezer
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newTestClient starts an in-process freezer server on top of a fresh local
+// freezer and returns a client connected to it.
+func newTestClient(t *testing.T) (*Client, *rawdb.Freezer) {
+	t.Helper()
+
+	store, err := rawdb.NewFreezer(t.TempDir(), "", false, 2049, map[string]bool{"raw": true, "rlp": false})
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	server, err := NewServer(store)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	client := New(rpc.DialInProc(server))
+	t.Cleanup(func() {
+		client.Close()
+		server.Stop()
+		store.Close()
+	})
+	return client, store
+}
+
+func TestRemoteModifyAncients(t *testing.T) {
+	client, store := newTestClient(t)
+
+	// Write enough data to force the batch to be uploaded in multiple chunks.
+	var raws [][]byte
+	for i := 0; i < 24; i++ {
+		raws = append(raws, bytes.Repeat([]byte{byte(i)}, maxChunkSize/8))
+	}
+	size, err := client.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := range raws {
+			if err := op.AppendRaw("raw", uint64(i), raws[i]); err != nil {
+				return err
+			}
+			if err := op.Append("rlp", uint64(i), big.NewInt(int64(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyAncients failed: %v", err)
+	}
+	if size == 0 {
+		t.Fatal("zero write size reported")
+	}
+	if frozen, _ := store.Ancients(); frozen != uint64(len(raws)) {
+		t.Fatalf("local ancients mismatch: have %d, want %d", frozen, len(raws))
+	}
+	if frozen, _ := client.Ancients(); frozen != uint64(len(raws)) {
+		t.Fatalf("remote ancients mismatch: have %d, want %d", frozen, len(raws))
+	}
+	for i := range raws {
+		blob, err := client.Ancient("raw", uint64(i))
+		if err != nil {
+			t.Fatalf("failed to retrieve item %d: %v", i, err)
+		}
+		if !bytes.Equal(blob, raws[i]) {
+			t.Fatalf("item %d mismatch", i)
+		}
+		want, _ := rlp.EncodeToBytes(big.NewInt(int64(i)))
+		if blob, _ := client.Ancient("rlp", uint64(i)); !bytes.Equal(blob, want) {
+			t.Fatalf("rlp item %d mismatch: have %x, want %x", i, blob, want)
+		}
+	}
+	items, err := client.AncientRange("rlp", 2, 5, 1024)
+	if err != nil {
+		t.Fatalf("failed to retrieve range: %v", err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("range length mismatch: have %d, want %d", len(items), 5)
+	}
+	if has, _ := client.HasAncient("raw", uint64(len(raws))); has {
+		t.Fatal("non-existent item reported present")
+	}
+	if err := client.Sync(); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+}
+
+func TestRemoteModifyAncientsRollback(t *testing.T) {
+	client, store := newTestClient(t)
+
+	// Failing write operations must not reach the server at all.
+	errFail := errors.New("fail")
+	_, err := client.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		op.AppendRaw("raw", 0, []byte{1})
+		op.AppendRaw("rlp", 0, []byte{1})
+		return errFail
+	})
+	if err != errFail {
+		t.Fatalf("wrong error: have %v, want %v", err, errFail)
+	}
+	// Out of order writes are rejected by the server, rolling back the batch.
+	_, err = client.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		op.AppendRaw("raw", 0, []byte{1})
+		op.AppendRaw("rlp", 0, []byte{1})
+		op.AppendRaw("raw", 2, []byte{2})
+		return nil
+	})
+	if err == nil {
+		t.Fatal("out of order insertion accepted")
+	}
+	if frozen, _ := store.Ancients(); frozen != 0 {
+		t.Fatalf("failed batch persisted: %d items", frozen)
+	}
+}
+
+func TestRemoteTruncate(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			op.AppendRaw("raw", i, []byte{byte(i)})
+			op.AppendRaw("rlp", i, []byte{byte(i)})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyAncients failed: %v", err)
+	}
+	if err := client.TruncateHead(8); err != nil {
+		t.Fatalf("failed to truncate head: %v", err)
+	}
+	if err := client.TruncateTail(3); err != nil {
+		t.Fatalf("failed to truncate tail: %v", err)
+	}
+	if frozen, _ := client.Ancients(); frozen != 8 {
+		t.Fatalf("head mismatch: have %d, want %d", frozen, 8)
+	}
+	if tail, _ := client.Tail(); tail != 3 {
+		t.Fatalf("tail mismatch: have %d, want %d", tail, 3)
+	}
+	if _, err := client.Ancient("raw", 1); err == nil {
+		t.Fatal("truncated item still retrievable")
+	}
+}
+
+// Tests that write batches abandoned by their clients are expired, so they
+// don't permanently exhaust the staging slots of the server.
+func TestRemoteAbandonedBatches(t *testing.T) {
+	store, err := rawdb.NewFreezer(t.TempDir(), "", false, 2049, map[string]bool{"raw": true, "rlp": false})
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	defer store.Close()
+
+	clock := new(mclock.Simulated)
+	api := NewAPI(store)
+	api.clock = clock
+
+	for i := 0; i < maxPendingBatches; i++ {
+		if _, err := api.NewBatch(); err != nil {
+			t.Fatalf("failed to allocate batch %d: %v", i, err)
+		}
+	}
+	if _, err := api.NewBatch(); err != errTooManyBatches {
+		t.Fatalf("error mismatch: have %v, want %v", err, errTooManyBatches)
+	}
+	// Keep one batch alive, the others should be dropped after the timeout.
+	clock.Run(batchTimeout / 2)
+	if err := api.WriteBatch(0, []Item{{Kind: "raw", Number: 0, Data: []byte{1}}}); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	clock.Run(batchTimeout/2 + time.Second)
+	if _, err := api.NewBatch(); err != nil {
+		t.Fatalf("failed to allocate batch after expiry: %v", err)
+	}
+	if _, err := api.CommitBatch(1); err != errUnknownBatch {
+		t.Fatalf("expired batch error mismatch: have %v, want %v", err, errUnknownBatch)
+	}
+	if err := api.WriteBatch(0, []Item{{Kind: "rlp", Number: 0, Data: []byte{1}}}); err != nil {
+		t.Fatalf("live batch dropped: %v", err)
+	}
+	if _, err := api.CommitBatch(0); err != nil {
+		t.Fatalf("failed to commit live batch: %v", err)
+	}
+	// Abandoned batches are released on allocation even below the limit.
+	clock.Run(batchTimeout + time.Second)
+	if _, err := api.NewBatch(); err != nil {
+		t.Fatalf("failed to allocate batch: %v", err)
+	}
+	if len(api.batches) != 1 {
+		t.Fatalf("abandoned batches retained: have %d, want 1", len(api.batches))
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotefreezer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxPendingBatches is the maximum number of write batches that can be
+	// staged on the server at the same time.
+	maxPendingBatches = 16
+
+	// batchTimeout is the time after which a staged write batch that was not
+	// touched by its client is considered abandoned and dropped. Without it,
+	// batches of crashed clients would hold on to their slots and memory forever.
+	batchTimeout = time.Minute
+)
+
+var (
+	errTooManyBatches = errors.New("too many pending write batches")
+	errUnknownBatch   = errors.New("unknown write batch")
+)
+
+// NewServer creates an RPC server exposing the given ancient store under the
+// `freezer_` namespace. The caller retains ownership of the store.
+func NewServer(store ethdb.AncientStore) (*rpc.Server, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("freezer", NewAPI(store)); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// API is the RPC handler of the freezer server.
+type API struct {
+	store ethdb.AncientStore
+
+	batches map[uint64]*stagedBatch // Write batches staged by clients, not yet committed
+	nextID  uint64                  // Identifier of the next staged write batch
+	clock   mclock.Clock            // Clock used to expire abandoned batches
+	lock    sync.Mutex
+}
+
+// stagedBatch is a write batch uploaded by a client but not yet committed.
+type stagedBatch struct {
+	items   []Item         // Items staged so far
+	updated mclock.AbsTime // Last time the client touched the batch
+}
+
+// NewAPI creates the RPC handler serving the given ancient store.
+func NewAPI(store ethdb.AncientStore) *API {
+	return &API{
+		store:   store,
+		batches: make(map[uint64]*stagedBatch),
+		clock:   mclock.System{},
+	}
+}
+
+// HasAncient returns an indicator whether the specified data exists.
+func (api *API) HasAncient(kind string, number uint64) (bool, error) {
+	return api.store.HasAncient(kind, number)
+}
+
+// Ancient retrieves an ancient binary blob.
+func (api *API) Ancient(kind string, number uint64) (hexutil.Bytes, error) {
+	return api.store.Ancient(kind, number)
+}
+
+// AncientRange retrieves multiple items in sequence, starting from 'start'.
+func (api *API) AncientRange(kind string, start, count, maxBytes uint64) ([]hexutil.Bytes, error) {
+	items, err := api.store.AncientRange(kind, start, count, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]hexutil.Bytes, len(items))
+	for i, item := range items {
+		resp[i] = item
+	}
+	return resp, nil
+}
+
+// Ancients returns the number of items in the ancient store.
+func (api *API) Ancients() (uint64, error) {
+	return api.store.Ancients()
+}
+
+// Tail returns the number of the first stored item.
+func (api *API) Tail() (uint64, error) {
+	return api.store.Tail()
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (api *API) AncientSize(kind string) (uint64, error) {
+	return api.store.AncientSize(kind)
+}
+
+// NewBatch allocates a new staging area for a write batch, dropping the batches
+// abandoned by their clients.
+func (api *API) NewBatch() (hexutil.Uint64, error) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	now := api.clock.Now()
+	api.expireBatches(now)
+	if len(api.batches) >= maxPendingBatches {
+		return 0, errTooManyBatches
+	}
+	id := api.nextID
+	api.nextID++
+	api.batches[id] = &stagedBatch{updated: now}
+	return hexutil.Uint64(id), nil
+}
+
+// expireBatches drops all the staged batches that were not touched by their
+// clients for longer than batchTimeout. The caller must hold the lock.
+func (api *API) expireBatches(now mclock.AbsTime) {
+	for id, batch := range api.batches {
+		if now.Sub(batch.updated) > batchTimeout {
+			delete(api.batches, id)
+		}
+	}
+}
+
+// WriteBatch stages a chunk of items into a previously allocated write batch.
+func (api *API) WriteBatch(id hexutil.Uint64, items []Item) error {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	batch, ok := api.batches[uint64(id)]
+	if !ok {
+		return errUnknownBatch
+	}
+	batch.items = append(batch.items, items...)
+	batch.updated = api.clock.Now()
+	return nil
+}
+
+// DiscardBatch drops a staged write batch without applying it.
+func (api *API) DiscardBatch(id hexutil.Uint64) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	delete(api.batches, uint64(id))
+}
+
+// CommitBatch atomically applies all the items of a staged write batch to the
+// ancient store, returning the total size of the written data.
+func (api *API) CommitBatch(id hexutil.Uint64) (hexutil.Uint64, error) {
+	api.lock.Lock()
+	batch, ok := api.batches[uint64(id)]
+	delete(api.batches, uint64(id))
+	api.lock.Unlock()
+
+	if !ok {
+		return 0, errUnknownBatch
+	}
+	size, err := api.store.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for _, item := range batch.items {
+			if err := op.AppendRaw(item.Kind, uint64(item.Number), item.Data); err != nil {
+				return fmt.Errorf("failed to append %s #%d: %v", item.Kind, item.Number, err)
+			}
+		}
+		return nil
+	})
+	return hexutil.Uint64(size), err
+}
+
+// TruncateHead discards all but the first n ancient data.
+func (api *API) TruncateHead(n uint64) error {
+	return api.store.TruncateHead(n)
+}
+
+// TruncateTail discards the first n ancient data.
+func (api *API) TruncateTail(n uint64) error {
+	return api.store.TruncateTail(n)
+}
+
+// Sync flushes all in-memory ancient store data to disk.
+func (api *API) Sync() error {
+	return api.store.Sync()
+}
@@ -25,6 +25,10 @@ type Snapshot interface {
 	// key-value data store.
 	Get(key []byte) ([]byte, error)
 
+	// Iteratee allows iterating over the key-value entries contained in the
+	// snapshot in binary-alphabetical order.
+	Iteratee
+
 	// Release releases associated resources. Release should always succeed and can
 	// be called multiple times without causing error.
 	Release()
@@ -37,9 +37,11 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -115,6 +117,64 @@ func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHe
 	return results, nil
 }
 
+type feeEstimateLevel struct {
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	ExpectedBlocks       hexutil.Uint64 `json:"expectedBlocks"`
+}
+
+type feeEstimateResult struct {
+	Strategy     string            `json:"strategy"`
+	BaseFee      *hexutil.Big      `json:"baseFeePerGas"`
+	BaseFeeTrend []*hexutil.Big    `json:"baseFeeTrend"`
+	Slow         *feeEstimateLevel `json:"slow"`
+	Standard     *feeEstimateLevel `json:"standard"`
+	Fast         *feeEstimateLevel `json:"fast"`
+}
+
+// FeeEstimate returns the tip and fee caps to pay for the slow, standard and fast
+// confidence levels, along with the expected number of blocks until inclusion.
+// The base fee is projected over blockCount upcoming blocks (10 by default) and
+// the estimates are made by the given strategy, or the node's default one.
+func (s *EthereumAPI) FeeEstimate(ctx context.Context, blockCount *rpc.DecimalOrHex, strategy *string) (*feeEstimateResult, error) {
+	blocks := gasprice.DefaultEstimateBlocks
+	if blockCount != nil {
+		blocks = int(*blockCount)
+	}
+	var name string
+	if strategy != nil {
+		name = *strategy
+	}
+	estimate, err := s.b.FeeEstimate(ctx, blocks, name)
+	if err != nil {
+		return nil, err
+	}
+	results := &feeEstimateResult{
+		Strategy:     estimate.Strategy,
+		BaseFee:      (*hexutil.Big)(estimate.BaseFee),
+		BaseFeeTrend: make([]*hexutil.Big, len(estimate.BaseFeeTrend)),
+	}
+	for i, v := range estimate.BaseFeeTrend {
+		results.BaseFeeTrend[i] = (*hexutil.Big)(v)
+	}
+	for _, level := range estimate.Levels {
+		result := &feeEstimateLevel{
+			MaxPriorityFeePerGas: (*hexutil.Big)(level.TipCap),
+			MaxFeePerGas:         (*hexutil.Big)(level.FeeCap),
+			ExpectedBlocks:       hexutil.Uint64(level.ExpectedBlocks),
+		}
+		switch level.Confidence {
+		case gasprice.Slow:
+			results.Slow = result
+		case gasprice.Standard:
+			results.Standard = result
+		case gasprice.Fast:
+			results.Fast = result
+		}
+	}
+	return results, nil
+}
+
 // Syncing returns false in case the node is currently not syncing with the network. It can be up to date or has not
 // yet received the latest block headers from its pears. In case it is synchronizing:
 // - startingBlock: block number this node started to synchronise from
@@ -218,6 +278,97 @@ func (s *TxPoolAPI) Status() map[string]hexutil.Uint {
 	}
 }
 
+// PrivateStatus returns the status of a privately submitted transaction, along
+// with the last block number it may be included in. The status is one of
+// pending, queued, included, dropped or expired. Nil is returned for unknown
+// transactions, including the ones which expired long ago.
+func (s *TxPoolAPI) PrivateStatus(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	deadline, ok := s.b.PrivateTxDeadline(hash)
+	if !ok {
+		return nil, nil
+	}
+	fields := map[string]interface{}{
+		"maxBlockNumber": hexutil.Uint64(deadline),
+	}
+	tx, _, blockNumber, _, err := s.b.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tx != nil:
+		fields["status"] = "included"
+		fields["blockNumber"] = hexutil.Uint64(blockNumber)
+
+	case s.b.GetPoolTransaction(hash) != nil:
+		fields["status"] = "queued"
+
+		from, _ := types.Sender(types.LatestSigner(s.b.ChainConfig()), s.b.GetPoolTransaction(hash))
+		pending, _ := s.b.TxPoolContentFrom(from)
+		for _, tx := range pending {
+			if tx.Hash() == hash {
+				fields["status"] = "pending"
+				break
+			}
+		}
+	case s.b.CurrentHeader().Number.Uint64() >= deadline:
+		fields["status"] = "expired"
+
+	default:
+		fields["status"] = "dropped"
+	}
+	return fields, nil
+}
+
+// TxHistory returns the lifecycle events recorded by the pool for a transaction,
+// oldest first, along with the number of the block including it if known. Nil
+// is returned for transactions neither recorded nor included.
+func (s *TxPoolAPI) TxHistory(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	events := s.b.TxHistory(hash)
+
+	tx, _, blockNumber, _, err := s.b.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 && tx == nil {
+		return nil, nil
+	}
+	fields := map[string]interface{}{
+		"events": events,
+	}
+	if tx != nil {
+		fields["blockNumber"] = hexutil.Uint64(blockNumber)
+	}
+	return fields, nil
+}
+
+// TxEvents creates a subscription that fires for the lifecycle events of the
+// transactions in the pool.
+func (s *TxPoolAPI) TxEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan txpool.TxEvent, 128)
+		eventsSub := s.b.SubscribeTxEvents(events)
+		defer eventsSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // Inspect retrieves the content of the transaction pool and flattens it into an
 // easily inspectable list.
 func (s *TxPoolAPI) Inspect() map[string]map[string]map[string]string {
@@ -933,7 +1084,7 @@ func (diff *BlockOverrides) Apply(blockCtx *vm.BlockContext) {
 		blockCtx.Difficulty = diff.Difficulty.ToInt()
 	}
 	if diff.Time != nil {
-		blockCtx.Time = diff.Time.ToInt()
+		blockCtx.Time = diff.Time.ToInt().Uint64()
 	}
 	if diff.GasLimit != nil {
 		blockCtx.GasLimit = uint64(*diff.GasLimit)
@@ -1208,6 +1359,18 @@ func RPCMarshalHeader(head *types.Header) map[string]interface{} {
 		result["baseFeePerGas"] = (*hexutil.Big)(head.BaseFee)
 	}
 
+	if head.WithdrawalsHash != nil {
+		result["withdrawalsRoot"] = head.WithdrawalsHash
+	}
+
+	if head.BlobGasUsed != nil {
+		result["blobGasUsed"] = hexutil.Uint64(*head.BlobGasUsed)
+	}
+
+	if head.ExcessBlobGas != nil {
+		result["excessBlobGas"] = hexutil.Uint64(*head.ExcessBlobGas)
+	}
+
 	return result
 }
 
@@ -1243,7 +1406,9 @@ func RPCMarshalBlock(block *types.Block, inclTx bool, fullTx bool, config *param
 		uncleHashes[i] = uncle.Hash()
 	}
 	fields["uncles"] = uncleHashes
-
+	if block.Header().WithdrawalsHash != nil {
+		fields["withdrawals"] = block.Withdrawals()
+	}
 	return fields, nil
 }
 
@@ -1286,6 +1451,8 @@ type RPCTransaction struct {
 	Type             hexutil.Uint64    `json:"type"`
 	Accesses         *types.AccessList `json:"accessList,omitempty"`
 	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	BlobFeeCap       *hexutil.Big      `json:"maxFeePerBlobGas,omitempty"`
+	BlobHashes       []common.Hash     `json:"blobVersionedHashes,omitempty"`
 	V                *hexutil.Big      `json:"v"`
 	R                *hexutil.Big      `json:"r"`
 	S                *hexutil.Big      `json:"s"`
@@ -1293,8 +1460,8 @@ type RPCTransaction struct {
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
 // representation, with the given location metadata set (if available).
-func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64, baseFee *big.Int, config *params.ChainConfig) *RPCTransaction {
-	signer := types.MakeSigner(config, new(big.Int).SetUint64(blockNumber))
+func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, blockTime uint64, index uint64, baseFee *big.Int, config *params.ChainConfig) *RPCTransaction {
+	signer := types.MakeSigner(config, new(big.Int).SetUint64(blockNumber), blockTime)
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
 	result := &RPCTransaction{
@@ -1326,10 +1493,14 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		al := tx.AccessList()
 		result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.BlobTxType:
 		al := tx.AccessList()
 		result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
+		if tx.Type() == types.BlobTxType {
+			result.BlobFeeCap = (*hexutil.Big)(tx.BlobGasFeeCap())
+			result.BlobHashes = tx.BlobHashes()
+		}
 		result.GasFeeCap = (*hexutil.Big)(tx.GasFeeCap())
 		result.GasTipCap = (*hexutil.Big)(tx.GasTipCap())
 		// if the transaction has been mined, compute the effective gas price
@@ -1346,13 +1517,17 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 
 // newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
 func newRPCPendingTransaction(tx *types.Transaction, current *types.Header, config *params.ChainConfig) *RPCTransaction {
-	var baseFee *big.Int
-	blockNumber := uint64(0)
+	var (
+		baseFee     *big.Int
+		blockNumber = uint64(0)
+		blockTime   = uint64(0)
+	)
 	if current != nil {
 		baseFee = misc.CalcBaseFee(config, current)
 		blockNumber = current.Number.Uint64()
+		blockTime = current.Time
 	}
-	return newRPCTransaction(tx, common.Hash{}, blockNumber, 0, baseFee, config)
+	return newRPCTransaction(tx, common.Hash{}, blockNumber, blockTime, 0, baseFee, config)
 }
 
 // newRPCTransactionFromBlockIndex returns a transaction that will serialize to the RPC representation.
@@ -1361,7 +1536,7 @@ func newRPCTransactionFromBlockIndex(b *types.Block, index uint64, config *param
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	return newRPCTransaction(txs[index], b.Hash(), b.NumberU64(), index, b.BaseFee(), config)
+	return newRPCTransaction(txs[index], b.Hash(), b.NumberU64(), b.Time(), index, b.BaseFee(), config)
 }
 
 // newRPCRawTransactionFromBlockIndex returns the bytes of a transaction given a block and a transaction index.
@@ -1438,7 +1613,7 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 	}
 	isPostMerge := header.Difficulty.Cmp(common.Big0) == 0
 	// Retrieve the precompiles since they don't need to be added to the access list
-	precompiles := vm.ActivePrecompiles(b.ChainConfig().Rules(header.Number, isPostMerge))
+	precompiles := vm.ActivePrecompiles(b.ChainConfig().Rules(header.Number, isPostMerge, header.Time))
 
 	// Create an initial tracer
 	prevTracer := logger.NewAccessListTracer(nil, args.from(), to, precompiles)
@@ -1573,7 +1748,7 @@ func (s *TransactionAPI) GetTransactionByHash(ctx context.Context, hash common.H
 		if err != nil {
 			return nil, err
 		}
-		return newRPCTransaction(tx, blockHash, blockNumber, index, header.BaseFee, s.b.ChainConfig()), nil
+		return newRPCTransaction(tx, blockHash, blockNumber, header.Time, index, header.BaseFee, s.b.ChainConfig()), nil
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
@@ -1618,9 +1793,13 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 	}
 	receipt := receipts[index]
 
+	header, err := s.b.HeaderByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
 	// Derive the sender.
 	bigblock := new(big.Int).SetUint64(blockNumber)
-	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
+	signer := types.MakeSigner(s.b.ChainConfig(), bigblock, header.Time)
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
@@ -1641,10 +1820,6 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 	if !s.b.ChainConfig().IsLondon(bigblock) {
 		fields["effectiveGasPrice"] = hexutil.Uint64(tx.GasPrice().Uint64())
 	} else {
-		header, err := s.b.HeaderByHash(ctx, blockHash)
-		if err != nil {
-			return nil, err
-		}
 		gasPrice := new(big.Int).Add(header.BaseFee, tx.EffectiveGasTipValue(header.BaseFee))
 		fields["effectiveGasPrice"] = hexutil.Uint64(gasPrice.Uint64())
 	}
@@ -1677,22 +1852,32 @@ func (s *TransactionAPI) sign(addr common.Address, tx *types.Transaction) (*type
 	return wallet.SignTx(account, tx, s.b.ChainConfig().ChainID)
 }
 
-// SubmitTransaction is a helper function that submits tx to txPool and logs a message.
-func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+// checkSubmission ensures a transaction submitted over RPC satisfies the
+// configured fee cap and replay protection requirements.
+func checkSubmission(b Backend, tx *types.Transaction) error {
 	// If the transaction fee cap is already specified, ensure the
 	// fee of the given transaction is _reasonable_.
 	if err := checkTxFee(tx.GasPrice(), tx.Gas(), b.RPCTxFeeCap()); err != nil {
-		return common.Hash{}, err
+		return err
 	}
 	if !b.UnprotectedAllowed() && !tx.Protected() {
 		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
-		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+		return errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+	}
+	return nil
+}
+
+// SubmitTransaction is a helper function that submits tx to txPool and logs a message.
+func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+	if err := checkSubmission(b, tx); err != nil {
+		return common.Hash{}, err
 	}
 	if err := b.SendTx(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
 	// Print a log with full tx details for manual investigations and interventions
-	signer := types.MakeSigner(b.ChainConfig(), b.CurrentBlock().Number())
+	head := b.CurrentBlock()
+	signer := types.MakeSigner(b.ChainConfig(), head.Number(), head.Time())
 	from, err := types.Sender(signer, tx)
 	if err != nil {
 		return common.Hash{}, err
@@ -1766,6 +1951,54 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+const (
+	// defaultPrivateTxBlocks is the number of blocks a private transaction is
+	// held for inclusion if no deadline is specified.
+	defaultPrivateTxBlocks = 25
+
+	// maxPrivateTxBlocks is the maximum number of blocks a private transaction
+	// may be held for inclusion.
+	maxPrivateTxBlocks = 256
+)
+
+// PrivateTransactionArgs represents the arguments to submit a transaction which
+// is not propagated to the network.
+type PrivateTransactionArgs struct {
+	Tx             hexutil.Bytes   `json:"tx"`
+	MaxBlockNumber *hexutil.Uint64 `json:"maxBlockNumber"`
+}
+
+// SendPrivateTransaction adds the signed transaction to the transaction pool
+// without propagating it to the network. The transaction is only included in
+// blocks built by the local node, up to and including the max block number, and
+// is dropped afterwards.
+func (s *TransactionAPI) SendPrivateTransaction(ctx context.Context, args PrivateTransactionArgs) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(args.Tx); err != nil {
+		return common.Hash{}, err
+	}
+	head := s.b.CurrentHeader().Number.Uint64()
+
+	deadline := head + defaultPrivateTxBlocks
+	if args.MaxBlockNumber != nil {
+		deadline = uint64(*args.MaxBlockNumber)
+	}
+	if deadline <= head {
+		return common.Hash{}, fmt.Errorf("max block number %d already reached, head %d", deadline, head)
+	}
+	if deadline > head+maxPrivateTxBlocks {
+		return common.Hash{}, fmt.Errorf("max block number %d too far in the future, limit %d", deadline, head+maxPrivateTxBlocks)
+	}
+	if err := checkSubmission(s.b, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.b.SendPrivateTx(ctx, tx, deadline); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted private transaction", "hash", tx.Hash().Hex(), "nonce", tx.Nonce(), "deadline", deadline)
+	return tx.Hash(), nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccak256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
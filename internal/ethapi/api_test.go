@@ -0,0 +1,81 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSendPrivateTransaction(t *testing.T) {
+	b := newBackendMock()
+	api := NewTransactionAPI(b, nil)
+
+	key, _ := crypto.GenerateKey()
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil), types.NewEIP155Signer(b.config.ChainID), key)
+	raw, _ := tx.MarshalBinary()
+
+	unprotected, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	rawUnprotected, _ := unprotected.MarshalBinary()
+
+	head := b.current.Number.Uint64()
+	deadline := func(n uint64) *hexutil.Uint64 { return (*hexutil.Uint64)(&n) }
+
+	tests := []struct {
+		tx       hexutil.Bytes
+		maxBlock *hexutil.Uint64
+		want     uint64 // Deadline passed to the backend, zero if rejected
+	}{
+		{raw, nil, head + defaultPrivateTxBlocks},
+		{raw, deadline(head + 1), head + 1},
+		{raw, deadline(head + maxPrivateTxBlocks), head + maxPrivateTxBlocks},
+		{raw, deadline(head), 0},
+		{raw, deadline(head + maxPrivateTxBlocks + 1), 0},
+		{rawUnprotected, nil, 0},
+		{hexutil.Bytes{0x01}, nil, 0},
+	}
+	for i, tt := range tests {
+		b.privateDeadline = 0
+
+		hash, err := api.SendPrivateTransaction(context.Background(), PrivateTransactionArgs{Tx: tt.tx, MaxBlockNumber: tt.maxBlock})
+		if tt.want == 0 {
+			if err == nil {
+				t.Errorf("test %d: expected error", i)
+			}
+			if b.privateDeadline != 0 {
+				t.Errorf("test %d: rejected transaction sent to backend", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if hash != tx.Hash() {
+			t.Errorf("test %d: hash mismatch: have %x, want %x", i, hash, tx.Hash())
+		}
+		if b.privateDeadline != tt.want {
+			t.Errorf("test %d: deadline mismatch: have %d, want %d", i, b.privateDeadline, tt.want)
+		}
+	}
+}
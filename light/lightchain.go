@@ -182,6 +182,19 @@ func (lc *LightChain) SetHead(head uint64) error {
 	return lc.loadLastState()
 }
 
+// SetHeadWithTimestamp rewinds the local chain to the last header with a
+// timestamp not exceeding the given one.
+func (lc *LightChain) SetHeadWithTimestamp(timestamp uint64) error {
+	header := lc.hc.CurrentHeader()
+	for header != nil && header.Number.Sign() > 0 && header.Time > timestamp {
+		header = lc.hc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	if header == nil {
+		return errors.New("missing header during timestamp rewind")
+	}
+	return lc.SetHead(header.Number.Uint64())
+}
+
 // GasLimit returns the gas limit of the current HEAD block.
 func (lc *LightChain) GasLimit() uint64 {
 	return lc.hc.CurrentHeader().GasLimit
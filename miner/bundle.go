@@ -0,0 +1,301 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// maxBundles is the maximum number of bundles waiting for inclusion.
+const maxBundles = 1024
+
+var (
+	bundleSimulatedMeter = metrics.NewRegisteredMeter("miner/bundles/simulated", nil)
+	bundleIncludedMeter  = metrics.NewRegisteredMeter("miner/bundles/included", nil)
+)
+
+var (
+	errBundleEmpty     = errors.New("bundle has no transactions")
+	errBundleTimestamp = errors.New("bundle max timestamp below min timestamp")
+	errBundleBlob      = errors.New("blob transactions are not supported in bundles")
+	errBundleStale     = errors.New("bundle target block already mined")
+	errBundlePoolFull  = errors.New("bundle pool is full")
+)
+
+// Bundle is an ordered list of transactions which is included atomically, at
+// the top of a specific block, or not at all.
+type Bundle struct {
+	Txs          types.Transactions
+	BlockNumber  uint64 // Number of the block the bundle is valid for
+	MinTimestamp uint64 // Minimum block timestamp, zero if unrestricted
+	MaxTimestamp uint64 // Maximum block timestamp, zero if unrestricted
+
+	// RevertingTxHashes are the transactions allowed to fail without
+	// invalidating the bundle.
+	RevertingTxHashes []common.Hash
+}
+
+// Hash returns the identifier of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// canRevert reports whether the given transaction of the bundle may fail.
+func (b *Bundle) canRevert(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the bundle targets a block with the given number and
+// timestamp.
+func (b *Bundle) matches(number, timestamp uint64) bool {
+	if b.BlockNumber != number {
+		return false
+	}
+	if b.MinTimestamp != 0 && timestamp < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && timestamp > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// newBundleEvent is posted when a bundle is added to the bundle pool.
+type newBundleEvent struct {
+	bundle *Bundle
+}
+
+// bundlePool holds the bundles submitted for inclusion in upcoming blocks.
+type bundlePool struct {
+	bundles map[common.Hash]*Bundle
+	feed    event.Feed // Feed of the added bundles, as newBundleEvent
+	mu      sync.Mutex
+}
+
+func newBundlePool() *bundlePool {
+	return &bundlePool{bundles: make(map[common.Hash]*Bundle)}
+}
+
+// add validates a bundle and adds it to the pool, dropping the bundles whose
+// target block precedes the given one.
+func (p *bundlePool) add(bundle *Bundle, head uint64) (common.Hash, error) {
+	if len(bundle.Txs) == 0 {
+		return common.Hash{}, errBundleEmpty
+	}
+	if bundle.MaxTimestamp != 0 && bundle.MaxTimestamp < bundle.MinTimestamp {
+		return common.Hash{}, errBundleTimestamp
+	}
+	for _, tx := range bundle.Txs {
+		if tx.Type() == types.BlobTxType {
+			return common.Hash{}, errBundleBlob
+		}
+	}
+	if bundle.BlockNumber <= head {
+		return common.Hash{}, fmt.Errorf("%w: target %d, head %d", errBundleStale, bundle.BlockNumber, head)
+	}
+	p.mu.Lock()
+	p.prune(head + 1)
+	if len(p.bundles) >= maxBundles {
+		p.mu.Unlock()
+		return common.Hash{}, errBundlePoolFull
+	}
+	hash := bundle.Hash()
+	p.bundles[hash] = bundle
+	p.mu.Unlock()
+
+	p.feed.Send(newBundleEvent{bundle: bundle})
+	return hash, nil
+}
+
+// subscribe registers a subscription of newBundleEvent, delivering the bundles
+// added to the pool.
+func (p *bundlePool) subscribe(ch chan<- newBundleEvent) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+// prune drops the bundles targeting blocks before the given one. The caller
+// must hold the lock.
+func (p *bundlePool) prune(number uint64) {
+	for hash, bundle := range p.bundles {
+		if bundle.BlockNumber < number {
+			delete(p.bundles, hash)
+		}
+	}
+}
+
+// matching returns the bundles eligible for a block with the given number and
+// timestamp.
+func (p *bundlePool) matching(number, timestamp uint64) []*Bundle {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prune(number)
+
+	var bundles []*Bundle
+	for _, bundle := range p.bundles {
+		if bundle.matches(number, timestamp) {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// simulatedBundle is a bundle along with the result of executing it on top of
+// the pending state.
+type simulatedBundle struct {
+	bundle  *Bundle
+	payment *big.Int // Balance increase of the coinbase
+	gasUsed uint64
+	price   *big.Int // Effective gas price paid to the coinbase, payment/gasUsed
+}
+
+// applyBundle executes the transactions of a bundle on top of the given
+// environment, returning the payment to the coinbase and the gas used. An
+// error is returned if any transaction can't be included, or it fails without
+// being allowed to.
+func (w *worker) applyBundle(env *environment, bundle *Bundle) (*big.Int, uint64, error) {
+	var (
+		before  = env.state.GetBalance(env.coinbase)
+		gasUsed = env.header.GasUsed
+	)
+	for _, tx := range bundle.Txs {
+		if tx.Protected() && !w.chainConfig.IsEIP155(env.header.Number) {
+			return nil, 0, fmt.Errorf("tx %x: replay protected before EIP-155", tx.Hash())
+		}
+		env.state.Prepare(tx.Hash(), env.tcount)
+		if _, err := w.commitTransaction(env, tx); err != nil {
+			return nil, 0, fmt.Errorf("tx %x: %w", tx.Hash(), err)
+		}
+		env.tcount++
+
+		receipt := env.receipts[len(env.receipts)-1]
+		if receipt.Status == types.ReceiptStatusFailed && !bundle.canRevert(tx.Hash()) {
+			return nil, 0, fmt.Errorf("tx %x: reverted", tx.Hash())
+		}
+	}
+	payment := new(big.Int).Sub(env.state.GetBalance(env.coinbase), before)
+	return payment, env.header.GasUsed - gasUsed, nil
+}
+
+// simulateBundles executes every bundle on top of the given environment, and
+// returns the ones which can be included, ordered by their effective gas price.
+// Simulation is aborted if the interrupt signal is raised.
+func (w *worker) simulateBundles(env *environment, bundles []*Bundle, interrupt *int32) ([]*simulatedBundle, error) {
+	var simulated []*simulatedBundle
+	for _, bundle := range bundles {
+		if interrupt != nil {
+			if signal := atomic.LoadInt32(interrupt); signal != commitInterruptNone {
+				return nil, signalToErr(signal)
+			}
+		}
+		work := env.copy()
+		payment, gasUsed, err := w.applyBundle(work, bundle)
+		work.discard()
+
+		bundleSimulatedMeter.Mark(1)
+		if err != nil {
+			log.Trace("Bundle simulation failed", "hash", bundle.Hash(), "err", err)
+			continue
+		}
+		if payment.Sign() <= 0 || gasUsed == 0 {
+			continue
+		}
+		simulated = append(simulated, &simulatedBundle{
+			bundle:  bundle,
+			payment: payment,
+			gasUsed: gasUsed,
+			price:   new(big.Int).Div(payment, new(big.Int).SetUint64(gasUsed)),
+		})
+	}
+	sort.SliceStable(simulated, func(i, j int) bool {
+		if c := simulated[i].price.Cmp(simulated[j].price); c != 0 {
+			return c > 0
+		}
+		return simulated[i].payment.Cmp(simulated[j].payment) > 0
+	})
+	return simulated, nil
+}
+
+// commitBundles fills the given environment with the best non-conflicting
+// bundles targeting the block. Bundles are merged greedily by their simulated
+// effective gas price. A bundle conflicts if, executed after the ones merged
+// before it, any of its transactions can't be included or fails, or it pays
+// less to the coinbase than simulated.
+func (w *worker) commitBundles(env *environment, interrupt *int32) error {
+	bundles := w.bundles.matching(env.header.Number.Uint64(), env.header.Time)
+	if len(bundles) == 0 {
+		return nil
+	}
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	simulated, err := w.simulateBundles(env, bundles, interrupt)
+	if err != nil {
+		return err
+	}
+
+	for _, sim := range simulated {
+		if interrupt != nil {
+			if signal := atomic.LoadInt32(interrupt); signal != commitInterruptNone {
+				return signalToErr(signal)
+			}
+		}
+		if env.gasPool.Gas() < sim.gasUsed {
+			continue
+		}
+		work := env.copy()
+		payment, _, err := w.applyBundle(work, sim.bundle)
+		if err == nil && payment.Cmp(sim.payment) < 0 {
+			err = fmt.Errorf("payment dropped from %v to %v", sim.payment, payment)
+		}
+		if err != nil {
+			log.Debug("Skipping conflicting bundle", "hash", sim.bundle.Hash(), "err", err)
+			work.discard()
+			continue
+		}
+		// Adopt the environment including the bundle. The prefetcher of the
+		// replaced state is terminated, the copy only holds an inactive one.
+		env.discard()
+		*env = *work
+
+		bundleIncludedMeter.Mark(1)
+		log.Debug("Included bundle", "hash", sim.bundle.Hash(), "txs", len(sim.bundle.Txs), "payment", payment)
+	}
+	return nil
+}
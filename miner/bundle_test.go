@@ -0,0 +1,237 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestBundlePoolAdd(t *testing.T) {
+	signer := types.LatestSigner(params.TestChainConfig)
+	tx := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		To:       &testUserAddress,
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	next := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		Nonce:    1,
+		To:       &testUserAddress,
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	blob := types.NewTx(&types.BlobTx{Gas: params.TxGas})
+	var (
+		pool  = newBundlePool()
+		tests = []struct {
+			bundle *Bundle
+			err    error
+		}{
+			{&Bundle{BlockNumber: 11}, errBundleEmpty},
+			{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 10}, errBundleStale},
+			{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 11, MinTimestamp: 2, MaxTimestamp: 1}, errBundleTimestamp},
+			{&Bundle{Txs: types.Transactions{blob}, BlockNumber: 11}, errBundleBlob},
+			{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 11, MinTimestamp: 1, MaxTimestamp: 1}, nil},
+			{&Bundle{Txs: types.Transactions{next}, BlockNumber: 12}, nil},
+		}
+	)
+	for i, test := range tests {
+		if _, err := pool.add(test.bundle, 10); !errors.Is(err, test.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+	}
+	if have := len(pool.matching(11, 1)); have != 1 {
+		t.Errorf("matching bundles mismatch: have %d, want 1", have)
+	}
+	if have := len(pool.matching(11, 2)); have != 0 {
+		t.Errorf("matching bundles after max timestamp: have %d, want 0", have)
+	}
+	// Bundles for past blocks are dropped once a later block is built.
+	pool.matching(12, 0)
+	if have := len(pool.bundles); have != 1 {
+		t.Errorf("pooled bundles mismatch: have %d, want 1", have)
+	}
+}
+
+func TestBundleInclusion(t *testing.T) {
+	var (
+		signer   = types.LatestSigner(ethashChainConfig)
+		coinbase = common.HexToAddress("0xc014ba5e")
+
+		// Transaction creating a contract whose constructor reverts.
+		reverting = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Gas:      100000,
+			GasPrice: big.NewInt(5 * params.InitialBaseFee),
+			Data:     []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)},
+		})
+	)
+	transfer := func(nonce uint64, price int64) *types.Transaction {
+		return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(price * params.InitialBaseFee),
+		})
+	}
+	var (
+		low  = &Bundle{Txs: types.Transactions{transfer(0, 2)}, BlockNumber: 1}
+		high = &Bundle{Txs: types.Transactions{transfer(0, 5), transfer(1, 5)}, BlockNumber: 1}
+	)
+	tests := []struct {
+		bundles []*Bundle
+		want    types.Transactions
+	}{
+		// No bundles, the pending transaction of the pool is included.
+		{nil, pendingTxs},
+		// Bundles are placed before the pool transactions, here replacing the
+		// one with the same nonce.
+		{[]*Bundle{low}, low.Txs},
+		// Conflicting bundles, only the best paying one is included.
+		{[]*Bundle{low, high}, high.Txs},
+		// Bundles for other blocks or timestamps are ignored.
+		{[]*Bundle{{Txs: types.Transactions{transfer(0, 5)}, BlockNumber: 2}}, pendingTxs},
+		{[]*Bundle{{Txs: types.Transactions{transfer(0, 5)}, BlockNumber: 1, MinTimestamp: ^uint64(0)}}, pendingTxs},
+		// Bundles with invalid or unexpectedly failing transactions are dropped.
+		{[]*Bundle{{Txs: types.Transactions{transfer(1, 5)}, BlockNumber: 1}}, pendingTxs},
+		{[]*Bundle{{Txs: types.Transactions{reverting}, BlockNumber: 1}}, pendingTxs},
+		{[]*Bundle{{Txs: types.Transactions{reverting}, BlockNumber: 1, RevertingTxHashes: []common.Hash{reverting.Hash()}}}, types.Transactions{reverting}},
+	}
+	for i, test := range tests {
+		engine := ethash.NewFaker()
+		w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+
+		for _, bundle := range test.bundles {
+			if _, err := w.bundles.add(bundle, 0); err != nil {
+				t.Fatalf("test %d: failed to add bundle: %v", i, err)
+			}
+		}
+		req, err := w.getSealingBlock(b.chain.Genesis().Hash(), uint64(time.Now().Unix()), coinbase, common.Hash{}, nil, false)
+		if err != nil {
+			t.Fatalf("test %d: failed to request block: %v", i, err)
+		}
+		block, _, err := <-req.result, <-req.value, <-req.err
+		if err != nil {
+			t.Fatalf("test %d: failed to build block: %v", i, err)
+		}
+		if have, want := len(block.Transactions()), len(test.want); have != want {
+			t.Errorf("test %d: transaction count mismatch: have %d, want %d", i, have, want)
+		} else {
+			for j, tx := range block.Transactions() {
+				if tx.Hash() != test.want[j].Hash() {
+					t.Errorf("test %d: tx %d mismatch: have %x, want %x", i, j, tx.Hash(), test.want[j].Hash())
+				}
+			}
+		}
+		w.close()
+		engine.Close()
+	}
+}
+
+// Tests that the value of a block includes the direct payments of bundles to the
+// fee recipient, not only the priority fees.
+func TestBundleCoinbasePayment(t *testing.T) {
+	var (
+		signer   = types.LatestSigner(ethashChainConfig)
+		coinbase = common.HexToAddress("0xc014ba5e")
+		payment  = big.NewInt(params.GWei)
+	)
+	build := func(to common.Address) *big.Int {
+		engine := ethash.NewFaker()
+		defer engine.Close()
+
+		w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+		defer w.close()
+
+		tx := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			To:       &to,
+			Value:    payment,
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(5 * params.InitialBaseFee),
+		})
+		if _, err := w.bundles.add(&Bundle{Txs: types.Transactions{tx}, BlockNumber: 1}, 0); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+		req, err := w.getSealingBlock(b.chain.Genesis().Hash(), uint64(time.Now().Unix()), coinbase, common.Hash{}, nil, false)
+		if err != nil {
+			t.Fatalf("failed to request block: %v", err)
+		}
+		block, value, err := <-req.result, <-req.value, <-req.err
+		if err != nil {
+			t.Fatalf("failed to build block: %v", err)
+		}
+		if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != tx.Hash() {
+			t.Fatalf("bundle not included")
+		}
+		return value
+	}
+	// The bundles only differ in the recipient of the transferred value
+	if have, want := new(big.Int).Sub(build(coinbase), build(testUserAddress)), payment; have.Cmp(want) != 0 {
+		t.Fatalf("block value difference mismatch: have %v, want %v", have, want)
+	}
+}
+
+// Tests that bundle simulation stops as soon as block building is interrupted,
+// instead of simulating every pending bundle past the deadline.
+func TestBundleSimulationInterrupt(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	signer := types.LatestSigner(ethashChainConfig)
+	tx := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		To:       &testUserAddress,
+		Value:    big.NewInt(1),
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(5 * params.InitialBaseFee),
+	})
+	bundle := &Bundle{Txs: types.Transactions{tx}, BlockNumber: 1}
+	if _, err := w.bundles.add(bundle, 0); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	work, err := w.prepareWork(&generateParams{
+		timestamp:  uint64(time.Now().Unix()),
+		parentHash: b.chain.Genesis().Hash(),
+	})
+	if err != nil {
+		t.Fatalf("failed to prepare work: %v", err)
+	}
+	defer work.discard()
+
+	interrupt := new(int32)
+	*interrupt = commitInterruptTimeout
+	if simulated, err := w.simulateBundles(work, []*Bundle{bundle}, interrupt); !errors.Is(err, errBlockInterruptedByTimeout) || simulated != nil {
+		t.Fatalf("simulation not interrupted: %d bundles simulated, err %v", len(simulated), err)
+	}
+	if err := w.commitBundles(work, interrupt); !errors.Is(err, errBlockInterruptedByTimeout) {
+		t.Fatalf("error mismatch: have %v, want %v", err, errBlockInterruptedByTimeout)
+	}
+	if len(work.txs) != 0 {
+		t.Fatalf("bundle included after interrupt: %d txs", len(work.txs))
+	}
+}
@@ -77,6 +77,9 @@ type Miner struct {
 	startCh  chan common.Address
 	stopCh   chan struct{}
 
+	lastPayload     *BuildPayloadArgs // Parameters of the latest payload requested
+	lastPayloadLock sync.Mutex
+
 	wg sync.WaitGroup
 }
 
@@ -245,32 +248,63 @@ func (miner *Miner) DisablePreseal() {
 	miner.worker.disablePreseal()
 }
 
+// AddBundle submits a bundle for inclusion at the top of the payloads built
+// for its target block, and returns the bundle hash.
+func (miner *Miner) AddBundle(bundle *Bundle) (common.Hash, error) {
+	return miner.worker.bundles.add(bundle, miner.eth.BlockChain().CurrentBlock().NumberU64())
+}
+
 // SubscribePendingLogs starts delivering logs from pending transactions
 // to the given channel.
 func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscription {
 	return miner.worker.pendingLogsFeed.Subscribe(ch)
 }
 
+// BuildPayload builds the payload according to the provided parameters, and
+// keeps improving it in the background until it's resolved.
+func (miner *Miner) BuildPayload(args *BuildPayloadArgs) (*Payload, error) {
+	miner.lastPayloadLock.Lock()
+	miner.lastPayload = args
+	miner.lastPayloadLock.Unlock()
+
+	return miner.worker.buildPayload(args)
+}
+
+// LastPayloadArgs returns the parameters of the latest payload requested, or
+// nil if none was yet.
+func (miner *Miner) LastPayloadArgs() *BuildPayloadArgs {
+	miner.lastPayloadLock.Lock()
+	defer miner.lastPayloadLock.Unlock()
+
+	if miner.lastPayload == nil {
+		return nil
+	}
+	args := *miner.lastPayload
+	return &args
+}
+
 // GetSealingBlockAsync requests to generate a sealing block according to the
 // given parameters. Regardless of whether the generation is successful or not,
-// there is always a result that will be returned through the result channel.
-// The difference is that if the execution fails, the returned result is nil
-// and the concrete error is dropped silently.
-func (miner *Miner) GetSealingBlockAsync(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (chan *types.Block, error) {
-	resCh, _, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, noTxs)
+// there is always a result that will be returned through the result channel,
+// along with the value delivered to the fee recipient by the block through the
+// value channel. The difference is that if the execution fails, the returned results
+// are nil and the concrete error is dropped silently.
+func (miner *Miner) GetSealingBlockAsync(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, withdrawals types.Withdrawals, noTxs bool) (chan *types.Block, chan *big.Int, error) {
+	req, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, withdrawals, noTxs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return resCh, nil
+	return req.result, req.value, nil
 }
 
-// GetSealingBlockSync creates a sealing block according to the given parameters.
-// If the generation is failed or the underlying work is already closed, an error
+// GetSealingBlockSync creates a sealing block according to the given parameters
+// and returns it along with the value it delivers to the fee recipient. If the
+// generation is failed or the underlying work is already closed, an error
 // will be returned.
-func (miner *Miner) GetSealingBlockSync(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (*types.Block, error) {
-	resCh, errCh, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, noTxs)
+func (miner *Miner) GetSealingBlockSync(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, withdrawals types.Withdrawals, noTxs bool) (*types.Block, *big.Int, error) {
+	req, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, withdrawals, noTxs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return <-resCh, <-errCh
+	return <-req.result, <-req.value, <-req.err
 }
@@ -0,0 +1,255 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// payloadLifetime is the maximum amount of time a payload keeps being
+	// improved for if it's not resolved, the length of a slot.
+	payloadLifetime = 12 * time.Second
+
+	// payloadResolveTimeout is the maximum amount of time a payload resolution
+	// waits for the first candidate including transactions, before falling
+	// back to the empty one.
+	payloadResolveTimeout = 500 * time.Millisecond
+)
+
+var (
+	payloadRebuildMeter     = metrics.NewRegisteredMeter("miner/payload/rebuilds", nil)
+	payloadImprovementMeter = metrics.NewRegisteredMeter("miner/payload/improvements", nil)
+
+	// payloadImprovementTimer measures the time from the start of building a
+	// payload until a better candidate was found.
+	payloadImprovementTimer = metrics.NewRegisteredTimer("miner/payload/improvementtime", nil)
+
+	// payloadImprovementHistogram tracks the value gained by the best candidate
+	// of a payload over its first one, in gwei.
+	payloadImprovementHistogram = metrics.NewRegisteredHistogram("miner/payload/improvement", nil, metrics.NewExpDecaySample(1028, 0.015))
+)
+
+// BuildPayloadArgs are the parameters of a payload to build.
+type BuildPayloadArgs struct {
+	Parent       common.Hash       // The parent block to build the payload on top of
+	Timestamp    uint64            // The timestamp of the payload
+	FeeRecipient common.Address    // The recipient of the payload fees
+	Random       common.Hash       // The randomness of the payload
+	Withdrawals  types.Withdrawals // The withdrawals processed by the payload
+}
+
+// Payload is a block being built for a beacon client. Starting from an empty
+// block, it's rebuilt in the background as new transactions arrive, retaining
+// the candidate delivering the most value to the fee recipient until the
+// payload is resolved.
+type Payload struct {
+	empty     *types.Block
+	full      *types.Block // Best candidate including transactions built so far
+	fullValue *big.Int     // Value delivered to the fee recipient by the best candidate
+	first     *big.Int     // Value delivered by the first candidate including transactions
+	start     time.Time
+	lock      sync.Mutex
+
+	built     chan struct{} // Closed when the first candidate was built, or building ended
+	builtOnce sync.Once
+	stop      chan struct{} // Closed when the payload is resolved
+	stopOnce  sync.Once
+}
+
+func newPayload(empty *types.Block) *Payload {
+	return &Payload{
+		empty: empty,
+		start: time.Now(),
+		built: make(chan struct{}),
+		stop:  make(chan struct{}),
+	}
+}
+
+// update records a newly built candidate, retaining it if it delivers more
+// value than the best one so far.
+func (p *Payload) update(block *types.Block, value *big.Int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	select {
+	case <-p.stop:
+		return // Already delivered, don't swap the block under the beacon client
+	default:
+	}
+	switch {
+	case p.full == nil:
+		p.first = value
+	case value.Cmp(p.fullValue) > 0:
+		payloadImprovementMeter.Mark(1)
+		payloadImprovementTimer.UpdateSince(p.start)
+		payloadImprovementHistogram.Update(new(big.Int).Div(new(big.Int).Sub(value, p.first), big.NewInt(params.GWei)).Int64())
+	default:
+		p.markBuilt()
+		return
+	}
+	p.full, p.fullValue = block, value
+	p.markBuilt()
+
+	log.Debug("Updated payload", "number", block.NumberU64(), "hash", block.Hash(), "txs", len(block.Transactions()),
+		"gas", block.GasUsed(), "value", value, "elapsed", common.PrettyDuration(time.Since(p.start)))
+}
+
+// markBuilt signals that no more waiting for a first candidate is needed.
+func (p *Payload) markBuilt() {
+	p.builtOnce.Do(func() { close(p.built) })
+}
+
+// Resolve stops improving the payload and returns the best candidate built so
+// far. If there's none including transactions yet, it waits a bit for one
+// before falling back to the empty block.
+func (p *Payload) Resolve() *beacon.ExecutionPayloadEnvelope {
+	timer := time.NewTimer(payloadResolveTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-p.built:
+	case <-timer.C:
+	}
+	return p.resolve()
+}
+
+// ResolveFull stops improving the payload and returns the best candidate built
+// so far, waiting for the first one including transactions if needed. The
+// empty block is only returned if building failed.
+func (p *Payload) ResolveFull() *beacon.ExecutionPayloadEnvelope {
+	<-p.built
+	return p.resolve()
+}
+
+// Stop stops improving the payload without resolving it, e.g. once it's not
+// tracked anymore by the beacon client API.
+func (p *Payload) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+func (p *Payload) resolve() *beacon.ExecutionPayloadEnvelope {
+	p.Stop()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.full != nil {
+		return &beacon.ExecutionPayloadEnvelope{
+			ExecutionPayload: beacon.BlockToExecutableData(p.full),
+			BlockValue:       new(big.Int).Set(p.fullValue),
+		}
+	}
+	return &beacon.ExecutionPayloadEnvelope{
+		ExecutionPayload: beacon.BlockToExecutableData(p.empty),
+		BlockValue:       new(big.Int),
+	}
+}
+
+// buildPayload builds the empty version of a payload synchronously, and keeps
+// improving it in the background until resolved. A new candidate is built as
+// soon as transactions or bundles for the payload arrive, but at most once per
+// recommit interval.
+func (w *worker) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
+	empty, _, err := w.buildPayloadBlock(args, true)
+	if err != nil {
+		return nil, err
+	}
+	payload := newPayload(empty)
+
+	// Subscribe before building the first candidate, not to miss any transactions
+	// or bundles.
+	txs := make(chan core.NewTxsEvent, txChanSize)
+	sub := w.eth.TxPool().SubscribeNewTxsEvent(txs)
+	bundles := make(chan newBundleEvent, txChanSize)
+	bundleSub := w.bundles.subscribe(bundles)
+
+	go func() {
+		defer sub.Unsubscribe()
+		defer bundleSub.Unsubscribe()
+		defer payload.markBuilt()
+
+		var (
+			end     = time.NewTimer(payloadLifetime)
+			rebuild = time.NewTimer(0)
+			armed   = true // Whether a rebuild is scheduled
+			last    time.Time
+		)
+		defer end.Stop()
+		defer rebuild.Stop()
+
+		// Arrivals during a build are picked up right after it, scheduling the
+		// next one.
+		schedule := func() {
+			if !armed {
+				rebuild.Reset(w.recommit - time.Since(last))
+				armed = true
+			}
+		}
+		for {
+			select {
+			case <-rebuild.C:
+				armed, last = false, time.Now()
+
+				block, value, err := w.buildPayloadBlock(args, false)
+				if err != nil {
+					log.Warn("Failed to build payload", "err", err)
+					payload.markBuilt() // Don't keep resolvers waiting for a full candidate
+				} else {
+					payloadRebuildMeter.Mark(1)
+					payload.update(block, value)
+				}
+			case <-txs:
+				schedule()
+			case ev := <-bundles:
+				if ev.bundle.matches(empty.NumberU64(), args.Timestamp) {
+					schedule()
+				}
+			case <-payload.stop:
+				return
+			case <-end.C:
+				return
+			case <-sub.Err():
+				return
+			case <-bundleSub.Err():
+				return
+			case <-w.exitCh:
+				return
+			}
+		}
+	}()
+	return payload, nil
+}
+
+// buildPayloadBlock builds a single candidate of a payload.
+func (w *worker) buildPayloadBlock(args *BuildPayloadArgs, noTxs bool) (*types.Block, *big.Int, error) {
+	req, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, args.Withdrawals, noTxs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return <-req.result, <-req.value, <-req.err
+}
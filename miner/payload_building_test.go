@@ -0,0 +1,182 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a payload is rebuilt as transactions arrive, retaining the best
+// candidate, and that it stops being improved once resolved.
+func TestBuildPayload(t *testing.T) {
+	config := new(params.ChainConfig)
+	*config = *ethashChainConfig
+	config.TerminalTotalDifficulty = big.NewInt(0)
+
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, config, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	coinbase := common.Address{0x01}
+	payload, err := w.buildPayload(&BuildPayloadArgs{
+		Parent:       b.chain.CurrentBlock().Hash(),
+		Timestamp:    uint64(time.Now().Unix()),
+		FeeRecipient: coinbase,
+		Random:       common.Hash{0x02},
+	})
+	if err != nil {
+		t.Fatalf("failed to build payload: %v", err)
+	}
+	waitCandidate := func(txs int) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			payload.lock.Lock()
+			built := payload.full != nil && len(payload.full.Transactions()) == txs
+			payload.lock.Unlock()
+			if built {
+				return
+			}
+		}
+		t.Fatalf("no candidate with %d transactions built", txs)
+	}
+	// The first candidate includes the pending transaction of the pool.
+	waitCandidate(len(pendingTxs))
+
+	payload.lock.Lock()
+	firstValue := new(big.Int).Set(payload.fullValue)
+	payload.lock.Unlock()
+	if firstValue.Sign() <= 0 {
+		t.Fatalf("first candidate delivers no value: %v", firstValue)
+	}
+	// Arriving transactions make the payload improve.
+	b.txPool.AddLocals(newTxs)
+	waitCandidate(len(pendingTxs) + len(newTxs))
+
+	envelope := payload.Resolve()
+	if have, want := len(envelope.ExecutionPayload.Transactions), len(pendingTxs)+len(newTxs); have != want {
+		t.Fatalf("transaction count mismatch: have %d, want %d", have, want)
+	}
+	if envelope.BlockValue.Cmp(firstValue) <= 0 {
+		t.Errorf("payload value not improved: have %v, first %v", envelope.BlockValue, firstValue)
+	}
+	if envelope.ExecutionPayload.FeeRecipient != coinbase {
+		t.Errorf("fee recipient mismatch: have %x, want %x", envelope.ExecutionPayload.FeeRecipient, coinbase)
+	}
+	// Once resolved, the payload doesn't change anymore.
+	tx := b.newRandomTx(false)
+	if err := b.txPool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	time.Sleep(2 * testConfig.Recommit)
+	if again := payload.ResolveFull(); again.ExecutionPayload.BlockHash != envelope.ExecutionPayload.BlockHash {
+		t.Errorf("resolved payload changed: have %x, want %x", again.ExecutionPayload.BlockHash, envelope.ExecutionPayload.BlockHash)
+	}
+}
+
+// Tests that a payload is rebuilt as bundles for it arrive.
+func TestBuildPayloadBundle(t *testing.T) {
+	config := new(params.ChainConfig)
+	*config = *ethashChainConfig
+	config.TerminalTotalDifficulty = big.NewInt(0)
+
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, config, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	payload, err := w.buildPayload(&BuildPayloadArgs{
+		Parent:    b.chain.CurrentBlock().Hash(),
+		Timestamp: uint64(time.Now().Unix()),
+	})
+	if err != nil {
+		t.Fatalf("failed to build payload: %v", err)
+	}
+	defer payload.Stop()
+
+	waitCandidate := func(first common.Hash) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			payload.lock.Lock()
+			built := payload.full != nil && len(payload.full.Transactions()) > 0 && payload.full.Transactions()[0].Hash() == first
+			payload.lock.Unlock()
+			if built {
+				return
+			}
+		}
+		t.Fatalf("no candidate starting with transaction %x built", first)
+	}
+	waitCandidate(pendingTxs[0].Hash())
+
+	// A better paying bundle replacing the pending transaction makes the
+	// payload improve, without any transaction arriving in the pool.
+	tx := types.MustSignNewTx(testBankKey, types.LatestSigner(config), &types.LegacyTx{
+		To:       &testUserAddress,
+		Value:    big.NewInt(1),
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(5 * params.InitialBaseFee),
+	})
+	if _, err := w.bundles.add(&Bundle{Txs: types.Transactions{tx}, BlockNumber: 1}, 0); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	waitCandidate(tx.Hash())
+}
+
+// Tests that stopping a payload ends its background building without waiting
+// for it to be resolved.
+func TestStopPayload(t *testing.T) {
+	config := new(params.ChainConfig)
+	*config = *ethashChainConfig
+	config.TerminalTotalDifficulty = big.NewInt(0)
+
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, config, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	payload, err := w.buildPayload(&BuildPayloadArgs{
+		Parent:    b.chain.CurrentBlock().Hash(),
+		Timestamp: uint64(time.Now().Unix()),
+	})
+	if err != nil {
+		t.Fatalf("failed to build payload: %v", err)
+	}
+	payload.Stop()
+
+	// Building ends, so full resolutions don't wait for the payload lifetime.
+	done := make(chan struct{})
+	go func() {
+		payload.ResolveFull()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(payloadLifetime / 2):
+		t.Fatal("payload building not stopped")
+	}
+}
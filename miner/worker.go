@@ -173,6 +173,7 @@ type newWorkReq struct {
 type getWorkReq struct {
 	params *generateParams
 	result chan *types.Block // non-blocking channel
+	value  chan *big.Int     // non-blocking channel, value of the result to the fee recipient
 	err    chan error
 }
 
@@ -227,6 +228,8 @@ type worker struct {
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
 
+	bundles *bundlePool // Bundles submitted for inclusion at the top of payloads
+
 	snapshotMu       sync.RWMutex // The lock used to protect the snapshots below
 	snapshotBlock    *types.Block
 	snapshotReceipts types.Receipts
@@ -250,6 +253,9 @@ type worker struct {
 	// in case there are some computation expensive transactions in txpool.
 	newpayloadTimeout time.Duration
 
+	// recommit is the minimum interval between two rebuilds of a payload.
+	recommit time.Duration
+
 	// External functions
 	isLocalBlock func(header *types.Header) bool // Function used to determine whether the specified block is mined by local miner.
 
@@ -273,6 +279,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		remoteUncles:       make(map[common.Hash]*types.Block),
 		unconfirmed:        newUnconfirmedBlocks(eth.BlockChain(), sealingLogAtDepth),
 		pendingTasks:       make(map[common.Hash]*task),
+		bundles:            newBundlePool(),
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:        make(chan core.ChainHeadEvent, chainHeadChanSize),
 		chainSideCh:        make(chan core.ChainSideEvent, chainSideChanSize),
@@ -307,6 +314,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		log.Warn("Low payload timeout may cause high amount of non-full blocks", "provided", newpayloadTimeout, "default", DefaultConfig.NewPayloadTimeout)
 	}
 	worker.newpayloadTimeout = newpayloadTimeout
+	worker.recommit = recommit
 
 	worker.wg.Add(4)
 	go worker.mainLoop()
@@ -553,12 +561,14 @@ func (w *worker) mainLoop() {
 			w.commitWork(req.interrupt, req.noempty, req.timestamp)
 
 		case req := <-w.getWorkCh:
-			block, err := w.generateWork(req.params)
+			block, value, err := w.generateWork(req.params)
 			if err != nil {
 				req.err <- err
+				req.value <- nil
 				req.result <- nil
 			} else {
 				req.err <- nil
+				req.value <- value
 				req.result <- block
 			}
 		case ev := <-w.chainSideCh:
@@ -782,7 +792,7 @@ func (w *worker) makeEnv(parent *types.Block, header *types.Header, coinbase com
 
 	// Note the passed coinbase may be different with header.Coinbase.
 	env := &environment{
-		signer:    types.MakeSigner(w.chainConfig, header.Number),
+		signer:    types.MakeSigner(w.chainConfig, header.Number, header.Time),
 		state:     state,
 		coinbase:  coinbase,
 		ancestors: mapset.NewSet(),
@@ -849,6 +859,11 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 		env.state.RevertToSnapshot(snap)
 		return nil, err
 	}
+	// Blob sidecars are only propagated over the network, never included in blocks.
+	if tx.Type() == types.BlobTxType {
+		tx = tx.WithoutBlobTxSidecar()
+		*env.header.BlobGasUsed += tx.BlobGas()
+	}
 	env.txs = append(env.txs, tx)
 	env.receipts = append(env.receipts, receipt)
 
@@ -891,6 +906,19 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			txs.Pop()
 			continue
 		}
+		// If the transaction carries blobs, make sure they still fit into the block.
+		if blobGas := tx.BlobGas(); blobGas > 0 {
+			if env.header.BlobGasUsed == nil {
+				log.Trace("Ignoring blob transaction before Cancun", "hash", tx.Hash())
+				txs.Pop()
+				continue
+			}
+			if *env.header.BlobGasUsed+blobGas > params.MaxBlobGasPerBlock {
+				log.Trace("Not enough blob gas left for transaction", "hash", tx.Hash(), "left", params.MaxBlobGasPerBlock-*env.header.BlobGasUsed, "needed", blobGas)
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), env.tcount)
 
@@ -957,6 +985,8 @@ type generateParams struct {
 	noUncle    bool           // Flag whether the uncle block inclusion is allowed
 	noExtra    bool           // Flag whether the extra field assignment is allowed
 	noTxs      bool           // Flag whether an empty block without any transaction is expected
+
+	withdrawals types.Withdrawals // List of withdrawals to include in block, nil before Shanghai
 }
 
 // prepareWork constructs the sealing task according to the given parameters,
@@ -1007,6 +1037,18 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.config.GasCeil)
 		}
 	}
+	// Apply EIP-4844 blob gas accounting if we are on a Cancun chain
+	if w.chainConfig.IsCancun(header.Number, header.Time) {
+		var excessBlobGas uint64
+		if parentExcess := parent.ExcessBlobGas(); parentExcess != nil {
+			excessBlobGas = misc.CalcExcessBlobGas(*parentExcess, *parent.BlobGasUsed())
+		} else {
+			// For the first post-fork block, both parent.data_gas_used and parent.excess_data_gas are evaluated as 0
+			excessBlobGas = misc.CalcExcessBlobGas(0, 0)
+		}
+		header.BlobGasUsed = new(uint64)
+		header.ExcessBlobGas = &excessBlobGas
+	}
 	// Run the consensus preparation with the default or customized consensus engine.
 	if err := w.engine.Prepare(w.chain, header); err != nil {
 		log.Error("Failed to prepare header for sealing", "err", err)
@@ -1070,14 +1112,17 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment) error {
 	return nil
 }
 
-// generateWork generates a sealing block based on the given parameters.
-func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
+// generateWork generates a sealing block based on the given parameters and
+// returns it along with the value it delivers to the fee recipient.
+func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, error) {
 	work, err := w.prepareWork(params)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer work.discard()
 
+	before := new(big.Int).Set(work.state.GetBalance(work.coinbase))
+
 	if !params.noTxs {
 		interrupt := new(int32)
 		timer := time.AfterFunc(w.newpayloadTimeout, func() {
@@ -1085,12 +1130,36 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
 		})
 		defer timer.Stop()
 
-		err := w.fillTransactions(interrupt, work)
+		// Bundles are placed at the top of the block, before the pending
+		// transactions of the pool.
+		err := w.commitBundles(work, interrupt)
+		if err == nil {
+			err = w.fillTransactions(interrupt, work)
+		}
 		if errors.Is(err, errBlockInterruptedByTimeout) {
 			log.Warn("Block building is interrupted", "allowance", common.PrettyDuration(w.newpayloadTimeout))
 		}
 	}
-	return w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts)
+	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts, params.withdrawals)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, blockValue(work, before, params.withdrawals), nil
+}
+
+// blockValue returns the value delivered to the fee recipient by the block built
+// in the environment, given its balance before. Besides the priority fees, this
+// includes the direct payments of bundles, but not the withdrawals credited to
+// the fee recipient, which aren't paid by the block's transactions.
+func blockValue(env *environment, before *big.Int, withdrawals types.Withdrawals) *big.Int {
+	value := new(big.Int).Sub(env.state.GetBalance(env.coinbase), before)
+	for _, w := range withdrawals {
+		if w.Address == env.coinbase {
+			amount := new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), big.NewInt(params.GWei))
+			value.Sub(value, amount)
+		}
+	}
+	return value
 }
 
 // commitWork generates several new sealing tasks based on the parent block
@@ -1171,7 +1240,7 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 		// Create a local environment copy, avoid the data race with snapshot state.
 		// https://github.com/ethereum/go-ethereum/issues/24299
 		env := env.copy()
-		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts)
+		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts, nil)
 		if err != nil {
 			return err
 		}
@@ -1197,32 +1266,30 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 }
 
 // getSealingBlock generates the sealing block based on the given parameters.
-// The generation result will be passed back via the given channel no matter
-// the generation itself succeeds or not.
-func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (chan *types.Block, chan error, error) {
-	var (
-		resCh = make(chan *types.Block, 1)
-		errCh = make(chan error, 1)
-	)
+// The generation result will be passed back via the channels of the returned
+// request no matter the generation itself succeeds or not.
+func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, withdrawals types.Withdrawals, noTxs bool) (*getWorkReq, error) {
 	req := &getWorkReq{
 		params: &generateParams{
-			timestamp:  timestamp,
-			forceTime:  true,
-			parentHash: parent,
-			coinbase:   coinbase,
-			random:     random,
-			noUncle:    true,
-			noExtra:    true,
-			noTxs:      noTxs,
+			timestamp:   timestamp,
+			forceTime:   true,
+			parentHash:  parent,
+			coinbase:    coinbase,
+			random:      random,
+			withdrawals: withdrawals,
+			noUncle:     true,
+			noExtra:     true,
+			noTxs:       noTxs,
 		},
-		result: resCh,
-		err:    errCh,
+		result: make(chan *types.Block, 1),
+		value:  make(chan *big.Int, 1),
+		err:    make(chan error, 1),
 	}
 	select {
 	case w.getWorkCh <- req:
-		return resCh, errCh, nil
+		return req, nil
 	case <-w.exitCh:
-		return nil, nil, errors.New("miner closed")
+		return nil, errors.New("miner closed")
 	}
 }
 
@@ -1253,12 +1320,18 @@ func (w *worker) postSideBlock(event core.ChainSideEvent) {
 
 // totalFees computes total consumed miner fees in ETH. Block transactions and receipts have to have the same order.
 func totalFees(block *types.Block, receipts []*types.Receipt) *big.Float {
+	feesWei := totalFeesWei(block, receipts)
+	return new(big.Float).Quo(new(big.Float).SetInt(feesWei), new(big.Float).SetInt(big.NewInt(params.Ether)))
+}
+
+// totalFeesWei computes total consumed miner fees in Wei.
+func totalFeesWei(block *types.Block, receipts []*types.Receipt) *big.Int {
 	feesWei := new(big.Int)
 	for i, tx := range block.Transactions() {
 		minerFee, _ := tx.EffectiveGasTip(block.BaseFee())
 		feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), minerFee))
 	}
-	return new(big.Float).Quo(new(big.Float).SetInt(feesWei), new(big.Float).SetInt(big.NewInt(params.Ether)))
+	return feesWei
 }
 
 // signalToErr converts the interruption signal to a concrete error type for return.
@@ -135,7 +135,7 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 		t.Fatalf("unexpected consensus engine type: %T", engine)
 	}
 	chain, _ := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, gspec, nil, engine, vm.Config{}, nil, nil)
-	txpool := txpool.NewTxPool(testTxPoolConfig, chainConfig, chain)
+	txpool := txpool.New(chain, []txpool.SubPool{txpool.NewLegacyPool(testTxPoolConfig, chainConfig, chain)})
 
 	// Generate a small n-block chain and an uncle block for it
 	var uncle *types.Block
@@ -634,9 +634,9 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 
 	// This API should work even when the automatic sealing is not enabled
 	for _, c := range cases {
-		resChan, errChan, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
-		block := <-resChan
-		err := <-errChan
+		req, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, nil, false)
+		block := <-req.result
+		err := <-req.err
 		if c.expectErr {
 			if err == nil {
 				t.Error("Expect error but get nil")
@@ -652,9 +652,9 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 	// This API should work even when the automatic sealing is enabled
 	w.start()
 	for _, c := range cases {
-		resChan, errChan, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
-		block := <-resChan
-		err := <-errChan
+		req, _ := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, nil, false)
+		block := <-req.result
+		err := <-req.err
 		if c.expectErr {
 			if err == nil {
 				t.Error("Expect error but get nil")
@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -199,6 +200,15 @@ type Config struct {
 
 	// JWTSecret is the path to the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
+
+	// ReadOnly runs the node as a secondary instance on top of the data directory
+	// of a running primary node. The databases are opened read-only without taking
+	// the directory lock and the p2p server is not started.
+	ReadOnly bool `toml:",omitempty"`
+
+	// ReadOnlyRefresh is the interval at which a read-only node catches up with
+	// the writes of the primary instance.
+	ReadOnlyRefresh time.Duration `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -21,6 +21,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/nat"
@@ -36,6 +37,8 @@ const (
 	DefaultGraphQLPort = 8547        // Default TCP port for the GraphQL server
 	DefaultAuthHost    = "localhost" // Default host interface for the authenticated apis
 	DefaultAuthPort    = 8551        // Default port for the authenticated apis
+
+	DefaultReadOnlyRefresh = 3 * time.Second // Default interval for read-only nodes to catch up with the primary
 )
 
 var (
@@ -59,6 +62,7 @@ var DefaultConfig = Config{
 	WSPort:              DefaultWSPort,
 	WSModules:           []string{"net", "web3"},
 	GraphQLVirtualHosts: []string{"localhost"},
+	ReadOnlyRefresh:     DefaultReadOnlyRefresh,
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
@@ -33,6 +33,8 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/ethdb/remotefreezer"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -263,10 +265,12 @@ func (n *Node) doClose(errs []error) error {
 
 // openEndpoints starts all network and RPC endpoints.
 func (n *Node) openEndpoints() error {
-	// start networking endpoints
-	n.log.Info("Starting peer-to-peer node", "instance", n.server.Name)
-	if err := n.server.Start(); err != nil {
-		return convertFileLockError(err)
+	// start networking endpoints, read-only nodes are not part of the network
+	if !n.config.ReadOnly {
+		n.log.Info("Starting peer-to-peer node", "instance", n.server.Name)
+		if err := n.server.Start(); err != nil {
+			return convertFileLockError(err)
+		}
 	}
 	// start RPC endpoints
 	err := n.startRPC()
@@ -318,6 +322,10 @@ func (n *Node) openDataDir() error {
 	if err := os.MkdirAll(instdir, 0700); err != nil {
 		return err
 	}
+	// Read-only nodes run alongside the primary instance, which holds the lock.
+	if n.config.ReadOnly {
+		return nil
+	}
 	// Lock the instance directory to prevent concurrent use by another instance as well as
 	// accidental use of the instance directory as a database.
 	release, _, err := fileutil.Flock(filepath.Join(instdir, "LOCK"))
@@ -696,6 +704,11 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 	var err error
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
+	} else if n.config.ReadOnly {
+		var kvdb ethdb.KeyValueStore
+		if kvdb, err = leveldb.NewSecondary(n.ResolvePath(name), cache, handles); err == nil {
+			db = rawdb.NewDatabase(kvdb)
+		}
 	} else {
 		db, err = rawdb.NewLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace, readonly)
 	}
@@ -722,6 +735,12 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	var err error
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
+	} else if n.config.ReadOnly {
+		refresh := n.config.ReadOnlyRefresh
+		if refresh <= 0 {
+			refresh = DefaultReadOnlyRefresh
+		}
+		db, err = rawdb.NewLevelDBSecondaryDatabase(n.ResolvePath(name), cache, handles, n.ResolveAncient(name, ancient), refresh)
 	} else {
 		db, err = rawdb.NewLevelDBDatabaseWithFreezer(n.ResolvePath(name), cache, handles, n.ResolveAncient(name, ancient), namespace, readonly)
 	}
@@ -732,6 +751,56 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	return db, err
 }
 
+// OpenDatabaseWithRemoteFreezer opens an existing database with the given name
+// (or creates one if no previous can be found) from within the node's data
+// directory, attaching a chain freezer which moves ancient chain data into the
+// ancient store served by the remote freezer server at the given endpoint. If
+// the node is an ephemeral one, a memory database is returned.
+func (n *Node) OpenDatabaseWithRemoteFreezer(name string, cache, handles int, endpoint string, namespace string, readonly bool) (ethdb.Database, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.state == closedState {
+		return nil, ErrNodeStopped
+	}
+
+	var db ethdb.Database
+	var err error
+	if n.config.DataDir == "" {
+		db = rawdb.NewMemoryDatabase()
+	} else if n.config.ReadOnly {
+		// Secondary instances follow the primary's files on disk, which a
+		// remote ancient store cannot provide.
+		err = errReadOnlyRemoteFreezer
+	} else {
+		db, err = openDatabaseWithRemoteFreezer(n.ResolvePath(name), cache, handles, endpoint, namespace, readonly)
+	}
+
+	if err == nil {
+		db = n.wrapDatabase(db)
+	}
+	return db, err
+}
+
+// openDatabaseWithRemoteFreezer opens a persistent key-value database combined
+// with a remote ancient store.
+func openDatabaseWithRemoteFreezer(file string, cache int, handles int, endpoint string, namespace string, readonly bool) (ethdb.Database, error) {
+	kvdb, err := leveldb.New(file, cache, handles, namespace, readonly)
+	if err != nil {
+		return nil, err
+	}
+	store, err := remotefreezer.Dial(endpoint)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	db, err := rawdb.NewDatabaseWithAncientStore(kvdb, store, readonly)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
@@ -166,6 +166,20 @@ func TestNodeCloseClosesDB(t *testing.T) {
 	}
 }
 
+// This test checks that read-only instances refuse to attach a remote freezer
+// instead of opening the primary's database writable.
+func TestNodeReadOnlyRemoteFreezer(t *testing.T) {
+	config := testNodeConfig()
+	config.DataDir = t.TempDir()
+	config.ReadOnly = true
+	stack, _ := New(config)
+	defer stack.Close()
+
+	if _, err := stack.OpenDatabaseWithRemoteFreezer("mydb", 0, 0, "http://127.0.0.1:0", "", false); err != errReadOnlyRemoteFreezer {
+		t.Fatalf("error mismatch: have %v, want %v", err, errReadOnlyRemoteFreezer)
+	}
+}
+
 // This test checks that OpenDatabase can be used from within a Lifecycle Start method.
 func TestNodeOpenDatabaseFromLifecycleStart(t *testing.T) {
 	stack, _ := New(testNodeConfig())
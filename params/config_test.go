@@ -17,82 +17,210 @@
 package params
 
 import (
+	"math"
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
 	type test struct {
-		stored, new *ChainConfig
-		head        uint64
-		wantErr     *ConfigCompatError
+		stored, new   *ChainConfig
+		headBlock     uint64
+		headTimestamp uint64
+		wantErr       *ConfigCompatError
 	}
 	tests := []test{
-		{stored: AllEthashProtocolChanges, new: AllEthashProtocolChanges, head: 0, wantErr: nil},
-		{stored: AllEthashProtocolChanges, new: AllEthashProtocolChanges, head: 100, wantErr: nil},
+		{stored: AllEthashProtocolChanges, new: AllEthashProtocolChanges, headBlock: 0, headTimestamp: 0, wantErr: nil},
+		{stored: AllEthashProtocolChanges, new: AllEthashProtocolChanges, headBlock: 100, wantErr: nil},
+		{
+			stored:    &ChainConfig{EIP150Block: big.NewInt(10)},
+			new:       &ChainConfig{EIP150Block: big.NewInt(20)},
+			headBlock: 9,
+			wantErr:   nil,
+		},
+		{
+			stored:    AllEthashProtocolChanges,
+			new:       &ChainConfig{HomesteadBlock: nil},
+			headBlock: 3,
+			wantErr: &ConfigCompatError{
+				What:          "Homestead fork block",
+				StoredBlock:   big.NewInt(0),
+				NewBlock:      nil,
+				RewindToBlock: 0,
+			},
+		},
 		{
-			stored:  &ChainConfig{EIP150Block: big.NewInt(10)},
-			new:     &ChainConfig{EIP150Block: big.NewInt(20)},
-			head:    9,
-			wantErr: nil,
+			stored:    AllEthashProtocolChanges,
+			new:       &ChainConfig{HomesteadBlock: big.NewInt(1)},
+			headBlock: 3,
+			wantErr: &ConfigCompatError{
+				What:          "Homestead fork block",
+				StoredBlock:   big.NewInt(0),
+				NewBlock:      big.NewInt(1),
+				RewindToBlock: 0,
+			},
 		},
 		{
-			stored: AllEthashProtocolChanges,
-			new:    &ChainConfig{HomesteadBlock: nil},
-			head:   3,
+			stored:    &ChainConfig{HomesteadBlock: big.NewInt(30), EIP150Block: big.NewInt(10)},
+			new:       &ChainConfig{HomesteadBlock: big.NewInt(25), EIP150Block: big.NewInt(20)},
+			headBlock: 25,
 			wantErr: &ConfigCompatError{
-				What:         "Homestead fork block",
-				StoredConfig: big.NewInt(0),
-				NewConfig:    nil,
-				RewindTo:     0,
+				What:          "EIP150 fork block",
+				StoredBlock:   big.NewInt(10),
+				NewBlock:      big.NewInt(20),
+				RewindToBlock: 9,
 			},
 		},
 		{
-			stored: AllEthashProtocolChanges,
-			new:    &ChainConfig{HomesteadBlock: big.NewInt(1)},
-			head:   3,
+			stored:    &ChainConfig{ConstantinopleBlock: big.NewInt(30)},
+			new:       &ChainConfig{ConstantinopleBlock: big.NewInt(30), PetersburgBlock: big.NewInt(30)},
+			headBlock: 40,
+			wantErr:   nil,
+		},
+		{
+			stored:    &ChainConfig{ConstantinopleBlock: big.NewInt(30)},
+			new:       &ChainConfig{ConstantinopleBlock: big.NewInt(30), PetersburgBlock: big.NewInt(31)},
+			headBlock: 40,
 			wantErr: &ConfigCompatError{
-				What:         "Homestead fork block",
-				StoredConfig: big.NewInt(0),
-				NewConfig:    big.NewInt(1),
-				RewindTo:     0,
+				What:          "Petersburg fork block",
+				StoredBlock:   nil,
+				NewBlock:      big.NewInt(31),
+				RewindToBlock: 30,
 			},
 		},
 		{
-			stored: &ChainConfig{HomesteadBlock: big.NewInt(30), EIP150Block: big.NewInt(10)},
-			new:    &ChainConfig{HomesteadBlock: big.NewInt(25), EIP150Block: big.NewInt(20)},
-			head:   25,
+			stored:        &ChainConfig{ShanghaiTime: newUint64(10)},
+			new:           &ChainConfig{ShanghaiTime: newUint64(20)},
+			headTimestamp: 9,
+			wantErr:       nil,
+		},
+		{
+			stored:        &ChainConfig{ShanghaiTime: newUint64(10)},
+			new:           &ChainConfig{ShanghaiTime: newUint64(20)},
+			headTimestamp: 25,
 			wantErr: &ConfigCompatError{
-				What:         "EIP150 fork block",
-				StoredConfig: big.NewInt(10),
-				NewConfig:    big.NewInt(20),
-				RewindTo:     9,
+				What:         "Shanghai fork timestamp",
+				StoredTime:   newUint64(10),
+				NewTime:      newUint64(20),
+				RewindToTime: 9,
 			},
 		},
 		{
-			stored:  &ChainConfig{ConstantinopleBlock: big.NewInt(30)},
-			new:     &ChainConfig{ConstantinopleBlock: big.NewInt(30), PetersburgBlock: big.NewInt(30)},
-			head:    40,
-			wantErr: nil,
+			stored:        &ChainConfig{ShanghaiTime: newUint64(10)},
+			new:           &ChainConfig{ShanghaiTime: nil},
+			headBlock:     1000,
+			headTimestamp: 5,
+			wantErr:       nil,
+		},
+		{
+			stored:    &ChainConfig{PrecompileBlocks: map[common.Address]*big.Int{{0x01}: big.NewInt(10)}},
+			new:       &ChainConfig{PrecompileBlocks: map[common.Address]*big.Int{{0x01}: big.NewInt(20)}},
+			headBlock: 9,
+			wantErr:   nil,
 		},
 		{
-			stored: &ChainConfig{ConstantinopleBlock: big.NewInt(30)},
-			new:    &ChainConfig{ConstantinopleBlock: big.NewInt(30), PetersburgBlock: big.NewInt(31)},
-			head:   40,
+			stored:    &ChainConfig{PrecompileBlocks: map[common.Address]*big.Int{{0x01}: big.NewInt(10)}},
+			new:       &ChainConfig{PrecompileBlocks: map[common.Address]*big.Int{{0x01}: big.NewInt(20)}},
+			headBlock: 15,
 			wantErr: &ConfigCompatError{
-				What:         "Petersburg fork block",
-				StoredConfig: nil,
-				NewConfig:    big.NewInt(31),
-				RewindTo:     30,
+				What:          "precompile 0x0100000000000000000000000000000000000000 activation block",
+				StoredBlock:   big.NewInt(10),
+				NewBlock:      big.NewInt(20),
+				RewindToBlock: 9,
+			},
+		},
+		{
+			stored:    &ChainConfig{},
+			new:       &ChainConfig{PrecompileBlocks: map[common.Address]*big.Int{{0x01}: big.NewInt(5)}},
+			headBlock: 15,
+			wantErr: &ConfigCompatError{
+				What:          "precompile 0x0100000000000000000000000000000000000000 activation block",
+				StoredBlock:   nil,
+				NewBlock:      big.NewInt(5),
+				RewindToBlock: 4,
 			},
 		},
 	}
 
 	for _, test := range tests {
-		err := test.stored.CheckCompatible(test.new, test.head)
+		err := test.stored.CheckCompatible(test.new, test.headBlock, test.headTimestamp)
 		if !reflect.DeepEqual(err, test.wantErr) {
-			t.Errorf("error mismatch:\nstored: %v\nnew: %v\nhead: %v\nerr: %v\nwant: %v", test.stored, test.new, test.head, err, test.wantErr)
+			t.Errorf("error mismatch:\nstored: %v\nnew: %v\nheadBlock: %v\nheadTimestamp: %v\nerr: %v\nwant: %v", test.stored, test.new, test.headBlock, test.headTimestamp, err, test.wantErr)
 		}
 	}
 }
+
+func TestConfigRules(t *testing.T) {
+	c := &ChainConfig{
+		LondonBlock:  new(big.Int),
+		ShanghaiTime: newUint64(500),
+	}
+	var stamp uint64
+	if r := c.Rules(big.NewInt(0), true, stamp); r.IsShanghai {
+		t.Errorf("expected %v to not be shanghai", stamp)
+	}
+	stamp = 500
+	if r := c.Rules(big.NewInt(0), true, stamp); !r.IsShanghai {
+		t.Errorf("expected %v to be shanghai", stamp)
+	}
+	stamp = math.MaxInt64
+	if r := c.Rules(big.NewInt(0), true, stamp); !r.IsShanghai {
+		t.Errorf("expected %v to be shanghai", stamp)
+	}
+	// Timestamp based forks require the block based ones to be active
+	c.LondonBlock = big.NewInt(10)
+	if r := c.Rules(big.NewInt(0), true, stamp); r.IsShanghai {
+		t.Errorf("expected pre-London block to not be shanghai")
+	}
+}
+
+func TestConfigRulesPrecompiles(t *testing.T) {
+	c := &ChainConfig{
+		PrecompileBlocks: map[common.Address]*big.Int{
+			{0x02}: big.NewInt(20),
+			{0x01}: big.NewInt(10),
+		},
+	}
+	for _, tt := range []struct {
+		number int64
+		want   []common.Address
+	}{
+		{9, nil},
+		{10, []common.Address{{0x01}}},
+		{20, []common.Address{{0x01}, {0x02}}},
+	} {
+		if have := c.Rules(big.NewInt(tt.number), false, 0).CustomPrecompiles; !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("block %d: custom precompiles mismatch: have %v, want %v", tt.number, have, tt.want)
+		}
+	}
+	// Replacing the schedule, e.g. on a copy of the config, must not reuse the
+	// schedule cached for the original one.
+	c.PrecompileBlocks = map[common.Address]*big.Int{{0x03}: big.NewInt(0)}
+	if have, want := c.Rules(big.NewInt(20), false, 0).CustomPrecompiles, []common.Address{{0x03}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("replaced schedule: custom precompiles mismatch: have %v, want %v", have, want)
+	}
+}
+
+func TestCheckConfigForkOrder(t *testing.T) {
+	config := *AllEthashProtocolChanges
+	config.ShanghaiTime = newUint64(10)
+	config.CancunTime = newUint64(20)
+	if err := config.CheckConfigForkOrder(); err != nil {
+		t.Fatalf("valid fork order rejected: %v", err)
+	}
+	config.CancunTime = newUint64(5)
+	if err := config.CheckConfigForkOrder(); err == nil {
+		t.Fatal("cancun before shanghai accepted")
+	}
+	config.ShanghaiTime, config.CancunTime = nil, newUint64(20)
+	if err := config.CheckConfigForkOrder(); err != nil {
+		t.Fatalf("optional shanghai rejected: %v", err)
+	}
+	config.LondonBlock = nil
+	if err := config.CheckConfigForkOrder(); err == nil {
+		t.Fatal("timestamp fork without london accepted")
+	}
+}
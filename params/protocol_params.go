@@ -153,6 +153,16 @@ const (
 	Bls12381MapG1Gas          uint64 = 5500   // Gas price for BLS12-381 mapping field element to G1 operation
 	Bls12381MapG2Gas          uint64 = 110000 // Gas price for BLS12-381 mapping field element to G2 operation
 
+	BlobTxBytesPerFieldElement         = 32                       // Size in bytes of a field element
+	BlobTxFieldElementsPerBlob         = 4096                     // Number of field elements stored in a single data blob
+	BlobTxHashVersion                  = 0x01                     // Version byte of the commitment hash
+	BlobTxBlobGasPerBlob               = 1 << 17                  // Gas consumption of a single data blob (== blob byte size)
+	BlobTxMinBlobGasprice              = 1                        // Minimum gas price for data blobs
+	BlobTxBlobGaspriceUpdateFraction   = 3338477                  // Controls the maximum rate of change for blob gas price
+	BlobTxPointEvaluationPrecompileGas = 50000                    // Gas price for the point evaluation precompile.
+	BlobTxTargetBlobGasPerBlock        = 3 * BlobTxBlobGasPerBlob // Target consumable blob gas for data blobs per block (for 1559-like pricing)
+	MaxBlobGasPerBlock                 = 6 * BlobTxBlobGasPerBlob // Maximum consumable blob gas for data blobs per block
+
 	// The Refund Quotient is the cap on how much of the used gas can be refunded. Before EIP-3529,
 	// up to half the consumed gas could be refunded. Redefined as 1/5th in EIP-3529
 	RefundQuotient        uint64 = 2
@@ -0,0 +1,74 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vmprogram
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+)
+
+// result is the observable outcome of executing a piece of code.
+type result struct {
+	ret  []byte
+	gas  uint64
+	err  error
+	root common.Hash
+}
+
+// execute runs the given code once with a fresh state.
+func execute(code []byte, programs bool) result {
+	address := common.BytesToAddress([]byte("contract"))
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(address, code)
+
+	ret, gas, err := runtime.Call(address, nil, &runtime.Config{
+		GasLimit:  1000000,
+		State:     statedb,
+		EVMConfig: vm.Config{EnableProgram: programs},
+	})
+	return result{ret: ret, gas: gas, err: err, root: statedb.IntermediateRoot(true)}
+}
+
+// Fuzz is the basic entry point for the go-fuzz tool. It executes the input as
+// code with the reference interpreter loop and as a decoded program, panicking
+// if the outcomes differ.
+//
+// This returns 1 for code which executed successfully, 0 otherwise.
+func Fuzz(code []byte) int {
+	want := execute(code, false)
+
+	// Code is only decoded once it was executed before, the first run with
+	// programs enabled is thus still executed by the reference loop.
+	for i := 0; i < 2; i++ {
+		have := execute(code, true)
+		if !bytes.Equal(have.ret, want.ret) || have.gas != want.gas || fmt.Sprint(have.err) != fmt.Sprint(want.err) || have.root != want.root {
+			panic(fmt.Sprintf("run %d: outcome mismatch\nhave: ret %x gas %d err %v root %x\nwant: ret %x gas %d err %v root %x",
+				i, have.ret, have.gas, have.err, have.root, want.ret, want.gas, want.err, want.root))
+		}
+	}
+	if want.err != nil {
+		return 0
+	}
+	return 1
+}
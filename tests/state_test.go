@@ -93,6 +93,18 @@ func TestState(t *testing.T) {
 						return st.checkFailure(t, err)
 					})
 				})
+				t.Run(key+"/programs", func(t *testing.T) {
+					// Decoded programs must yield the same post state as the
+					// reference loop. Code is only decoded once it was executed
+					// before, so the first run just warms up the analysis cache.
+					config := vm.Config{EnableProgram: true}
+					for i := 0; i < 2; i++ {
+						_, _, err := test.Run(subtest, config, false)
+						if err := st.checkFailure(t, err); err != nil {
+							t.Fatalf("run %d: %v", i, err)
+						}
+					}
+				})
 			}
 		})
 	}
@@ -185,7 +197,7 @@ func runBenchmark(b *testing.B, t *StateTest) {
 				b.Error(err)
 				return
 			}
-			var rules = config.Rules(new(big.Int), false)
+			var rules = config.Rules(new(big.Int), false, 0)
 
 			vmconfig.ExtraEips = eips
 			block := t.genesis(config).ToBlock()